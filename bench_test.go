@@ -54,6 +54,36 @@ func BenchmarkNewContext(b *testing.B) {
 	}
 }
 
+// 模拟浏览器的报头，比较缓存与未缓存的协商结果
+func BenchmarkInternalServer_negotiate(b *testing.B) {
+	a := assert.New(b, false)
+	s := newTestServer(a)
+
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set(header.Accept, "text/html,application/xhtml+xml,application/xml;q=0.9,application/json;q=0.8,*/*;q=0.7")
+	r.Header.Set(header.AcceptCharset, "gbk;q=0.9,utf-8")
+	r.Header.Set(header.AcceptEncoding, "gzip, deflate, br, zstd")
+	r.Header.Set(header.AcceptLanguage, "zh-CN,zh;q=0.9,en;q=0.8,en-US;q=0.7")
+
+	b.Run("cached", func(b *testing.B) {
+		for b.Loop() {
+			s.negotiate(s.config, r)
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		key := negotiationKey{
+			accept:         r.Header.Get(header.Accept),
+			acceptCharset:  r.Header.Get(header.AcceptCharset),
+			acceptEncoding: r.Header.Get(header.AcceptEncoding),
+			acceptLanguage: r.Header.Get(header.AcceptLanguage),
+		}
+		for b.Loop() {
+			s.newNegotiation(s.codec, key)
+		}
+	})
+}
+
 func BenchmarkContext_Render(b *testing.B) {
 	a := assert.New(b, false)
 	s := newTestServer(a)
@@ -245,7 +275,7 @@ func BenchmarkCodec_accept(b *testing.B) {
 	mt := newCodec(a)
 
 	for b.Loop() {
-		item := mt.accept("application/json;q=0.9")
+		item, _, _ := mt.accept("application/json;q=0.9")
 		a.NotNil(item)
 	}
 }
@@ -0,0 +1,222 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/issue9/localeutil"
+	"github.com/issue9/mux/v9/header"
+)
+
+type (
+	// BodyLimit 对客户端提交内容的限制
+	//
+	// 各字段为 0 表示不作限制。
+	//
+	// 可通过 [WithBodyLimit] 应用于整个路由，或是作为中间件应用于单个路由项：
+	//
+	//	router.Post("/upload", handler, &web.BodyLimit{Size: 100 << 20})
+	//
+	// 提交的内容始终会按 Content-Encoding 解压，多个压缩方法按相反的顺序解压，
+	// 不支持的压缩方法在 [InternalServer.NewContext] 中即返回 415 错误。
+	// BodyLimit 仅用于限制内容的大小，不影响是否解压。
+	BodyLimit struct {
+		// 提交内容的最大字节数
+		//
+		// 如果指定了 Content-Encoding，表示的是解压之前的大小。
+		Size int64
+
+		// 解压之后的最大字节数
+		//
+		// 仅在指定了 Content-Encoding 时有效。
+		DecodedSize int64
+
+		// 解压之后与解压之前的最大比值
+		//
+		// 仅在指定了 Content-Encoding 时有效，根据已经读取的内容计算。
+		Ratio int64
+	}
+
+	// BodyLimitError 提交的内容超出 [BodyLimit] 限制时返回的错误
+	//
+	// 同时也作为 [ProblemRequestEntityTooLarge] 的 [Problem.Extensions] 输出。
+	BodyLimitError struct {
+		// 超出限制的项，可以是 size、decodedSize 和 ratio
+		Field string `json:"field" xml:"field" form:"field" cbor:"field" yaml:"field"`
+
+		// 该项的限制值
+		Limit int64 `json:"limit" xml:"limit" form:"limit" cbor:"limit" yaml:"limit"`
+	}
+
+	// 对提交内容的包装
+	//
+	// 负责解压和统计读取的字节数，在超出 limit 的限制时返回 [BodyLimitError]。
+	requestBody struct {
+		limit       *BodyLimit
+		length      int64  // Content-Length 报头的值
+		contentType string // Content-Type 报头的值
+
+		raw     io.Reader
+		rawSize int64 // 已经从 raw 读取的字节数
+
+		codec     *Codec
+		encodings []string        // Content-Encoding 报头指定的压缩方法，按压缩的顺序排列。
+		decoder   io.Reader       // 为空表示未初始化或是不需要解压
+		decoders  []io.ReadCloser // 需要关闭的解码器
+		size      int64           // 解压之后已经读取的字节数
+
+		err error // 超出限制之后的错误信息
+	}
+
+	// 以 [io.Reader] 的形式读取 requestBody.raw 的内容
+	rawBody requestBody
+
+	// 经过字符集转换或是被缓存的 requestBody，依然提供 ContentType 方法。
+	transformBody struct {
+		io.Reader
+		body *requestBody
+	}
+)
+
+func (e *BodyLimitError) Error() string {
+	return fmt.Sprintf("request body %s exceeds the limit %d", e.Field, e.Limit)
+}
+
+func (e *BodyLimitError) LocaleString(p *localeutil.Printer) string {
+	return localeutil.Phrase("request body %s exceeds the limit %d", e.Field, e.Limit).LocaleString(p)
+}
+
+// Middleware 实现 [Middleware] 接口
+//
+// 将 l 作为当前路由项的限制，会覆盖 [WithBodyLimit] 的设置。
+func (l *BodyLimit) Middleware(next HandlerFunc, _, _, _ string) HandlerFunc {
+	return func(ctx *Context) Responser {
+		ctx.SetBodyLimit(l)
+		return next(ctx)
+	}
+}
+
+func (b *requestBody) reset(r *http.Request, c *Codec, limit *BodyLimit) {
+	b.limit = limit
+	b.length = r.ContentLength
+	b.contentType = r.Header.Get(header.ContentType)
+	b.raw = r.Body
+	b.rawSize = 0
+	b.codec = c
+	b.encodings = b.encodings[:0]
+	for _, name := range strings.Split(r.Header.Get(header.ContentEncoding), ",") {
+		if name = strings.TrimSpace(name); name != "" && name != header.Identity {
+			b.encodings = append(b.encodings, name)
+		}
+	}
+	b.decoder = nil
+	b.decoders = b.decoders[:0]
+	b.size = 0
+	b.err = nil
+}
+
+// ContentType 返回 Content-Type 报头的内容
+//
+// [UnmarshalFunc] 可以借此获取报头中的参数，比如 multipart/form-data 的 boundary。
+func (b *requestBody) ContentType() string { return b.contentType }
+
+func (b *transformBody) ContentType() string { return b.body.contentType }
+
+func (b *requestBody) exceed(field string, limit int64) error {
+	b.err = NewError(http.StatusRequestEntityTooLarge, &BodyLimitError{Field: field, Limit: limit})
+	return b.err
+}
+
+func (b *rawBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	if l := b.limit; l != nil && l.Size > 0 && b.length > l.Size { // 无须读取内容即可判断
+		return 0, (*requestBody)(b).exceed("size", l.Size)
+	}
+
+	n, err := b.raw.Read(p)
+	b.rawSize += int64(n)
+	if l := b.limit; l != nil && l.Size > 0 && b.rawSize > l.Size {
+		return n, (*requestBody)(b).exceed("size", l.Size)
+	}
+	return n, err
+}
+
+func (b *requestBody) Read(p []byte) (int, error) {
+	if b.decoder == nil && (len(b.encodings) == 0 || b.rawSize > 0) {
+		return (*rawBody)(b).Read(p)
+	}
+
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	if b.decoder == nil { // 解码器在读取时才创建，部分解码器在创建时就需要读取内容。
+		if err := b.initDecoder(); err != nil {
+			if b.err != nil {
+				return 0, b.err
+			}
+			return 0, err
+		}
+	}
+
+	n, err := b.decoder.Read(p)
+	if b.err != nil { // 读取原始内容时超出了限制
+		return n, b.err
+	}
+
+	b.size += int64(n)
+	if l := b.limit; l != nil {
+		if l.DecodedSize > 0 && b.size > l.DecodedSize {
+			return n, b.exceed("decodedSize", l.DecodedSize)
+		}
+		if l.Ratio > 0 && b.rawSize > 0 && b.size > l.Ratio*b.rawSize {
+			return n, b.exceed("ratio", l.Ratio)
+		}
+	}
+	return n, err
+}
+
+// 按与压缩相反的顺序创建解码器
+//
+// 压缩方法是否存在已经在 [InternalServer.NewContext] 中检测。
+func (b *requestBody) initDecoder() error {
+	var r io.Reader = (*rawBody)(b)
+	for i := len(b.encodings) - 1; i >= 0; i-- {
+		d, err := b.codec.findCompression(b.encodings[i]).compressor.NewDecoder(r)
+		if err != nil {
+			return err
+		}
+		b.decoders = append(b.decoders, d)
+		r = d
+	}
+	b.decoder = r
+	return nil
+}
+
+func (b *requestBody) close() error {
+	var err error
+	for i := len(b.decoders) - 1; i >= 0; i-- {
+		err = errors.Join(err, b.decoders[i].Close())
+	}
+	b.decoders = b.decoders[:0]
+	b.decoder = nil
+	return err
+}
+
+// SetBodyLimit 修改对提交内容的限制
+//
+// 默认值由 [WithBodyLimit] 指定，l 为空表示不作任何限制。需要在读取内容之前调用才有效果。
+func (ctx *Context) SetBodyLimit(l *BodyLimit) { ctx.body.limit = l }
+
+// BodyLimit 对提交内容的限制
+func (ctx *Context) BodyLimit() *BodyLimit { return ctx.body.limit }
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/mux/v9/types"
+)
+
+var _ Middleware = &BodyLimit{}
+
+func gzipBody(a *assert.Assertion, body string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	_, err := w.Write([]byte(body))
+	a.NotError(err).NotError(w.Close())
+	return buf
+}
+
+func TestContext_Read_bodyLimit(t *testing.T) {
+	a := assert.New(t, false)
+	s := newTestServer(a)
+
+	read := func(r *http.Request, l *BodyLimit) (*httptest.ResponseRecorder, *object) {
+		r.Header.Set(header.ContentType, header.JSON)
+		r.Header.Set(header.Accept, header.JSON)
+		w := httptest.NewRecorder()
+		ctx := s.NewContext(w, r, types.NewContext())
+		a.NotNil(ctx)
+		defer s.freeContext(ctx)
+
+		ctx.SetBodyLimit(l)
+		a.Equal(ctx.BodyLimit(), l)
+
+		obj := &object{}
+		if resp := ctx.Read(false, obj, "41110"); resp != nil {
+			resp.Apply(ctx)
+		}
+		return w, obj
+	}
+
+	// 未压缩
+	r := httptest.NewRequest(http.MethodPost, "/path", bytes.NewBufferString(objectJSONString))
+	w, obj := read(r, &BodyLimit{Size: int64(len(objectJSONString))})
+	a.Equal(w.Code, http.StatusOK).Equal(obj, objectInst)
+
+	r = httptest.NewRequest(http.MethodPost, "/path", bytes.NewBufferString(objectJSONString))
+	w, _ = read(r, &BodyLimit{Size: 10})
+	a.Equal(w.Code, http.StatusRequestEntityTooLarge).
+		Contains(w.Body.String(), `"extensions":{"field":"size","limit":10}`)
+
+	// 未指定 Content-Length
+	r = httptest.NewRequest(http.MethodPost, "/path", bytes.NewBufferString(objectJSONString))
+	r.ContentLength = -1
+	w, _ = read(r, &BodyLimit{Size: 10})
+	a.Equal(w.Code, http.StatusRequestEntityTooLarge)
+
+	// 压缩
+	r = httptest.NewRequest(http.MethodPost, "/path", gzipBody(a, objectJSONString))
+	r.Header.Set(header.ContentEncoding, "gzip")
+	w, obj = read(r, &BodyLimit{DecodedSize: 1 << 20})
+	a.Equal(w.Code, http.StatusOK).Equal(obj, objectInst)
+
+	// 多个压缩方法
+	body := &bytes.Buffer{}
+	fw, err := flate.NewWriter(body, flate.DefaultCompression)
+	a.NotError(err)
+	_, err = fw.Write(gzipBody(a, objectJSONString).Bytes())
+	a.NotError(err).NotError(fw.Close())
+	r = httptest.NewRequest(http.MethodPost, "/path", body)
+	r.Header.Set(header.ContentEncoding, "gzip, identity, deflate")
+	w, obj = read(r, &BodyLimit{Ratio: 100})
+	a.Equal(w.Code, http.StatusOK).Equal(obj, objectInst)
+
+	r = httptest.NewRequest(http.MethodPost, "/path", gzipBody(a, objectJSONString))
+	r.Header.Set(header.ContentEncoding, "gzip")
+	w, _ = read(r, &BodyLimit{DecodedSize: 10})
+	a.Equal(w.Code, http.StatusRequestEntityTooLarge).
+		Contains(w.Body.String(), `"extensions":{"field":"decodedSize","limit":10}`)
+
+	bomb := `{"name":"` + strings.Repeat("0", 1<<20) + `"}`
+	r = httptest.NewRequest(http.MethodPost, "/path", gzipBody(a, bomb))
+	r.Header.Set(header.ContentEncoding, "gzip")
+	w, _ = read(r, &BodyLimit{Ratio: 100})
+	a.Equal(w.Code, http.StatusRequestEntityTooLarge).
+		Contains(w.Body.String(), `"extensions":{"field":"ratio","limit":100}`)
+
+	r = httptest.NewRequest(http.MethodPost, "/path", gzipBody(a, bomb))
+	r.Header.Set(header.ContentEncoding, "gzip")
+	w, _ = read(r, &BodyLimit{Size: 100})
+	a.Equal(w.Code, http.StatusRequestEntityTooLarge).
+		Contains(w.Body.String(), `"extensions":{"field":"size","limit":100}`)
+
+	// 未指定 DecodedSize 和 Ratio，依然解压。
+	r = httptest.NewRequest(http.MethodPost, "/path", gzipBody(a, objectJSONString))
+	r.Header.Set(header.ContentEncoding, "gzip")
+	w, obj = read(r, &BodyLimit{Size: 1 << 20})
+	a.Equal(w.Code, http.StatusOK).Equal(obj, objectInst)
+
+	r = httptest.NewRequest(http.MethodPost, "/path", gzipBody(a, objectJSONString))
+	r.Header.Set(header.ContentEncoding, "gzip")
+	w, obj = read(r, nil)
+	a.Equal(w.Code, http.StatusOK).Equal(obj, objectInst)
+
+	// 不支持的压缩方式，与是否指定了限制无关。
+	r = httptest.NewRequest(http.MethodPost, "/path", gzipBody(a, objectJSONString))
+	r.Header.Set(header.ContentEncoding, "gzip, not-exists")
+	w = httptest.NewRecorder()
+	a.Nil(s.NewContext(w, r, types.NewContext())).
+		Equal(w.Code, http.StatusUnsupportedMediaType)
+}
+
+func TestWithBodyLimit(t *testing.T) {
+	a := assert.New(t, false)
+	s := newTestServer(a)
+
+	router := s.Routers().New("def", nil, WithBodyLimit(&BodyLimit{Size: 10}))
+	h := func(ctx *Context) Responser {
+		obj := &object{}
+		if resp := ctx.Read(false, obj, "41110"); resp != nil {
+			return resp
+		}
+		return OK(nil)
+	}
+	router.Post("/limit", h)
+	router.Post("/unlimited", h, &BodyLimit{})
+
+	r := httptest.NewRequest(http.MethodPost, "/limit", bytes.NewBufferString(objectJSONString))
+	r.Header.Set(header.ContentType, header.JSON)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	a.Equal(w.Code, http.StatusRequestEntityTooLarge)
+
+	r = httptest.NewRequest(http.MethodPost, "/unlimited", bytes.NewBufferString(objectJSONString))
+	r.Header.Set(header.ContentType, header.JSON)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	a.Equal(w.Code, http.StatusOK)
+}
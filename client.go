@@ -11,6 +11,7 @@ import (
 
 	"github.com/issue9/mux/v9/header"
 	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
 	"golang.org/x/text/transform"
 
 	"github.com/issue9/web/internal/qheader"
@@ -159,6 +160,34 @@ func (c *Client) ParseResponse(rsp *http.Response, resp any, pb ProblemBuilder)
 	return inputMimetype(reader, resp)
 }
 
+// ReadBody 返回经过解码的 rsp.Body
+//
+// 会根据 Content-Encoding 和 Content-Type 中的字符集对 rsp.Body 进行解码，
+// 适用于需要自行处理返回内容的情况，比如流式的数据。
+// 调用方需要在使用完之后关闭返回的对象。
+func (c *Client) ReadBody(rsp *http.Response) (io.ReadCloser, error) {
+	r, err := c.codec.contentEncoding(rsp.Header.Get(header.ContentEncoding), rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	_, charset := qheader.ParseWithParam(rsp.Header.Get(header.ContentType), "charset")
+	if charset == "" || charset == header.UTF8 {
+		return r, nil
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+	return readCloser{Reader: transform.NewReader(r, enc.NewDecoder()), Closer: r}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
 // NewRequest 生成 [http.Request]
 //
 // body 为需要提交的对象；
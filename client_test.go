@@ -6,6 +6,7 @@ package web
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -15,6 +16,7 @@ import (
 	"github.com/issue9/assert/v4"
 	"github.com/issue9/mux/v9/header"
 
+	"github.com/issue9/web/compressor"
 	"github.com/issue9/web/internal/qheader"
 	"github.com/issue9/web/selector"
 )
@@ -87,3 +89,29 @@ func TestClient_ParseResponse(t *testing.T) {
 		a.NotError(c.ParseResponse(resp, rsp, p)).Equal(rsp, obj)
 	})
 }
+
+func TestClient_ReadBody(t *testing.T) {
+	a := assert.New(t, false)
+	codec := newCodec(a)
+
+	sel := selector.NewRoundRobin(false, 1)
+	sel.Update(selector.NewPeer("https://example.com"))
+	c := NewClient(nil, codec, sel, header.JSON, json.Marshal, "", nil)
+
+	buf := &bytes.Buffer{}
+	w, err := compressor.NewGzip(gzip.DefaultCompression).NewEncoder(buf)
+	a.NotError(err)
+	_, err = w.Write(objectGBKBytes)
+	a.NotError(err).NotError(w.Close())
+
+	h := http.Header{}
+	h.Set(header.ContentType, qheader.BuildContentType(header.JSON, "gbk"))
+	h.Set(header.ContentEncoding, "gzip")
+	resp := &http.Response{Header: h, Body: io.NopCloser(buf), StatusCode: http.StatusOK}
+
+	r, err := c.ReadBody(resp)
+	a.NotError(err).NotNil(r)
+	data, err := io.ReadAll(r)
+	a.NotError(err).Equal(string(data), objectJSONString).
+		NotError(r.Close())
+}
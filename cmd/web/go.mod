@@ -34,6 +34,7 @@ require (
 	github.com/jellydator/ttlcache/v3 v3.4.0 // indirect
 	github.com/klauspost/compress v1.18.2 // indirect
 	github.com/otiai10/mint v1.6.3 // indirect
+	github.com/puzpuzpuz/xsync/v4 v4.2.0 // indirect
 	golang.org/x/sync v0.18.0 // indirect
 	golang.org/x/sys v0.38.0 // indirect
 	golang.org/x/tools v0.39.0 // indirect
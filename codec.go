@@ -46,6 +46,11 @@ type mediaType struct {
 	// 生成编码方法
 	Marshal MarshalFunc
 
+	// 流式的编码方法
+	//
+	// 可以为空，表示采用 Marshal 进行编码。
+	Encode EncodeFunc
+
 	// 解码方法
 	Unmarshal UnmarshalFunc
 
@@ -54,6 +59,7 @@ type mediaType struct {
 
 type compression struct {
 	compressor compressor.Compressor
+	minSize    int
 
 	types []string
 
@@ -62,38 +68,82 @@ type compression struct {
 
 	// 是模糊类型的，比如 text/*，只有在 Types 找不到时，才在此处查找。
 	wildcardSuffix []string
+
+	// 排除的类型，优先于 types 和 wildcard。
+	exclude               []string
+	excludeWildcardSuffix []string
+	excludeWildcard       bool // 排除所有类型
+}
+
+// CompressionOptions 压缩算法的设置项
+type CompressionOptions struct {
+	// 适用的 content-type 类型
+	//
+	// 可以包含通配符，比如 text/*，为空或是包含 * 表示匹配所有。
+	Types []string
+
+	// 排除的 content-type 类型
+	//
+	// 格式与 Types 相同，优先级高于 Types，包含 * 表示排除所有，即不对任何内容进行压缩。
+	// 一般用于排除已经压缩过的内容，比如 image/*、application/zip 等。
+	Exclude []string
+
+	// 内容小于此值时不作压缩
+	//
+	// 在输出内容达到此值之前，内容会被缓存。0 表示始终压缩。
+	MinSize int
 }
 
-func buildCompression(c compressor.Compressor, types []string) *compression {
-	m := &compression{compressor: c}
+func buildCompression(c compressor.Compressor, o *CompressionOptions) *compression {
+	if o == nil {
+		o = &CompressionOptions{}
+	}
+
+	m := &compression{compressor: c, minSize: o.MinSize}
+	m.exclude, m.excludeWildcardSuffix, m.excludeWildcard = splitTypes(o.Exclude)
 
-	if len(types) == 0 {
+	if len(o.Types) == 0 {
 		m.wildcard = true
 		return m
 	}
+	m.types, m.wildcardSuffix, m.wildcard = splitTypes(o.Types)
 
-	m.types = make([]string, 0, len(types))
-	m.wildcardSuffix = make([]string, 0, len(types))
+	return m
+}
+
+// 将 types 分为完整的类型和带通配符的类型，如果包含 * 则 wildcard 为 true。
+func splitTypes(types []string) (full, suffix []string, wildcard bool) {
+	full = make([]string, 0, len(types))
+	suffix = make([]string, 0, len(types))
 	for _, c := range types {
 		if c == "" {
 			continue
 		}
 
 		if c == "*" {
-			m.types = nil
-			m.wildcardSuffix = nil
-			m.wildcard = true
-			return m
+			return nil, nil, true
 		}
 
 		if c[len(c)-1] == '*' {
-			m.wildcardSuffix = append(m.wildcardSuffix, c[:len(c)-1])
+			suffix = append(suffix, c[:len(c)-1])
 		} else {
-			m.types = append(m.types, c)
+			full = append(full, c)
 		}
 	}
+	return full, suffix, false
+}
 
-	return m
+// 是否适用于 contentType 类型的内容
+func (c *compression) match(contentType string) bool {
+	if c.excludeWildcard ||
+		slices.Contains(c.exclude, contentType) ||
+		slices.ContainsFunc(c.excludeWildcardSuffix, func(p string) bool { return strings.HasPrefix(contentType, p) }) {
+		return false
+	}
+
+	return c.wildcard ||
+		slices.Contains(c.types, contentType) ||
+		slices.ContainsFunc(c.wildcardSuffix, func(p string) bool { return strings.HasPrefix(contentType, p) })
 }
 
 // NewCodec 声明 [Codec] 对象
@@ -113,17 +163,56 @@ func NewCodec() *Codec {
 //	*
 //
 // 如果为空，则和 * 是相同的，表示匹配所有。
+//
+// 如果 c 实现了 [compressor.DictionaryCompressor]，只有在客户端通过 Available-Dictionary
+// 报头指定了服务端存在的字典时才会被采用，字典由 [UseAsDictionary] 生成。
 func (e *Codec) AddCompressor(c compressor.Compressor, t ...string) *Codec {
-	e.compressions = append(e.compressions, buildCompression(c, t))
+	return e.AddCompressorWithOptions(c, &CompressionOptions{Types: t})
+}
+
+// AddCompressorWithOptions 添加新的压缩算法
+//
+// 与 [Codec.AddCompressor] 相同，但是可以通过 o 指定更多的设置项。
+func (e *Codec) AddCompressorWithOptions(c compressor.Compressor, o *CompressionOptions) *Codec {
+	e.compressions = append(e.compressions, buildCompression(c, o))
+	e.buildAcceptEncodingHeader()
+	return e
+}
 
+func (e *Codec) buildAcceptEncodingHeader() {
 	names := make([]string, 0, len(e.compressions))
 	for _, item := range e.compressions {
 		names = append(names, item.compressor.Name())
 	}
 	names = sliceutil.Unique(names, func(i, j string) bool { return i == j })
 	e.acceptEncodingHeader = strings.Join(names, ",")
+}
 
-	return e
+// 返回仅包含指定名称压缩算法的副本
+//
+// name 为空表示不支持任何压缩算法。
+func (e *Codec) withCompressors(name ...string) *Codec {
+	c := &Codec{
+		compressions:       make([]*compression, 0, len(name)),
+		types:              e.types,
+		clientAcceptHeader: e.clientAcceptHeader,
+		serverAcceptHeader: e.serverAcceptHeader,
+	}
+
+	for _, n := range name {
+		index := len(c.compressions)
+		for _, item := range e.compressions {
+			if item.compressor.Name() == n {
+				c.compressions = append(c.compressions, item)
+			}
+		}
+		if index == len(c.compressions) {
+			panic(fmt.Sprintf("未找到名为 %s 的压缩算法", n))
+		}
+	}
+	c.buildAcceptEncodingHeader()
+
+	return c
 }
 
 // AddMimetype 添加对媒体类型的编解码函数
@@ -132,7 +221,8 @@ func (e *Codec) AddCompressor(c compressor.Compressor, t ...string) *Codec {
 // problem 媒体类型非正常状态下的子类型，比如 application/problem+json；
 // requestAccept 是否出现在客户端请求的 accept 报头中；
 // responseAccept 是否出现在服务端返回的 accept 报头中；
-func (e *Codec) AddMimetype(name string, m MarshalFunc, u UnmarshalFunc, problem string, requestAccept, responseAccept bool) *Codec {
+// enc 流式的编码方法，可以为空，最多只能指定一个，如果指定了，[Context.Render] 等将优先采用此方法输出；
+func (e *Codec) AddMimetype(name string, m MarshalFunc, u UnmarshalFunc, problem string, requestAccept, responseAccept bool, enc ...EncodeFunc) *Codec {
 	if problem == "" {
 		problem = name
 	}
@@ -149,6 +239,15 @@ func (e *Codec) AddMimetype(name string, m MarshalFunc, u UnmarshalFunc, problem
 		panic("参数 u 不能为空")
 	}
 
+	var encode EncodeFunc
+	switch len(enc) {
+	case 0:
+	case 1:
+		encode = enc[0]
+	default:
+		panic("参数 enc 最多只能指定一个")
+	}
+
 	// 检测复复值
 	if slices.IndexFunc(e.types, func(v *mediaType) bool { return v.Name == name }) >= 0 {
 		panic(fmt.Sprintf("存在重复的项 %s", name))
@@ -157,6 +256,7 @@ func (e *Codec) AddMimetype(name string, m MarshalFunc, u UnmarshalFunc, problem
 	e.types = append(e.types, &mediaType{
 		Name:           name,
 		Marshal:        m,
+		Encode:         encode,
 		Unmarshal:      u,
 		Problem:        problem,
 		requestAccept:  requestAccept,
@@ -189,12 +289,19 @@ func (e *Codec) contentEncoding(name string, r io.Reader) (io.ReadCloser, error)
 		return io.NopCloser(r), nil
 	}
 
-	if c, f := sliceutil.At(e.compressions, func(item *compression, _ int) bool { return item.compressor.Name() == name }); f {
+	if c := e.findCompression(name); c != nil {
 		return c.compressor.NewDecoder(r)
 	}
 	return nil, NewLocaleError("not found compress for %s", name)
 }
 
+func (e *Codec) findCompression(name string) *compression {
+	if c, f := sliceutil.At(e.compressions, func(item *compression, _ int) bool { return item.compressor.Name() == name }); f {
+		return c
+	}
+	return nil
+}
+
 // 根据客户端的 Accept-Encoding 报头选择是适合的压缩方法
 //
 // 如果返回的 c 为空值表示不需要压缩。
@@ -254,34 +361,53 @@ func (e *Codec) acceptEncoding(contentType, h string) (c compressor.Compressor,
 	return // 没有匹配，表示不需要进行压缩
 }
 
-func (e *Codec) getMatchCompresses(contentType string) []int {
-	indexes := make([]int, 0, len(e.compressions))
+// 根据客户端的 Accept-Encoding 报头选择基于字典的压缩方法
+//
+// 基于字典的压缩方法只有在客户端提供可用的字典时才能使用，
+// 所以不参与 [Codec.acceptEncoding] 的协商，且只匹配明确指定的名称。
+func (e *Codec) acceptDictionaryEncoding(contentType, h string) compressor.DictionaryCompressor {
+	if len(e.compressions) == 0 || h == "" {
+		return nil
+	}
 
-LOOP:
-	for index, c := range e.compressions {
-		if c.wildcard {
-			indexes = append(indexes, index)
+	accepts := qheader.ParseQHeader(h, "*")
+	defer qheader.PutQHeader(&accepts)
+
+	for _, accept := range accepts {
+		if accept.Err != nil || accept.Q <= 0 {
 			continue
 		}
 
-		for _, s := range c.types {
-			if s == contentType {
-				indexes = append(indexes, index)
-				continue LOOP
+		for _, item := range e.compressions {
+			if c, ok := item.compressor.(compressor.DictionaryCompressor); ok && c.Name() == accept.Value && item.match(contentType) {
+				return c
 			}
 		}
+	}
+	return nil
+}
 
-		for _, p := range c.wildcardSuffix {
-			if strings.HasPrefix(contentType, p) {
-				indexes = append(indexes, index)
-				continue LOOP
-			}
+// 查找适用于 contentType 的压缩方法，不包含基于字典的压缩方法。
+func (e *Codec) getMatchCompresses(contentType string) []int {
+	indexes := make([]int, 0, len(e.compressions))
+	for index, c := range e.compressions {
+		if _, ok := c.compressor.(compressor.DictionaryCompressor); !ok && c.match(contentType) {
+			indexes = append(indexes, index)
 		}
 	}
-
 	return indexes
 }
 
+// 查找适用于 contentType 的 c
+func (e *Codec) matchCompression(c compressor.Compressor, contentType string) *compression {
+	for _, item := range e.compressions {
+		if item.compressor == c && item.match(contentType) {
+			return item
+		}
+	}
+	return nil
+}
+
 func (m *mediaType) name(problem bool) string {
 	if problem {
 		return m.Problem
@@ -292,10 +418,14 @@ func (m *mediaType) name(problem bool) string {
 // 从请求端提交的 Content-Type 报头中获取解码和字符集函数
 //
 // h 表示 Content-Type 报头的内容。如果字符集为 utf-8 或是未指定，返回的字符解码为 nil；
+// 找不到完全匹配的媒体类型时，会尝试以结构化后缀进行匹配，比如 application/vnd.api.v2+json 可匹配 application/json。
 func (e *Codec) contentType(h string) (UnmarshalFunc, encoding.Encoding, error) {
 	mimetype, charset := qheader.ParseWithParam(h, "charset")
 
 	item := e.searchFunc(func(s string) bool { return s == mimetype })
+	if item == nil {
+		item = e.findSuffix(mimetype)
+	}
 	if item == nil {
 		return nil, nil, NewLocaleError("not found serialization function for %s", mimetype)
 	}
@@ -315,29 +445,34 @@ func (e *Codec) contentType(h string) (UnmarshalFunc, encoding.Encoding, error)
 //
 // */* 或是空值 表示匹配任意内容，一般会选择第一个元素作匹配；
 // xx/* 表示匹配以 xx/ 开头的任意元素，一般会选择 xx/* 开头的第一个元素；
-// xx/ 表示完全匹配以 xx/ 的内容
+// xx/ 表示完全匹配以 xx/ 的内容，找不到时会尝试以结构化后缀进行匹配；
 // 如果传递的内容如下：
 //
 //	application/json;q=0.9,*/*;q=1
 //
 // 则因为 */* 的 q 值比较高，而返回 */* 匹配的内容
-func (e *Codec) accept(h string) *mediaType {
+//
+// name 和 params 为客户端请求的媒体类型名称及其原始的参数，
+// 通配符或是匹配到的是 problem 类型时，两者都为空。
+func (e *Codec) accept(h string) (mt *mediaType, name, params string) {
 	if h == "" {
-		if item := e.findMarshal("*/*"); item != nil {
-			return item
-		}
-		return nil
+		return e.findMarshal("*/*"), "", ""
 	}
 
 	items := qheader.ParseQHeader(h, "*/*")
 	defer qheader.PutQHeader(&items)
 	for _, item := range items {
-		if i := e.findMarshal(item.Value); i != nil {
-			return i
+		if mt = e.findMarshal(item.Value); mt == nil {
+			continue
+		}
+
+		if v := item.Value; v != "*/*" && !strings.HasSuffix(v, "/*") && (v == mt.Name || v != mt.Problem) {
+			return mt, v, item.Params
 		}
+		return mt, "", ""
 	}
 
-	return nil
+	return nil, "", ""
 }
 
 func (e *Codec) findMarshal(name string) *mediaType {
@@ -350,7 +485,10 @@ func (e *Codec) findMarshal(name string) *mediaType {
 		prefix := name[:len(name)-3]
 		return e.searchFunc(func(s string) bool { return strings.HasPrefix(s, prefix) })
 	default:
-		return e.searchFunc(func(s string) bool { return s == name })
+		if item := e.searchFunc(func(s string) bool { return s == name }); item != nil {
+			return item
+		}
+		return e.findSuffix(name)
 	}
 }
 
@@ -358,3 +496,21 @@ func (e *Codec) searchFunc(match func(string) bool) *mediaType {
 	item, _ := sliceutil.At(e.types, func(i *mediaType, _ int) bool { return match(i.Name) || match(i.Problem) })
 	return item
 }
+
+// 根据 [RFC6839] 的结构化后缀查找
+//
+// 比如 application/vnd.api.v2+json 的后缀为 json，会匹配子类型为 json 的媒体类型，
+// 即 application/json 或是 text/json，按添加顺序返回第一个。
+// 同理 +xml、+cbor 和 +yaml 等也是相同的处理方式。
+//
+// [RFC6839]: https://www.rfc-editor.org/rfc/rfc6839
+func (e *Codec) findSuffix(name string) *mediaType {
+	index := strings.LastIndexByte(name, '+')
+	if index < 0 || strings.IndexByte(name, '/') > index {
+		return nil
+	}
+
+	subtype := "/" + name[index+1:]
+	item, _ := sliceutil.At(e.types, func(i *mediaType, _ int) bool { return strings.HasSuffix(i.Name, subtype) })
+	return item
+}
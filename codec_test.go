@@ -41,6 +41,8 @@ func marshalXML(_ *Context, v any) ([]byte, error) { return xml.Marshal(v) }
 
 func unmarshalXML(r io.Reader, v any) error { return xml.NewDecoder(r).Decode(v) }
 
+func encodeJSON(_ *Context, w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }
+
 func newCodec(a *assert.Assertion) *Codec {
 	c := NewCodec()
 	a.NotNil(c)
@@ -50,7 +52,8 @@ func newCodec(a *assert.Assertion) *Codec {
 		//AddCompressor(nil).
 		AddMimetype(header.JSON, marshalJSON, unmarshalJSON, "application/problem+json", true, true).
 		AddMimetype(header.XML, marshalXML, unmarshalXML, "application/problem+xml", true, true).
-		AddMimetype("application/test", marshalTest, unmarshalTest, "application/problem+test", true, true)
+		AddMimetype("application/test", marshalTest, unmarshalTest, "application/problem+test", true, true).
+		AddMimetype("application/stream", marshalJSON, unmarshalJSON, "application/problem+stream", true, true, encodeJSON)
 
 	return c
 }
@@ -78,6 +81,15 @@ func TestCodec_AddMimetype(t *testing.T) {
 	a.PanicString(func() {
 		c.AddMimetype(header.JSON, marshalJSON, unmarshalJSON, "", true, true)
 	}, "存在重复的项 "+header.JSON)
+
+	a.PanicString(func() {
+		c.AddMimetype(header.XML, marshalXML, unmarshalXML, "", true, true, encodeJSON, encodeJSON)
+	}, "参数 enc 最多只能指定一个")
+
+	a.NotPanic(func() {
+		c.AddMimetype("application/stream", marshalJSON, unmarshalJSON, "", true, true, encodeJSON)
+	})
+	a.NotNil(c.types[1].Encode).Nil(c.types[0].Encode)
 }
 
 func TestBuildCompression(t *testing.T) {
@@ -88,13 +100,38 @@ func TestBuildCompression(t *testing.T) {
 		Length(c.types, 0).
 		Length(c.wildcardSuffix, 0)
 
-	c = buildCompression(compressor.NewGzip(gzip.DefaultCompression), []string{"text"})
+	c = buildCompression(compressor.NewGzip(gzip.DefaultCompression), &CompressionOptions{Types: []string{"text"}})
 	a.Equal(c.types, []string{"text"})
 
-	c = buildCompression(compressor.NewGzip(gzip.DefaultCompression), []string{"text", "*"})
+	c = buildCompression(compressor.NewGzip(gzip.DefaultCompression), &CompressionOptions{Types: []string{"text", "*"}})
 	a.Nil(c.types).
 		True(c.wildcard).
 		Nil(c.wildcardSuffix)
+
+	c = buildCompression(compressor.NewGzip(gzip.DefaultCompression), &CompressionOptions{
+		Types:   []string{"text/*", "application/*"},
+		Exclude: []string{"application/zip", "image/*"},
+		MinSize: 1024,
+	})
+	a.Equal(c.minSize, 1024).
+		Equal(c.exclude, []string{"application/zip"}).
+		Equal(c.excludeWildcardSuffix, []string{"image/"}).
+		True(c.match("text/plain")).
+		True(c.match("application/json")).
+		False(c.match("application/zip")).
+		False(c.match("image/png"))
+
+	c = buildCompression(compressor.NewGzip(gzip.DefaultCompression), &CompressionOptions{Exclude: []string{"image/*"}})
+	a.True(c.match("application/zip")).
+		False(c.match("image/png"))
+
+	c = buildCompression(compressor.NewGzip(gzip.DefaultCompression), &CompressionOptions{
+		Types:   []string{"text/*"},
+		Exclude: []string{"image/*", "*"},
+	})
+	a.True(c.excludeWildcard).
+		False(c.match("text/plain")).
+		False(c.match("image/png"))
 }
 
 func TestCodec_contentEncoding(t *testing.T) {
@@ -221,6 +258,10 @@ func TestCodec_contentType(t *testing.T) {
 	// 未指定 charset 参数
 	f, e, err = mt.contentType("application/octet-stream; invalid-params")
 	a.NotError(err).NotNil(f).Nil(e)
+
+	// 结构化后缀
+	f, e, err = mt.contentType("application/vnd.example+octet-stream; charset=utf-8")
+	a.NotError(err).NotNil(f).Nil(e)
 }
 
 func TestCodec_accept(t *testing.T) {
@@ -228,10 +269,10 @@ func TestCodec_accept(t *testing.T) {
 	mt := NewCodec()
 	a.NotNil(mt)
 
-	item := mt.accept(header.JSON)
+	item, _, _ := mt.accept(header.JSON)
 	a.Nil(item)
 
-	item = mt.accept("")
+	item, _, _ = mt.accept("")
 	a.Nil(item)
 
 	mt = NewCodec()
@@ -239,34 +280,56 @@ func TestCodec_accept(t *testing.T) {
 	mt.AddMimetype(header.JSON, marshalJSON, unmarshalJSON, "", true, true).
 		AddMimetype(header.Plain, marshalXML, unmarshalXML, "text/plain+problem", true, true)
 
-	item = mt.accept(header.JSON)
+	item, _, _ = mt.accept(header.JSON)
 	a.NotNil(item).
 		NotNil(item.Marshal).
 		Equal(item.name(false), header.JSON).
 		Equal(item.name(true), header.JSON)
 
 	// */*
-	item = mt.accept("*/*")
+	item, _, _ = mt.accept("*/*")
 	a.NotNil(item).
 		NotNil(item.Marshal).
 		Equal(item.name(false), header.JSON)
 
 	// 空参数，结果同 */*
-	item = mt.accept("")
+	item, _, _ = mt.accept("")
 	a.NotNil(item).
 		NotNil(item.Marshal).
 		Equal(item.name(false), header.JSON)
 
-	item = mt.accept("*/*,text/plain")
+	item, _, _ = mt.accept("*/*,text/plain")
 	a.NotNil(item).
 		NotNil(item.Marshal).
 		Equal(item.name(false), header.Plain).
 		Equal(item.name(true), "text/plain+problem")
 
-	item = mt.accept("font/wottf;q=x.9")
+	item, _, _ = mt.accept("font/wottf;q=x.9")
 	a.Nil(item)
 
-	item = mt.accept("font/wottf")
+	item, _, _ = mt.accept("font/wottf")
+	a.Nil(item)
+
+	// 结构化后缀
+	item, name, params := mt.accept("application/vnd.example.v2+json;q=0.9;version=2")
+	a.NotNil(item).
+		Equal(item.name(false), header.JSON).
+		Equal(name, "application/vnd.example.v2+json").
+		Equal(params, "q=0.9;version=2")
+
+	item, name, params = mt.accept("application/json;v=1")
+	a.NotNil(item).
+		Equal(item.name(false), header.JSON).
+		Equal(name, header.JSON).
+		Equal(params, "v=1")
+
+	// 通配符和 problem 不返回 name
+	item, name, params = mt.accept("text/*;v=1")
+	a.NotNil(item).Equal(item.name(false), header.Plain).Empty(name).Empty(params)
+	item, name, params = mt.accept("text/plain+problem")
+	a.NotNil(item).Equal(item.name(false), header.Plain).Empty(name).Empty(params)
+
+	item, _, _ = mt.accept("application/vnd.example+cbor")
 	a.Nil(item)
 }
 
@@ -309,4 +372,14 @@ func TestCodec_findMarshal(t *testing.T) {
 	// 不存在
 	item = mm.findMarshal("xx/*")
 	a.Nil(item)
+
+	// 结构化后缀
+	item = mm.findMarshal("application/vnd.api+json")
+	a.NotNil(item).Equal(item.name(false), header.JSON)
+	item = mm.findMarshal("application/vnd.api+text")
+	a.NotNil(item).Equal(item.name(false), "text/text")
+	item = mm.findMarshal("application/vnd.api+xml")
+	a.Nil(item)
+	item = mm.findMarshal("application+json/vnd")
+	a.Nil(item)
 }
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package compressor
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var adaptiveEncoderPool = &sync.Pool{New: func() any { return &adaptiveEncoder{} }}
+
+type (
+	adaptiveCompressor struct {
+		levels     []Compressor
+		maxActive  int
+		maxLatency time.Duration
+
+		active  atomic.Int64 // 正在进行的编码数量
+		latency atomic.Int64 // 最近编码每 KB 内容耗时的加权平均值
+	}
+
+	adaptiveEncoder struct {
+		io.WriteCloser
+		c       *adaptiveCompressor
+		w       io.Writer     // 压缩后的内容写入的对象
+		elapsed time.Duration // 写入、刷新和关闭操作的耗时
+		blocked time.Duration // elapsed 中向 w 写入的耗时
+		size    int64         // 写入的未压缩内容的字节数
+	}
+
+	// 作为 adaptiveEncoder.WriteCloser 的输出对象，统计向 w 写入的耗时。
+	adaptiveOutput adaptiveEncoder
+)
+
+// NewAdaptive 声明根据负载自动切换压缩等级的压缩算法
+//
+// 负载较低时采用压缩率高的等级，负载较高时则切换到速度快的等级。
+//
+// maxActive 同时进行编码的数量达到此值时采用 c 的最后一项，0 表示不以此作为判断依据；
+// maxLatency 最近编码每 KB 内容的平均耗时达到此值时采用 c 的最后一项，0 表示不以此作为判断依据，
+// 耗时为单个编码器所有写入、刷新和关闭操作的时间之和，但不包含向下层写入的时间，
+// 即仅统计压缩本身的耗时，不受网络速度和响应内容大小的影响；
+// c 为同一压缩算法的不同等级，按压缩率从高到低排列，比如：
+//
+//	NewAdaptive(100, 0, NewGzip(gzip.BestCompression), NewGzip(gzip.DefaultCompression), NewGzip(gzip.BestSpeed))
+//
+// 负载介于两者之间时，按比例选择 c 中的元素。解码始终由 c 的第一项进行。
+func NewAdaptive(maxActive int, maxLatency time.Duration, c ...Compressor) Compressor {
+	if len(c) == 0 {
+		panic("参数 c 不能为空")
+	}
+
+	for _, item := range c[1:] {
+		if item.Name() != c[0].Name() {
+			panic(fmt.Sprintf("参数 c 中的压缩算法名称必须相同，%s 与 %s 不同", item.Name(), c[0].Name()))
+		}
+	}
+
+	if maxActive < 0 {
+		panic("参数 maxActive 不能小于 0")
+	}
+
+	if maxLatency < 0 {
+		panic("参数 maxLatency 不能小于 0")
+	}
+
+	return &adaptiveCompressor{
+		levels:     c,
+		maxActive:  maxActive,
+		maxLatency: maxLatency,
+	}
+}
+
+func (c *adaptiveCompressor) Name() string { return c.levels[0].Name() }
+
+func (c *adaptiveCompressor) NewDecoder(r io.Reader) (io.ReadCloser, error) {
+	return c.levels[0].NewDecoder(r)
+}
+
+func (c *adaptiveCompressor) NewEncoder(w io.Writer) (io.WriteCloser, error) {
+	e := adaptiveEncoderPool.Get().(*adaptiveEncoder)
+	ww, err := c.level(c.active.Add(1)).NewEncoder((*adaptiveOutput)(e))
+	if err != nil {
+		c.active.Add(-1)
+		adaptiveEncoderPool.Put(e)
+		return nil, err
+	}
+
+	e.WriteCloser = ww
+	e.c = c
+	e.w = w
+	e.elapsed = 0
+	e.blocked = 0
+	e.size = 0
+	return e, nil
+}
+
+// 根据负载选择压缩等级
+//
+// active 为包含当前在内的正在进行的编码数量。
+func (c *adaptiveCompressor) level(active int64) Compressor {
+	var load float64
+	if c.maxActive > 0 {
+		load = float64(active) / float64(c.maxActive)
+	}
+	if c.maxLatency > 0 {
+		load = max(load, float64(c.latency.Load())/float64(c.maxLatency))
+	}
+
+	return c.levels[min(int(load*float64(len(c.levels))), len(c.levels)-1)]
+}
+
+// 记录一次编码的耗时
+//
+// elapsed 为压缩 size 字节内容的耗时，最终换算成每 KB 的耗时。
+func (c *adaptiveCompressor) done(elapsed time.Duration, size int64) {
+	c.active.Add(-1)
+	if size <= 0 { // 没有内容，无法计算。
+		return
+	}
+
+	latency := max(int64(elapsed)*1024/size, 1) // 不能为 0，0 表示尚未有记录。
+
+	// 采用 1/8 的权重计算加权平均值，并发时可能会丢失部分数据，但不影响整体的趋势。
+	if old := c.latency.Load(); old == 0 {
+		c.latency.Store(latency)
+	} else {
+		c.latency.Store(old + (latency-old)/8)
+	}
+}
+
+func (e *adaptiveEncoder) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := e.WriteCloser.Write(p)
+	e.elapsed += time.Since(start)
+	e.size += int64(n)
+	return n, err
+}
+
+func (o *adaptiveOutput) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := o.w.Write(p)
+	o.blocked += time.Since(start)
+	return n, err
+}
+
+// Flush 刷新缓存的内容
+//
+// 如果底层的压缩算法不支持该操作，则不作任何处理。
+func (e *adaptiveEncoder) Flush() error {
+	if f, ok := e.WriteCloser.(interface{ Flush() error }); ok {
+		start := time.Now()
+		err := f.Flush()
+		e.elapsed += time.Since(start)
+		return err
+	}
+	return nil
+}
+
+func (e *adaptiveEncoder) Close() error {
+	start := time.Now()
+	err := e.WriteCloser.Close()
+	e.elapsed += time.Since(start)
+	e.c.done(e.elapsed-e.blocked, e.size)
+
+	e.WriteCloser = nil
+	e.c = nil
+	e.w = nil
+	adaptiveEncoderPool.Put(e)
+	return err
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package compressor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestNewAdaptive(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.PanicString(func() {
+		NewAdaptive(0, 0)
+	}, "参数 c 不能为空")
+
+	a.PanicString(func() {
+		NewAdaptive(0, 0, NewGzip(gzip.BestCompression), NewZstd())
+	}, "参数 c 中的压缩算法名称必须相同")
+
+	a.PanicString(func() {
+		NewAdaptive(-1, 0, NewGzip(gzip.BestCompression))
+	}, "参数 maxActive 不能小于 0")
+
+	a.PanicString(func() {
+		NewAdaptive(0, -1, NewGzip(gzip.BestCompression))
+	}, "参数 maxLatency 不能小于 0")
+
+	c := NewAdaptive(10, time.Second, NewGzip(gzip.BestCompression), NewGzip(gzip.BestSpeed))
+	a.Equal(c.Name(), "gzip")
+	testCompress(a, c, NewGzip(gzip.DefaultCompression))
+}
+
+func TestAdaptiveCompressor_level(t *testing.T) {
+	a := assert.New(t, false)
+
+	best, def, speed := NewGzip(gzip.BestCompression), NewGzip(gzip.DefaultCompression), NewGzip(gzip.BestSpeed)
+
+	// 未指定任何限制
+	c := NewAdaptive(0, 0, best, def, speed).(*adaptiveCompressor)
+	a.Equal(c.level(1), best).Equal(c.level(100), best)
+
+	// maxActive
+	c = NewAdaptive(6, 0, best, def, speed).(*adaptiveCompressor)
+	a.Equal(c.level(1), best).
+		Equal(c.level(2), def).
+		Equal(c.level(3), def).
+		Equal(c.level(4), speed).
+		Equal(c.level(6), speed).
+		Equal(c.level(100), speed)
+
+	// maxLatency
+	c = NewAdaptive(0, time.Second, best, def, speed).(*adaptiveCompressor)
+	a.Equal(c.level(1), best)
+	c.latency.Store(int64(500 * time.Millisecond))
+	a.Equal(c.level(1), def)
+	c.latency.Store(int64(2 * time.Second))
+	a.Equal(c.level(1), speed)
+
+	// 取两者的最大值
+	c = NewAdaptive(6, time.Second, best, def, speed).(*adaptiveCompressor)
+	c.latency.Store(int64(500 * time.Millisecond))
+	a.Equal(c.level(1), def).Equal(c.level(5), speed)
+}
+
+func TestAdaptiveCompressor_NewEncoder(t *testing.T) {
+	a := assert.New(t, false)
+	c := NewAdaptive(2, 0, NewGzip(gzip.BestCompression), NewGzip(gzip.BestSpeed)).(*adaptiveCompressor)
+
+	w1, err := c.NewEncoder(&bytes.Buffer{})
+	a.NotError(err).NotNil(w1).Equal(c.active.Load(), 1)
+
+	w2, err := c.NewEncoder(&bytes.Buffer{})
+	a.NotError(err).NotNil(w2).Equal(c.active.Load(), 2)
+
+	_, err = w1.Write([]byte("123"))
+	a.NotError(err).
+		NotError(w1.(interface{ Flush() error }).Flush()).
+		NotError(w1.Close()).
+		Equal(c.active.Load(), 1).
+		True(c.latency.Load() > 0)
+
+	a.NotError(w2.Close()).Equal(c.active.Load(), 0)
+}
+
+// 向下层写入的耗时不计入压缩耗时，且耗时按内容大小换算。
+func TestAdaptiveCompressor_latency(t *testing.T) {
+	a := assert.New(t, false)
+
+	write := func(size int) int64 {
+		c := NewAdaptive(0, time.Millisecond, NewGzip(gzip.BestSpeed)).(*adaptiveCompressor)
+		w, err := c.NewEncoder(&slowWriter{delay: 100 * time.Millisecond})
+		a.NotError(err).NotNil(w)
+
+		data := bytes.Repeat([]byte("0123456789abcdef"), size/16)
+		for i := 0; i < len(data); i += 4096 {
+			_, err = w.Write(data[i : i+4096])
+			a.NotError(err)
+		}
+		a.NotError(w.Close())
+		return c.latency.Load()
+	}
+
+	// 包含写入时间的话，每 KB 的耗时至少为 100ms*1024/16K，即 6.25ms。
+	l := write(16 << 10)
+	a.True(l > 0).True(l < int64(5*time.Millisecond), l)
+
+	// 大内容的总耗时超过 maxLatency，但每 KB 的耗时不会。
+	l = write(1 << 20)
+	a.True(l > 0).True(l < int64(time.Millisecond), l)
+}
+
+type slowWriter struct {
+	delay time.Duration
+	bytes.Buffer
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return w.Buffer.Write(p)
+}
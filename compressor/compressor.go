@@ -273,6 +273,16 @@ func (e *encoder) Close() error {
 	return err
 }
 
+// Flush 刷新缓存的内容
+//
+// 如果底层的压缩算法不支持该操作，则不作任何处理。
+func (e *encoder) Flush() error {
+	if f, ok := e.WriteCloser.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
 func (d *decoder) Close() error {
 	d.destroy()
 	err := d.ReadCloser.Close()
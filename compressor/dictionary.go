@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package compressor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/andybalholm/brotli/matchfinder"
+	"github.com/klauspost/compress/zstd"
+)
+
+// MaxDictionarySize 字典的最大字节数
+//
+// brotli 的窗口大小为 16M，字典和需要压缩的内容都应该在此窗口之内。
+const MaxDictionarySize = 8 << 20
+
+// dcb 在字典之后可以引用的最大距离
+const dcbMaxDistance = 1 << 20
+
+var (
+	dczMagic = []byte{0x5e, 0x2a, 0x4d, 0x18, 0x20, 0x00, 0x00, 0x00}
+	dcbMagic = []byte{0xff, 0x44, 0x43, 0x42}
+
+	errDictionaryRequired = errors.New("需要指定字典")
+	errDictionaryMismatch = errors.New("内容与字典不匹配")
+)
+
+type (
+	// Dictionary 共享的压缩字典
+	//
+	// 由 [Compression Dictionary Transport] 定义，
+	// 客户端将之前的某个响应内容作为字典，之后的请求可以基于该字典进行压缩。
+	//
+	// [Compression Dictionary Transport]: https://datatracker.ietf.org/doc/rfc9842/
+	Dictionary struct {
+		data []byte
+		hash [sha256.Size]byte
+
+		writers sync.Map // 以压缩算法的名称为键名，值为 *sync.Pool。
+	}
+
+	// DictionaryCompressor 基于共享字典的压缩算法
+	//
+	// 在未指定字典的情况下，[Compressor.NewEncoder] 和 [Compressor.NewDecoder] 始终返回错误。
+	DictionaryCompressor interface {
+		Compressor
+
+		// NewDictionaryDecoder 以 d 为字典将 r 包装成为当前压缩算法的解码器
+		NewDictionaryDecoder(r io.Reader, d *Dictionary) (io.ReadCloser, error)
+
+		// NewDictionaryEncoder 以 d 为字典将 w 包装成当前压缩算法的编码器
+		NewDictionaryEncoder(w io.Writer, d *Dictionary) (io.WriteCloser, error)
+	}
+
+	dczCompressor struct{}
+
+	dcbCompressor struct{}
+
+	// 在查找匹配项之前先将字典加入到历史记录中
+	dictionaryMatchFinder struct {
+		matchfinder.M4
+		dict   []byte
+		primed bool
+	}
+)
+
+// NewDictionary 声明 [Dictionary] 对象
+//
+// data 为字典的内容，长度不能超过 [MaxDictionarySize]。
+func NewDictionary(data []byte) *Dictionary {
+	if len(data) > MaxDictionarySize {
+		panic(fmt.Sprintf("参数 data 的长度不能超过 %d", MaxDictionarySize))
+	}
+	return &Dictionary{data: data, hash: sha256.Sum256(data)}
+}
+
+// Bytes 字典的内容
+func (d *Dictionary) Bytes() []byte { return d.data }
+
+// Hash 字典内容的 SHA-256 值
+//
+// 即客户端 Available-Dictionary 报头中的值。
+func (d *Dictionary) Hash() []byte { return d.hash[:] }
+
+func (d *Dictionary) writerPool(name string, f func() any) *sync.Pool {
+	p, _ := d.writers.LoadOrStore(name, &sync.Pool{New: f})
+	return p.(*sync.Pool)
+}
+
+func (d *Dictionary) writeHeader(w io.Writer, magic []byte) error {
+	if _, err := w.Write(magic); err != nil {
+		return err
+	}
+	_, err := w.Write(d.hash[:])
+	return err
+}
+
+func (d *Dictionary) readHeader(r io.Reader, magic []byte) error {
+	buf := make([]byte, len(magic)+sha256.Size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(buf[:len(magic)], magic) || !bytes.Equal(buf[len(magic):], d.hash[:]) {
+		return errDictionaryMismatch
+	}
+	return nil
+}
+
+// NewDCZ 声明基于字典的 zstd 压缩算法
+//
+// 在 http 报头中名称为 dcz。
+func NewDCZ() DictionaryCompressor { return &dczCompressor{} }
+
+func (c *dczCompressor) Name() string { return "dcz" }
+
+func (c *dczCompressor) NewDecoder(io.Reader) (io.ReadCloser, error) {
+	return nil, errDictionaryRequired
+}
+
+func (c *dczCompressor) NewEncoder(io.Writer) (io.WriteCloser, error) {
+	return nil, errDictionaryRequired
+}
+
+func (c *dczCompressor) NewDictionaryDecoder(r io.Reader, d *Dictionary) (io.ReadCloser, error) {
+	if err := d.readHeader(r, dczMagic); err != nil {
+		return nil, err
+	}
+
+	rr, err := zstd.NewReader(r, zstd.WithDecoderDictRaw(0, d.data), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return rr.IOReadCloser(), nil
+}
+
+func (c *dczCompressor) NewDictionaryEncoder(w io.Writer, d *Dictionary) (io.WriteCloser, error) {
+	if err := d.writeHeader(w, dczMagic); err != nil {
+		return nil, err
+	}
+
+	p := d.writerPool(c.Name(), func() any {
+		ww, err := zstd.NewWriter(nil, zstd.WithEncoderDictRaw(0, d.data), zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			panic(err)
+		}
+		return ww
+	})
+	ww := p.Get().(*zstd.Encoder)
+	ww.Reset(w)
+	return wrapEncoder(ww, func() { p.Put(ww) }), nil
+}
+
+// NewDCB 声明基于字典的 brotli 压缩算法
+//
+// 在 http 报头中名称为 dcb。
+//
+// NOTE: 仅支持压缩，[DictionaryCompressor.NewDictionaryDecoder] 始终返回 [errors.ErrUnsupported]。
+func NewDCB() DictionaryCompressor { return &dcbCompressor{} }
+
+func (c *dcbCompressor) Name() string { return "dcb" }
+
+func (c *dcbCompressor) NewDecoder(io.Reader) (io.ReadCloser, error) {
+	return nil, errDictionaryRequired
+}
+
+func (c *dcbCompressor) NewEncoder(io.Writer) (io.WriteCloser, error) {
+	return nil, errDictionaryRequired
+}
+
+func (c *dcbCompressor) NewDictionaryDecoder(io.Reader, *Dictionary) (io.ReadCloser, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (c *dcbCompressor) NewDictionaryEncoder(w io.Writer, d *Dictionary) (io.WriteCloser, error) {
+	if err := d.writeHeader(w, dcbMagic); err != nil {
+		return nil, err
+	}
+
+	p := d.writerPool(c.Name(), func() any {
+		return &matchfinder.Writer{
+			MatchFinder: &dictionaryMatchFinder{
+				M4: matchfinder.M4{
+					MaxDistance:     len(d.data) + dcbMaxDistance,
+					ChainLength:     8,
+					HashLen:         5,
+					DistanceBitCost: 66,
+				},
+				dict: d.data,
+			},
+			Encoder:   &brotli.Encoder{},
+			BlockSize: 1 << 16,
+		}
+	})
+	ww := p.Get().(*matchfinder.Writer)
+	ww.Reset(w)
+	return wrapEncoder(ww, func() { p.Put(ww) }), nil
+}
+
+// FindMatches 实现 [matchfinder.MatchFinder] 接口
+//
+// 字典相当于是内容之前的数据，超出已输出内容的引用将指向字典。
+func (f *dictionaryMatchFinder) FindMatches(dst []matchfinder.Match, src []byte) []matchfinder.Match {
+	if !f.primed {
+		dst = f.M4.FindMatches(dst[:0], f.dict)
+		f.primed = true
+	}
+	return f.M4.FindMatches(dst[:0], src)
+}
+
+// Reset 实现 [matchfinder.MatchFinder] 接口
+func (f *dictionaryMatchFinder) Reset() {
+	f.M4.Reset()
+	f.primed = false
+}
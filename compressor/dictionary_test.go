@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package compressor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/issue9/assert/v4"
+)
+
+var (
+	_ DictionaryCompressor = &dczCompressor{}
+	_ DictionaryCompressor = &dcbCompressor{}
+)
+
+var (
+	testDictionaryData = strings.Repeat(`{"id":1,"name":"name","description":"dictionary"}`, 10) + "abcdefghijklmnopqrstuvwxyz"
+	testDictionaryText = `{"id":2,"name":"name","description":"dictionary","letters":"abcdefghijklmnopqrstuvwxyz"}`
+)
+
+func TestNewDictionary(t *testing.T) {
+	a := assert.New(t, false)
+
+	d := NewDictionary([]byte("123"))
+	hash := sha256.Sum256([]byte("123"))
+	a.Equal(d.Bytes(), []byte("123")).
+		Equal(d.Hash(), hash[:])
+
+	a.PanicString(func() {
+		NewDictionary(make([]byte, MaxDictionarySize+1))
+	}, "参数 data 的长度不能超过")
+}
+
+func TestDCZ(t *testing.T) {
+	a := assert.New(t, false)
+	c := NewDCZ()
+	a.Equal(c.Name(), "dcz")
+	d := NewDictionary([]byte(testDictionaryData))
+
+	_, err := c.NewEncoder(&bytes.Buffer{})
+	a.Equal(err, errDictionaryRequired)
+	_, err = c.NewDecoder(&bytes.Buffer{})
+	a.Equal(err, errDictionaryRequired)
+
+	for range 2 { // 第二次从对象池中获取
+		buf := &bytes.Buffer{}
+		w, err := c.NewDictionaryEncoder(buf, d)
+		a.NotError(err).NotNil(w)
+		_, err = w.Write([]byte(testDictionaryText))
+		a.NotError(err).NotError(w.Close())
+
+		data := buf.Bytes()
+		a.Equal(data[:len(dczMagic)], dczMagic).
+			Equal(data[len(dczMagic):len(dczMagic)+sha256.Size], d.Hash())
+
+		r, err := c.NewDictionaryDecoder(bytes.NewReader(data), d)
+		a.NotError(err).NotNil(r)
+		content, err := io.ReadAll(r)
+		a.NotError(err).Equal(string(content), testDictionaryText).
+			NotError(r.Close())
+
+		// 字典不匹配
+		_, err = c.NewDictionaryDecoder(bytes.NewReader(data), NewDictionary([]byte("123")))
+		a.Equal(err, errDictionaryMismatch)
+	}
+}
+
+func TestDCB(t *testing.T) {
+	a := assert.New(t, false)
+	c := NewDCB()
+	a.Equal(c.Name(), "dcb")
+	d := NewDictionary([]byte(testDictionaryData))
+
+	_, err := c.NewEncoder(&bytes.Buffer{})
+	a.Equal(err, errDictionaryRequired)
+	_, err = c.NewDecoder(&bytes.Buffer{})
+	a.Equal(err, errDictionaryRequired)
+	_, err = c.NewDictionaryDecoder(&bytes.Buffer{}, d)
+	a.True(errors.Is(err, errors.ErrUnsupported))
+
+	for range 2 { // 第二次从对象池中获取
+		buf := &bytes.Buffer{}
+		w, err := c.NewDictionaryEncoder(buf, d)
+		a.NotError(err).NotNil(w)
+		_, err = w.Write([]byte(testDictionaryText))
+		a.NotError(err).NotError(w.Close())
+
+		data := buf.Bytes()
+		a.Equal(data[:len(dcbMagic)], dcbMagic).
+			Equal(data[len(dcbMagic):len(dcbMagic)+sha256.Size], d.Hash())
+		data = data[len(dcbMagic)+sha256.Size:]
+		a.True(len(data) < len(testDictionaryText))
+
+		r := brotli.NewReader(bytes.NewReader(prependBrotliDictionary(d.Bytes(), data)))
+		content, err := io.ReadAll(r)
+		a.NotError(err).Equal(string(content), testDictionaryData+testDictionaryText)
+	}
+}
+
+// 将 dict 以未压缩块的形式放在 data 之前
+//
+// 字典相当于是已经输出的内容，这样就可以用普通的 brotli 解码器验证 dcb 的内容。
+// dict 的长度不能超过 65536。
+func prependBrotliDictionary(dict, data []byte) []byte {
+	// WBITS(4) + ISLAST(1) + MNIBBLES(2) + MLEN-1(16) + ISUNCOMPRESSED(1) 正好 3 个字节
+	l := len(dict) - 1
+	var bits uint32 = 0b1111 | 0<<4 | 0b00<<5 | uint32(l)<<7 | 1<<23
+	out := []byte{byte(bits), byte(bits >> 8), byte(bits >> 16)}
+	out = append(out, dict...)
+
+	// data 中同样以 4 比特的 WBITS 开头，需要去掉。
+	for i := range data {
+		b := data[i] >> 4
+		if i+1 < len(data) {
+			b |= data[i+1] << 4
+		}
+		out = append(out, b)
+	}
+	return out
+}
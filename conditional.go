@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web/internal/qheader"
+)
+
+// PreconditionFailed 决定何时可返回 412 状态码
+//
+// 是 [NotModified] 在 PUT、PATCH 和 DELETE 等非安全请求方法下的对应版本，可用于实现乐观锁。
+// 在执行 mutation 之前，根据 If-Match 和 If-Unmodified-Since 报头判断资源是否已被修改，
+// 如果已被修改则返回 [ProblemPreconditionFailed]。
+//
+// required 为 true 时，PUT、PATCH 和 DELETE 请求必须包含 If-Match 或 If-Unmodified-Since 报头，
+// 否则返回 [ProblemPreconditionRequired]；
+//
+// version 返回资源当前的版本信息，其原型为：
+//
+//	func()(etag string, modtime time.Time, err error)
+//
+// etag 表示资源的 ETag，需要包含双引号，为空表示资源不存在，弱验证的 ETag 始终无法通过 If-Match 的验证；
+// modtime 表示资源的最后修改时间，零值表示不参与判断；
+// err 不为空时，会按照 [Context.Error] 的规则返回 [Problem]；
+//
+// mutation 为验证通过之后执行的操作；
+//
+// 如果需要生成 openapi 文档，可以采用 [github.com/issue9/web/openapi.Operation.Precondition]。
+func PreconditionFailed(required bool, version func() (string, time.Time, error), mutation func() Responser) Responser {
+	return ResponserFunc(func(ctx *Context) {
+		r := ctx.Request()
+
+		if required && isUnsafeMethod(r) &&
+			r.Header.Get(header.IfMatch) == "" && r.Header.Get(header.IfUnmodifiedSince) == "" {
+			ctx.Problem(ProblemPreconditionRequired).Apply(ctx)
+			return
+		}
+
+		etag, modtime, err := version()
+		if err != nil {
+			ctx.Error(err, "").Apply(ctx)
+			return
+		}
+
+		if checkUnmodified(r, etag, modtime) == condFalse {
+			ctx.Problem(ProblemPreconditionFailed).Apply(ctx)
+			return
+		}
+
+		if resp := mutation(); resp != nil {
+			resp.Apply(ctx)
+		}
+	})
+}
+
+// 条件请求报头的判断结果
+const (
+	condNone  = iota // 未指定报头或是报头无法判断
+	condTrue         // 满足条件
+	condFalse        // 不满足条件
+)
+
+// 时间是否可以用于 Last-Modified 等报头的比较
+func isZeroTime(t time.Time) bool { return t.IsZero() || t.Equal(time.Unix(0, 0)) }
+
+func isGetOrHead(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// 需要通过 [PreconditionFailed] 保护的请求方法
+func isUnsafeMethod(r *http.Request) bool {
+	return r.Method == http.MethodPut || r.Method == http.MethodPatch || r.Method == http.MethodDelete
+}
+
+func checkIfMatch(r *http.Request, etag string) int {
+	h := r.Header.Get(header.IfMatch)
+	if h == "" {
+		return condNone
+	}
+
+	if qheader.MatchETag(h, etag, false) {
+		return condTrue
+	}
+	return condFalse
+}
+
+func checkIfUnmodifiedSince(r *http.Request, modtime time.Time) int {
+	h := r.Header.Get(header.IfUnmodifiedSince)
+	if h == "" || isZeroTime(modtime) {
+		return condNone
+	}
+
+	t, err := http.ParseTime(h)
+	if err != nil {
+		return condNone
+	}
+
+	if modtime.Truncate(time.Second).After(t) {
+		return condFalse
+	}
+	return condTrue
+}
+
+func checkIfNoneMatch(r *http.Request, etag string) int {
+	h := r.Header.Get(header.IfNoneMatch)
+	if h == "" {
+		return condNone
+	}
+
+	if qheader.MatchETag(h, etag, true) {
+		return condFalse
+	}
+	return condTrue
+}
+
+func checkIfModifiedSince(r *http.Request, modtime time.Time) int {
+	h := r.Header.Get(header.IfModifiedSince)
+	if h == "" || isZeroTime(modtime) || !isGetOrHead(r) {
+		return condNone
+	}
+
+	t, err := http.ParseTime(h)
+	if err != nil {
+		return condNone
+	}
+
+	if modtime.Truncate(time.Second).After(t) {
+		return condTrue
+	}
+	return condFalse
+}
+
+func checkIfRange(r *http.Request, etag string, modtime time.Time) int {
+	h := r.Header.Get(header.IfRange)
+	if h == "" || !isGetOrHead(r) {
+		return condNone
+	}
+
+	if strings.HasPrefix(h, `"`) || strings.HasPrefix(h, "W/") {
+		if qheader.MatchETag(h, etag, false) {
+			return condTrue
+		}
+		return condFalse
+	}
+
+	if isZeroTime(modtime) {
+		return condFalse
+	}
+	t, err := http.ParseTime(h)
+	if err != nil || t.Unix() != modtime.Unix() {
+		return condFalse
+	}
+	return condTrue
+}
+
+// 根据 If-Match 或是 If-Unmodified-Since 判断内容是否未被修改
+//
+// 仅在 If-Match 不存在时才判断 If-Unmodified-Since。
+func checkUnmodified(r *http.Request, etag string, modtime time.Time) int {
+	if c := checkIfMatch(r, etag); c != condNone {
+		return c
+	}
+	return checkIfUnmodifiedSince(r, modtime)
+}
+
+// 根据条件请求的报头判断是否需要输出内容
+//
+// 判断顺序参考 RFC9110 的 13.2.2 节。
+// etag 为内容的 ETag，包含双引号，弱验证时带 W/ 前缀，为空表示不存在；
+// modtime 为内容的最后修改时间，零值表示不存在；
+// 返回值表示需要输出的状态码，0 表示需要正常输出内容。
+func checkPreconditions(r *http.Request, etag string, modtime time.Time) int {
+	if checkUnmodified(r, etag, modtime) == condFalse {
+		return http.StatusPreconditionFailed
+	}
+
+	switch checkIfNoneMatch(r, etag) {
+	case condFalse:
+		if isGetOrHead(r) {
+			return http.StatusNotModified
+		}
+		return http.StatusPreconditionFailed
+	case condNone:
+		if checkIfModifiedSince(r, modtime) == condFalse {
+			return http.StatusNotModified
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/mux/v9/types"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	a := assert.New(t, false)
+
+	modtime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	before := modtime.Add(-time.Hour).Format(http.TimeFormat)
+	after := modtime.Add(time.Hour).Format(http.TimeFormat)
+
+	data := []*struct {
+		method string
+		etag   string
+		h      []string
+		status int
+	}{
+		{method: http.MethodGet, etag: `"v1"`, status: 0},
+		{method: http.MethodGet, etag: `"v1"`, h: []string{header.IfMatch, `"v1"`}, status: 0},
+		{method: http.MethodGet, etag: `"v1"`, h: []string{header.IfMatch, "*"}, status: 0},
+		{method: http.MethodGet, etag: `W/"v1"`, h: []string{header.IfMatch, `W/"v1"`}, status: http.StatusPreconditionFailed},
+		{method: http.MethodGet, etag: "", h: []string{header.IfMatch, "*"}, status: http.StatusPreconditionFailed},
+		{method: http.MethodGet, etag: `"v1"`, h: []string{header.IfUnmodifiedSince, before}, status: http.StatusPreconditionFailed},
+		{method: http.MethodGet, etag: `"v1"`, h: []string{header.IfUnmodifiedSince, after}, status: 0},
+		// If-Match 存在时忽略 If-Unmodified-Since
+		{method: http.MethodGet, etag: `"v1"`, h: []string{header.IfMatch, `"v1"`, header.IfUnmodifiedSince, before}, status: 0},
+
+		{method: http.MethodGet, etag: `"v1"`, h: []string{header.IfNoneMatch, `W/"v1"`}, status: http.StatusNotModified},
+		{method: http.MethodHead, etag: `"v1"`, h: []string{header.IfNoneMatch, "*"}, status: http.StatusNotModified},
+		{method: http.MethodPost, etag: `"v1"`, h: []string{header.IfNoneMatch, `"v1"`}, status: http.StatusPreconditionFailed},
+		{method: http.MethodGet, etag: `"v1"`, h: []string{header.IfModifiedSince, after}, status: http.StatusNotModified},
+		{method: http.MethodGet, etag: `"v1"`, h: []string{header.IfModifiedSince, before}, status: 0},
+		{method: http.MethodPost, etag: `"v1"`, h: []string{header.IfModifiedSince, after}, status: 0},
+		// If-None-Match 存在时忽略 If-Modified-Since
+		{method: http.MethodGet, etag: `"v1"`, h: []string{header.IfNoneMatch, `"v2"`, header.IfModifiedSince, after}, status: 0},
+	}
+
+	for i, item := range data {
+		r := httptest.NewRequest(item.method, "/", nil)
+		for j := 0; j < len(item.h); j += 2 {
+			r.Header.Set(item.h[j], item.h[j+1])
+		}
+		a.Equal(checkPreconditions(r, item.etag, modtime), item.status, "at %d", i)
+	}
+}
+
+func TestPreconditionFailed(t *testing.T) {
+	a := assert.New(t, false)
+	s := newTestServer(a)
+
+	modtime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	var versionErr error
+	version := func() (string, time.Time, error) { return `"v1"`, modtime, versionErr }
+
+	serve := func(method string, required bool, h ...string) (*httptest.ResponseRecorder, bool) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/p", nil)
+		r.Header.Set(header.Accept, header.JSON)
+		for i := 0; i < len(h); i += 2 {
+			r.Header.Set(h[i], h[i+1])
+		}
+
+		executed := false
+		ctx := s.NewContext(w, r, types.NewContext())
+		ctx.apply(PreconditionFailed(required, version, func() Responser {
+			executed = true
+			return NoContent()
+		}))
+		s.freeContext(ctx)
+		return w, executed
+	}
+
+	w, executed := serve(http.MethodPut, false)
+	a.Equal(w.Code, http.StatusNoContent).True(executed)
+
+	w, executed = serve(http.MethodPut, false, header.IfMatch, `"v1"`)
+	a.Equal(w.Code, http.StatusNoContent).True(executed)
+
+	w, executed = serve(http.MethodPut, false, header.IfMatch, `"v0", "v2"`)
+	a.Equal(w.Code, http.StatusPreconditionFailed).False(executed).
+		Equal(w.Header().Get(header.ContentType), "application/problem+json; charset=utf-8").
+		Contains(w.Body.String(), ProblemPreconditionFailed)
+
+	w, executed = serve(http.MethodDelete, false, header.IfUnmodifiedSince, modtime.Add(-time.Second).Format(http.TimeFormat))
+	a.Equal(w.Code, http.StatusPreconditionFailed).False(executed)
+
+	w, executed = serve(http.MethodDelete, false, header.IfUnmodifiedSince, modtime.Format(http.TimeFormat))
+	a.Equal(w.Code, http.StatusNoContent).True(executed)
+
+	// required
+
+	w, executed = serve(http.MethodPatch, true)
+	a.Equal(w.Code, http.StatusPreconditionRequired).False(executed).
+		Contains(w.Body.String(), ProblemPreconditionRequired)
+
+	w, executed = serve(http.MethodPatch, true, header.IfMatch, `"v1"`)
+	a.Equal(w.Code, http.StatusNoContent).True(executed)
+
+	w, executed = serve(http.MethodPost, true) // POST 不要求条件请求
+	a.Equal(w.Code, http.StatusNoContent).True(executed)
+
+	// version 返回错误
+
+	versionErr = fs.ErrNotExist
+	w, executed = serve(http.MethodPut, false, header.IfMatch, `"v1"`)
+	a.Equal(w.Code, http.StatusNotFound).False(executed)
+
+	versionErr = errors.New("500")
+	w, executed = serve(http.MethodPut, false, header.IfMatch, `"v1"`)
+	a.Equal(w.Code, http.StatusInternalServerError).False(executed)
+}
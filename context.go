@@ -27,6 +27,9 @@ import (
 
 var errExitContext = NewLocaleError("exit context")
 
+// 回收 [Context] 时可保留的 Context.buffer 最大容量
+const maxBufferSize = 64 * 1024
+
 var contextPool = &sync.Pool{
 	New: func() any {
 		return &Context{
@@ -46,6 +49,7 @@ func ErrExitContext() error { return errExitContext }
 // 而是采用返回 [Responser] 的方式向客户端输出内容。
 type Context struct {
 	s       *InternalServer
+	config  *routerConfig
 	route   types.Route
 	request *http.Request
 	exits   []OnExitContextFunc
@@ -58,15 +62,30 @@ type Context struct {
 
 	originResponse    http.ResponseWriter // 原始的 http.ResponseWriter
 	writer            io.Writer
+	compressWriter    io.Writer // 由 outputCompressor 生成的 io.Writer，用于 FlushError
 	outputCompressor  compressor.Compressor
+	compressMinSize   int    // 大于 0 表示在等待内容达到此值之后再决定是否压缩
+	buffer            []byte // 在决定是否压缩之前缓存的内容
 	outputCharset     encoding.Encoding
 	outputCharsetName string
 	outputMimetype    *mediaType
-	status            int // WriteHeader 保存的副本
+	acceptName        string     // 客户端请求的媒体类型，为空表示采用 outputMimetype.Name。
+	acceptParams      string     // 客户端请求的媒体类型的原始参数
+	mediaType         *MediaType // 由 acceptName 和 acceptParams 解析而来，在调用 MediaType 时才初始化。
+	status            int        // WriteHeader 保存的副本
+	contentLength     int64      // 由 ServeContent 指定的内容长度，小于 0 表示未知。
 	wrote             bool
+	autoETag          bool          // 是否由 Render 自动生成 ETag
+	recorder          *recordWriter // 记录压缩和字符集转换之前的输出内容
+
+	// 压缩字典的相关内容
+	dictionary      *compressor.Dictionary // 客户端通过 Available-Dictionary 指定的字典
+	useAsDictionary *UseAsDictionary       // 为空表示不将输出内容作为字典
+	dictionaryData  []byte                 // 作为字典的输出内容
 
 	// 从客户端提交的 Content-Type 报头解析到的内容
 	inputMimetype UnmarshalFunc
+	body          requestBody // 对 request.Body 的包装，处理解压和大小限制。
 	requestBody   io.Reader
 
 	// 区域和本地相关信息
@@ -93,48 +112,63 @@ func (s *InternalServer) NewContext(w http.ResponseWriter, r *http.Request, rout
 		return s.server.Logs().DEBUG()
 	}
 
-	h := r.Header.Get(header.Accept)
-	mt := s.codec.accept(h) // 空值相当于 */*，如果正确设置，肯定不会返回 nil。
-	if mt == nil {
-		debug().LocaleString(Phrase("not found serialization for %s", h))
+	conf := s.routers.config(route)
+	n := s.negotiate(conf, r)
+	if n.mimetype == nil { // 空值相当于 */*，如果正确设置，肯定不会返回 nil。
+		debug().LocaleString(Phrase("not found serialization for %s", r.Header.Get(header.Accept)))
 		w.WriteHeader(http.StatusNotAcceptable) // 此时还不知道将 problem 序列化成什么类型，只简单地返回状态码。
 		return nil
 	}
 
-	h = r.Header.Get(header.AcceptCharset)
-	outputCharsetName, outputCharset := qheader.ParseAcceptCharset(h)
-	if outputCharsetName == "" {
-		debug().LocaleString(Phrase("not found charset for %s", h))
+	if n.charsetName == "" {
+		debug().LocaleString(Phrase("not found charset for %s", r.Header.Get(header.AcceptCharset)))
 		w.WriteHeader(http.StatusNotAcceptable) // 无法找到对方要求的字符集，依然只是简单地返回状态码。
 		return nil
 	}
 
 	var outputCompressor compressor.Compressor
+	var dictionary *compressor.Dictionary
 	if s.server.CanCompress() {
-		var na bool
-		if outputCompressor, na = s.codec.acceptEncoding(mt.name(false), r.Header.Get(header.AcceptEncoding)); na {
+		if n.encodingNotAcceptable {
 			w.WriteHeader(http.StatusNotAcceptable)
 			return nil
 		}
+		outputCompressor = n.compressor
+
+		if n.dictionaryCompressor != nil {
+			if dictionary = s.availableDictionary(r); dictionary != nil {
+				outputCompressor = n.dictionaryCompressor
+			}
+		}
+	}
+
+	ctx := contextPool.Get().(*Context)
+	ctx.body.reset(r, conf.codec, conf.bodyLimit)
+	for _, name := range ctx.body.encodings {
+		if conf.codec.findCompression(name) == nil {
+			contextPool.Put(ctx)
+			debug().LocaleString(Phrase("not found compress for %s", name))
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return nil
+		}
 	}
 
-	var inputReader io.Reader = r.Body // 作为服务端使用，Body 始终不为空，且不需要调用 Close，所以类型为 io.Reader 就可以了。
+	var inputReader io.Reader = &ctx.body // 作为服务端使用，Body 始终不为空，且不需要调用 Close，所以类型为 io.Reader 就可以了。
 	var inputMimetype UnmarshalFunc
-	if h = r.Header.Get(header.ContentType); h != "" {
+	if h := r.Header.Get(header.ContentType); h != "" {
 		var err error
 		var inputCharset encoding.Encoding
-		if inputMimetype, inputCharset, err = s.codec.contentType(h); err != nil {
+		if inputMimetype, inputCharset, err = conf.codec.contentType(h); err != nil {
+			contextPool.Put(ctx)
 			debug().Error(err)
 			w.WriteHeader(http.StatusUnsupportedMediaType)
 			return nil
 		}
 		if !qheader.CharsetIsNop(inputCharset) {
-			inputReader = transform.NewReader(inputReader, inputCharset.NewDecoder())
+			inputReader = &transformBody{Reader: transform.NewReader(inputReader, inputCharset.NewDecoder()), body: &ctx.body}
 		}
 	}
 
-	tag := acceptLanguage(s.server, r.Header.Get(header.AcceptLanguage))
-
 	// 以上是获取构建 Context 的必要参数，并未真正构建 Context，
 	// 保证 ID 不为空无任何意义，因为没有后续的执行链可追踪的。
 	// 此处开始才会构建 Context 对象，须确保 ID 不为空。
@@ -146,8 +180,8 @@ func (s *InternalServer) NewContext(w http.ResponseWriter, r *http.Request, rout
 
 	// NOTE: ctx 是从对象池中获取的，所有变量都必须初始化。
 
-	ctx := contextPool.Get().(*Context)
 	ctx.s = s
+	ctx.config = conf
 	ctx.route = route
 	ctx.request = r
 	ctx.exits = ctx.exits[:0]
@@ -160,20 +194,29 @@ func (s *InternalServer) NewContext(w http.ResponseWriter, r *http.Request, rout
 
 	ctx.originResponse = w
 	ctx.writer = w
+	ctx.compressWriter = nil
 	ctx.outputCompressor = outputCompressor
-	ctx.outputCharset = outputCharset
-	ctx.outputCharsetName = outputCharsetName
-	ctx.outputMimetype = mt
+	ctx.compressMinSize = 0
+	ctx.buffer = ctx.buffer[:0]
+	ctx.dictionary = dictionary
+	ctx.useAsDictionary = nil
+	ctx.dictionaryData = ctx.dictionaryData[:0]
+	ctx.outputCharset = n.charset
+	ctx.outputCharsetName = n.charsetName
+	ctx.outputMimetype = n.mimetype
+	ctx.acceptName = n.acceptName
+	ctx.acceptParams = n.acceptParams
+	ctx.mediaType = nil
 	ctx.status = 0
+	ctx.contentLength = -1
 	ctx.wrote = false
-	if ctx.outputCompressor != nil {
-		ctx.Header().Set(header.ContentEncoding, ctx.outputCompressor.Name())
-	}
+	ctx.autoETag = conf.autoETag
+	ctx.recorder = nil
 
 	ctx.inputMimetype = inputMimetype
 	ctx.requestBody = inputReader
-	ctx.languageTag = tag
-	ctx.localePrinter = s.server.Locale().NewPrinter(tag)
+	ctx.languageTag = n.languageTag
+	ctx.localePrinter = s.server.Locale().NewPrinter(n.languageTag)
 	clear(ctx.vars)
 	ctx.logs = s.server.Logs().New(map[string]any{s.requestIDKey: id})
 
@@ -255,18 +298,51 @@ func (ctx *Context) SetMimetype(mimetype string) {
 		return
 	}
 
-	item := ctx.s.codec.accept(mimetype)
+	item, name, params := ctx.config.codec.accept(mimetype)
 	if item == nil {
 		panic(fmt.Sprintf("指定的编码 %s 不存在", mimetype))
 	}
 	ctx.outputMimetype = item
+	ctx.acceptName = name
+	ctx.acceptParams = params
+	ctx.mediaType = nil
 }
 
 // Mimetype 返回输出编码名称
 //
 // problem 表示是否返回 problem 状态时的值。
+// 返回的是通过 [Codec.AddMimetype] 注册的名称，客户端实际请求的媒体类型可通过 [Context.MediaType] 获取。
 func (ctx *Context) Mimetype(problem bool) string { return ctx.outputMimetype.name(problem) }
 
+// MediaType 客户端通过 Accept 报头请求的媒体类型
+//
+// 如果 Accept 报头为空或是由通配符匹配，返回的是 [Context.Mimetype] 的相关信息。
+// 返回对象在当前请求中是共享的，不应该修改其内容。
+func (ctx *Context) MediaType() *MediaType {
+	if ctx.mediaType == nil {
+		name := ctx.acceptName
+		if name == "" {
+			name = ctx.Mimetype(false)
+		}
+		ctx.mediaType = newMediaType(name, ctx.acceptParams)
+	}
+	return ctx.mediaType
+}
+
+// 输出时 Content-Type 报头中的媒体类型
+//
+// 原样返回客户端请求的媒体类型，包括参数。
+func (ctx *Context) contentType() string {
+	switch {
+	case ctx.acceptName == "":
+		return ctx.Mimetype(false)
+	case ctx.acceptParams == "":
+		return ctx.acceptName
+	default:
+		return ctx.MediaType().String()
+	}
+}
+
 // SetEncoding 设置输出的压缩编码
 //
 // 不会修改 [Context.Request] 中的 Accept-Encoding 报头，如果需要同时修改此报头
@@ -279,19 +355,17 @@ func (ctx *Context) SetEncoding(enc string) {
 		return
 	}
 
-	c, notAcceptable := ctx.s.codec.acceptEncoding(ctx.Mimetype(false), enc)
+	c, notAcceptable := ctx.config.codec.acceptEncoding(ctx.Mimetype(false), enc)
 	if notAcceptable {
 		panic(fmt.Sprintf("指定的压缩编码 %s 不存在", enc))
 	}
 	ctx.outputCompressor = c
-
-	if ctx.outputCompressor != nil {
-		h := ctx.Header()
-		h.Set(header.ContentEncoding, c.Name())
-	}
 }
 
 // Encoding 输出的压缩编码名称
+//
+// 在输出状态码之前，返回的是协商的结果，最终是否压缩还取决于输出的内容，
+// 具体可参考 [CompressionOptions]。
 func (ctx *Context) Encoding() string {
 	if ctx.outputCompressor == nil {
 		return ""
@@ -316,6 +390,12 @@ func (ctx *Context) LocalePrinter() *message.Printer { return ctx.localePrinter
 func (ctx *Context) LanguageTag() language.Tag { return ctx.languageTag }
 
 func (s *InternalServer) freeContext(ctx *Context) {
+	if ctx.compressMinSize > 0 { // 内容未达到压缩的要求
+		if err := ctx.flushBuffer(false); err != nil {
+			ctx.Logs().ERROR().Error(err)
+		}
+	}
+
 	for _, exit := range ctx.exits {
 		exit(ctx, ctx.status)
 	}
@@ -329,6 +409,16 @@ func (s *InternalServer) freeContext(ctx *Context) {
 	close(ctx.done)
 	ctx.doneErr = ErrExitContext()
 
+	if err := ctx.body.close(); err != nil {
+		ctx.Logs().ERROR().Error(err)
+	}
+
+	if cap(ctx.buffer) > maxBufferSize {
+		ctx.buffer = nil
+	}
+	if cap(ctx.dictionaryData) > maxBufferSize {
+		ctx.dictionaryData = nil
+	}
 	logs.FreeAttrLogs(ctx.logs)
 	contextPool.Put(ctx)
 }
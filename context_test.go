@@ -132,6 +132,53 @@ func TestContext_SetMimetype(t *testing.T) {
 	}, "已有内容输出，不可再更改！")
 }
 
+func TestContext_MediaType(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+
+	// 结构化后缀
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set(header.Accept, "application/vnd.example+json;version=2;q=0.9")
+	ctx := srv.NewContext(w, r, types.NewContext())
+	a.NotNil(ctx).
+		Equal(ctx.Mimetype(false), header.JSON).
+		Equal(ctx.MediaType(), &MediaType{
+			Name:    "application/vnd.example+json",
+			Vendor:  "example",
+			Version: "2",
+			Suffix:  "json",
+			Params:  map[string]string{"version": "2"},
+		})
+	ctx.Render(http.StatusOK, 1)
+	a.Equal(w.Header().Get(header.ContentType), "application/vnd.example+json; version=2; charset=utf-8").
+		Equal(w.Body.String(), "1")
+
+	// problem 依然采用注册的类型
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set(header.Accept, "application/vnd.example.v3+json")
+	ctx = srv.NewContext(w, r, types.NewContext())
+	a.NotNil(ctx).
+		Equal(ctx.MediaType().Version, "3")
+	ctx.Problem(ProblemBadRequest).Apply(ctx)
+	a.Equal(w.Header().Get(header.ContentType), "application/problem+json; charset=utf-8")
+
+	// 通配符
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set(header.Accept, "*/*")
+	ctx = srv.NewContext(w, r, types.NewContext())
+	a.NotNil(ctx).
+		Equal(ctx.MediaType(), &MediaType{Name: ctx.Mimetype(false)})
+
+	ctx.SetMimetype("application/vnd.example.v1+xml")
+	a.Equal(ctx.Mimetype(false), header.XML).
+		Equal(ctx.MediaType().Version, "1")
+	ctx.Render(http.StatusOK, 1)
+	a.Equal(w.Header().Get(header.ContentType), "application/vnd.example.v1+xml; charset=utf-8")
+}
+
 func TestContext_SetCharset(t *testing.T) {
 	a := assert.New(t, false)
 	srv := newTestServer(a)
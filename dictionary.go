@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/issue9/cache"
+	"github.com/jellydator/ttlcache/v3"
+
+	"github.com/issue9/web/compressor"
+	"github.com/issue9/web/internal/qheader"
+)
+
+const (
+	useAsDictionaryHeader     = "Use-As-Dictionary"
+	availableDictionaryHeader = "Available-Dictionary"
+
+	dictionaryCachePrefix = "web-dictionary:"
+
+	// 已加载的字典在内存中保留的数量和时间
+	dictionaryLoadedSize = 50
+	dictionaryLoadedTTL  = time.Minute
+
+	// 未指定 [UseAsDictionary.TTL] 时字典在缓存中的保存时间
+	dictionaryDefaultTTL = 24 * time.Hour
+)
+
+type (
+	// UseAsDictionary 将响应内容作为压缩字典
+	//
+	// 会向客户端输出 Use-As-Dictionary 报头，并将成功输出的内容保存至 [Server.Cache]。
+	// 客户端在之后请求与 Match 相匹配的地址时，会通过 Available-Dictionary 报头指定该字典，
+	// 此时可以采用 [compressor.NewDCZ] 和 [compressor.NewDCB] 等基于字典的压缩方法。
+	//
+	// 可通过 [Context.SetUseAsDictionary] 指定，或是作为中间件应用于单个路由项：
+	//
+	//	router.Get("/app.v1.js", handler, &web.UseAsDictionary{Match: "/app.*.js"})
+	//
+	// 超过 [compressor.MaxDictionarySize] 的内容不会被保存。
+	UseAsDictionary struct {
+		// 适用该字典的地址，URL Pattern 格式，不能为空。
+		Match string
+
+		// 适用该字典的请求目标，即 Sec-Fetch-Dest 报头的值，可以为空。
+		MatchDest []string
+
+		// 字典的 ID，可以为空。
+		//
+		// 客户端在之后的请求中会通过 Dictionary-ID 报头原样返回。
+		ID string
+
+		// 字典在缓存中的保存时间
+		//
+		// 0 表示采用默认值 24 小时，不能小于 0。
+		// 每一个不同的输出内容都会作为一个字典保存，对于内容经常变化的地址，应该指定较短的时间。
+		TTL time.Duration
+	}
+
+	// 对保存在缓存中的字典进行管理
+	dictionaries struct {
+		cache  cache.Cache
+		loaded *ttlcache.Cache[string, *compressor.Dictionary] // 从 cache 中加载的字典，避免每次都重新加载。
+	}
+
+	// 收集作为字典的输出内容
+	dictionaryWriter Context
+)
+
+// Middleware 实现 [Middleware] 接口
+func (d *UseAsDictionary) Middleware(next HandlerFunc, _, _, _ string) HandlerFunc {
+	return func(ctx *Context) Responser {
+		ctx.SetUseAsDictionary(d)
+		return next(ctx)
+	}
+}
+
+// 生成 Use-As-Dictionary 报头的内容
+func (d *UseAsDictionary) header() string {
+	var b strings.Builder
+	b.WriteString("match=")
+	writeSFString(&b, d.Match)
+
+	if len(d.MatchDest) > 0 {
+		b.WriteString(", match-dest=(")
+		for i, dest := range d.MatchDest {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			writeSFString(&b, dest)
+		}
+		b.WriteByte(')')
+	}
+
+	if d.ID != "" {
+		b.WriteString(", id=")
+		writeSFString(&b, d.ID)
+	}
+
+	return b.String()
+}
+
+// 以结构化报头中字符串的格式输出 s
+func writeSFString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for _, c := range []byte(s) {
+		if c == '"' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte('"')
+}
+
+func newDictionaries(c cache.Cache) *dictionaries {
+	return &dictionaries{
+		cache: cache.Prefix(c, dictionaryCachePrefix),
+		loaded: ttlcache.New(
+			ttlcache.WithCapacity[string, *compressor.Dictionary](dictionaryLoadedSize),
+			ttlcache.WithTTL[string, *compressor.Dictionary](dictionaryLoadedTTL),
+		),
+	}
+}
+
+// 获取 SHA-256 值为 hash 的字典，不存在时返回 nil。
+func (d *dictionaries) load(hash []byte) (*compressor.Dictionary, error) {
+	key := hex.EncodeToString(hash)
+	if item := d.loaded.Get(key); item != nil {
+		return item.Value(), nil
+	}
+
+	var data []byte
+	if err := d.cache.Get(key, &data); err != nil {
+		if errors.Is(err, cache.ErrCacheMiss()) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	dict := compressor.NewDictionary(data)
+	if !bytes.Equal(dict.Hash(), hash) { // 缓存中的内容已被破坏
+		return nil, nil
+	}
+	d.loaded.Set(key, dict, ttlcache.DefaultTTL)
+	return dict, nil
+}
+
+// 将 data 作为字典保存至缓存
+//
+// ttl 为 0 表示采用 dictionaryDefaultTTL，已经存在相同内容的字典时不再保存。
+func (d *dictionaries) store(data []byte, ttl time.Duration) error {
+	if ttl == 0 {
+		ttl = dictionaryDefaultTTL
+	}
+
+	hash := sha256.Sum256(data)
+	key := hex.EncodeToString(hash[:])
+	if d.cache.Exists(key) {
+		return nil
+	}
+	return d.cache.Set(key, data, ttl)
+}
+
+// 根据 r 的 Available-Dictionary 报头获取字典
+func (s *InternalServer) availableDictionary(r *http.Request) *compressor.Dictionary {
+	hash, ok := qheader.ParseByteSequence(r.Header.Get(availableDictionaryHeader))
+	if !ok || len(hash) != sha256.Size {
+		return nil
+	}
+
+	d, err := s.dictionaries.load(hash)
+	if err != nil {
+		s.Logs().ERROR().Error(err)
+	}
+	return d
+}
+
+func (w *dictionaryWriter) Write(p []byte) (int, error) {
+	if w.useAsDictionary != nil {
+		if len(w.dictionaryData)+len(p) > compressor.MaxDictionarySize {
+			w.useAsDictionary = nil
+		} else {
+			w.dictionaryData = append(w.dictionaryData, p...)
+		}
+	}
+	return len(p), nil
+}
+
+// 根据状态码决定是否将输出内容作为字典
+func (ctx *Context) initUseAsDictionary(status int) {
+	if status >= http.StatusOK && status < http.StatusMultipleChoices {
+		ctx.Header().Set(useAsDictionaryHeader, ctx.useAsDictionary.header())
+	} else {
+		ctx.useAsDictionary = nil
+	}
+}
+
+func (ctx *Context) storeDictionary() {
+	if ctx.useAsDictionary == nil || len(ctx.dictionaryData) == 0 {
+		return
+	}
+
+	if err := ctx.s.dictionaries.store(bytes.Clone(ctx.dictionaryData), ctx.useAsDictionary.TTL); err != nil {
+		ctx.Logs().ERROR().Error(err)
+	}
+}
+
+// SetUseAsDictionary 将当前的输出内容作为压缩字典
+//
+// d 为空表示取消，需要在输出状态码之前调用才有效果。
+func (ctx *Context) SetUseAsDictionary(d *UseAsDictionary) {
+	if d != nil {
+		if d.Match == "" {
+			panic("参数 d.Match 不能为空")
+		}
+		if d.TTL < 0 {
+			panic("参数 d.TTL 不能小于 0")
+		}
+	}
+	ctx.useAsDictionary = d
+}
+
+// UseAsDictionary 是否将当前的输出内容作为压缩字典
+func (ctx *Context) UseAsDictionary() *UseAsDictionary { return ctx.useAsDictionary }
+
+// Dictionary 客户端通过 Available-Dictionary 报头指定的字典
+//
+// 仅在服务端存在该字典，且协商出了基于字典的压缩方法时才有值，否则返回 nil。
+// 最终是否采用基于字典的压缩方法，与 [Context.Encoding] 一样还取决于输出的内容。
+func (ctx *Context) Dictionary() *compressor.Dictionary { return ctx.dictionary }
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/cache/caches/memory"
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web/compressor"
+)
+
+var _ Middleware = &UseAsDictionary{}
+
+func TestUseAsDictionary_header(t *testing.T) {
+	a := assert.New(t, false)
+
+	d := &UseAsDictionary{Match: "/app.*.js"}
+	a.Equal(d.header(), `match="/app.*.js"`)
+
+	d = &UseAsDictionary{Match: `/a"b\c`, MatchDest: []string{"script", "style"}, ID: "v1"}
+	a.Equal(d.header(), `match="/a\"b\\c", match-dest=("script" "style"), id="v1"`)
+}
+
+func TestDictionaries(t *testing.T) {
+	a := assert.New(t, false)
+	d := newDictionaries(memory.New())
+
+	hash := sha256.Sum256([]byte("123"))
+	dict, err := d.load(hash[:])
+	a.NotError(err).Nil(dict)
+
+	a.NotError(d.store([]byte("123"), 0))
+	dict, err = d.load(hash[:])
+	a.NotError(err).NotNil(dict).
+		Equal(dict.Bytes(), []byte("123")).
+		Equal(dict.Hash(), hash[:])
+
+	// 从 loaded 中获取
+	dict2, err := d.load(hash[:])
+	a.NotError(err).Equal(dict2, dict)
+
+	// 过期
+	hash = sha256.Sum256([]byte("456"))
+	a.NotError(d.store([]byte("456"), time.Millisecond))
+	a.NotError(d.store([]byte("456"), 0)) // 已经存在，不会更新过期时间
+	time.Sleep(50 * time.Millisecond)
+	dict, err = d.load(hash[:])
+	a.NotError(err).Nil(dict)
+}
+
+func TestContext_dictionary(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+
+	dcz := compressor.NewDCZ()
+	c := NewCodec().
+		AddCompressor(dcz).
+		AddCompressor(compressor.NewGzip(gzip.DefaultCompression)).
+		AddMimetype(header.JSON, marshalJSON, unmarshalJSON, "application/problem+json", true, true)
+	router := srv.Routers().New("dictionary", nil, WithCodec(c))
+
+	dictContent := strings.Repeat("dictionary-", 20)
+	router.Get("/dict", func(ctx *Context) Responser { return OK(dictContent) },
+		&UseAsDictionary{Match: "/data*", ID: "v1"})
+	router.Get("/dict-not-found", func(ctx *Context) Responser {
+		ctx.SetUseAsDictionary(&UseAsDictionary{Match: "/data*"})
+		return ctx.NotFound()
+	})
+	router.Get("/data", func(ctx *Context) Responser { return OK(dictContent + "123") })
+
+	get := func(path string, h ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(header.Accept, header.JSON)
+		for i := 0; i < len(h); i += 2 {
+			r.Header.Set(h[i], h[i+1])
+		}
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	a.PanicString(func() {
+		ctx := srv.NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil)
+		ctx.SetUseAsDictionary(&UseAsDictionary{})
+	}, "参数 d.Match 不能为空")
+
+	a.PanicString(func() {
+		ctx := srv.NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil)
+		ctx.SetUseAsDictionary(&UseAsDictionary{Match: "/data*", TTL: -1})
+	}, "参数 d.TTL 不能小于 0")
+
+	// 生成字典
+
+	w := get("/dict")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(useAsDictionaryHeader), `match="/data*", id="v1"`).
+		Empty(w.Header().Get(header.ContentEncoding))
+	dictData := w.Body.Bytes()
+	hash := sha256.Sum256(dictData)
+	available := ":" + base64.StdEncoding.EncodeToString(hash[:]) + ":"
+	dict, err := srv.dictionaries.load(hash[:])
+	a.NotError(err).NotNil(dict).Equal(dict.Bytes(), dictData)
+
+	// 压缩的内容，字典是解压之后的内容。
+	w = get("/dict", header.AcceptEncoding, "gzip")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(header.ContentEncoding), "gzip").
+		NotEmpty(w.Header().Get(useAsDictionaryHeader))
+
+	// 非 2xx 不作为字典
+	w = get("/dict-not-found")
+	a.Equal(w.Code, http.StatusNotFound).
+		Empty(w.Header().Get(useAsDictionaryHeader))
+
+	// 采用字典压缩
+
+	w = get("/data", header.AcceptEncoding, "gzip, dcz", availableDictionaryHeader, available)
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(header.ContentEncoding), "dcz").
+		Contains(w.Header().Values(header.Vary), availableDictionaryHeader)
+	r, err := dcz.NewDictionaryDecoder(w.Body, dict)
+	a.NotError(err)
+	data, err := io.ReadAll(r)
+	a.NotError(err).Equal(string(data), `"`+dictContent+`123"`)
+
+	// 不存在的字典
+	hash = sha256.Sum256([]byte("not-exists"))
+	w = get("/data", header.AcceptEncoding, "gzip, dcz", availableDictionaryHeader, ":"+base64.StdEncoding.EncodeToString(hash[:])+":")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(header.ContentEncoding), "gzip").
+		NotContains(w.Header().Values(header.Vary), availableDictionaryHeader)
+
+	// 格式错误
+	w = get("/data", header.AcceptEncoding, "gzip, dcz", availableDictionaryHeader, "not-exists")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(header.ContentEncoding), "gzip")
+
+	// 未指定 dcz
+	w = get("/data", header.AcceptEncoding, "gzip", availableDictionaryHeader, available)
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(header.ContentEncoding), "gzip")
+
+	// 仅指定了 dcz，但是未提供字典。
+	w = get("/data", header.AcceptEncoding, "dcz")
+	a.Equal(w.Code, http.StatusOK).
+		Empty(w.Header().Get(header.ContentEncoding))
+	a.Equal(w.Body.String(), `"`+dictContent+`123"`)
+
+	// 禁用压缩
+	srv.SetCompress(false)
+	w = get("/data", header.AcceptEncoding, "gzip, dcz", availableDictionaryHeader, available)
+	a.Equal(w.Code, http.StatusOK).
+		Empty(w.Header().Get(header.ContentEncoding))
+	srv.SetCompress(true)
+}
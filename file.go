@@ -0,0 +1,394 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+)
+
+const multipartByteranges = "multipart/byteranges"
+
+// 用于检测内容类型的字节数，与 [http.DetectContentType] 相同。
+const sniffLen = 512
+
+var errInvalidRange = errors.New("invalid range")
+
+// ServeOptions [ServeContent] 和 [ServeFile] 的可选项
+type ServeOptions struct {
+	// 内容的 ETag 值
+	//
+	// 需要包含双引号，但是不需要 W/ 前缀，为空表示不输出 ETag 报头。
+	// 强验证的 ETag 要求内容逐字节相同，所以在压缩输出时，会在其后添加压缩方法的名称，
+	// 比如 "v1" 在采用 gzip 压缩时输出为 "v1-gzip"。
+	ETag string
+
+	// ETag 是否为弱验证
+	//
+	// 弱验证的 ETag 不能用于 If-Range 报头的比较。
+	WeakETag bool
+
+	// 内容的类型
+	//
+	// 为空表示根据文件的扩展名判断，如果依然无法判断，则根据内容判断。
+	ContentType string
+
+	// Content-Disposition 报头的类型
+	//
+	// 可以是 inline 或是 attachment，为空表示不输出该报头。
+	// 文件名取自 [ServeContent] 和 [ServeFile] 的 name 参数。
+	Disposition string
+}
+
+type httpRange struct {
+	start, length int64
+}
+
+// ServeContent 将 content 作为报文主体输出
+//
+// 与 [http.ServeContent] 相似，支持 Range、If-Range、If-Modified-Since 和 ETag 等报头，
+// 但是错误信息会以 [Problem] 的形式输出：
+//   - 请求的范围无法满足时，返回 [ProblemRequestedRangeNotSatisfiable]；
+//   - If-Match 或 If-Unmodified-Since 验证失败时，返回 [ProblemPreconditionFailed]；
+//
+// name 为内容的名称，用于判断内容的类型和生成 Content-Disposition 报头；
+// modtime 为内容的最后修改时间，零值表示不输出 Last-Modified 报头；
+// o 为可选项，可以为空；
+//
+// 内容会原样输出，不会进行字符集的转换。如果请求的是部分内容，也不会对内容进行压缩。
+func ServeContent(name string, modtime time.Time, content io.ReadSeeker, o *ServeOptions) Responser {
+	if o == nil {
+		o = &ServeOptions{}
+	}
+
+	return ResponserFunc(func(ctx *Context) { serveContent(ctx, name, modtime, content, o) })
+}
+
+// ServeFile 将 fsys 中的 name 文件作为报文主体输出
+//
+// 文件不存在或是 name 指向的是目录时返回 [ProblemNotFound]，其它与 [ServeContent] 相同。
+// 如果文件未实现 [io.Seeker]，会返回 [ProblemInternalServerError]。
+func ServeFile(fsys fs.FS, name string, o *ServeOptions) Responser {
+	if o == nil {
+		o = &ServeOptions{}
+	}
+
+	return ResponserFunc(func(ctx *Context) {
+		f, err := fsys.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			ctx.NotFound().Apply(ctx)
+			return
+		} else if err != nil {
+			ctx.Error(err, "").Apply(ctx)
+			return
+		}
+		defer f.Close()
+
+		stat, err := f.Stat()
+		if err != nil {
+			ctx.Error(err, "").Apply(ctx)
+			return
+		}
+		if stat.IsDir() {
+			ctx.NotFound().Apply(ctx)
+			return
+		}
+
+		rs, ok := f.(io.ReadSeeker)
+		if !ok {
+			ctx.Error(fmt.Errorf("文件 %s 未实现 io.Seeker", name), ProblemInternalServerError).Apply(ctx)
+			return
+		}
+
+		serveContent(ctx, name, stat.ModTime(), rs, o)
+	})
+}
+
+func serveContent(ctx *Context, name string, modtime time.Time, content io.ReadSeeker, o *ServeOptions) {
+	etag := o.ETag // 未压缩内容的 ETag
+	if etag != "" && o.WeakETag {
+		etag = "W/" + etag
+	}
+
+	h := ctx.Header()
+	if !isZeroTime(modtime) {
+		h.Set(header.LastModified, modtime.UTC().Format(http.TimeFormat))
+	}
+
+	size, err := content.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = content.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		ctx.Error(err, ProblemInternalServerError).Apply(ctx)
+		return
+	}
+
+	ct, err := detectContentType(name, content, o.ContentType)
+	if err != nil {
+		ctx.Error(err, ProblemInternalServerError).Apply(ctx)
+		return
+	}
+
+	// 计算需要输出的范围，范围是针对未压缩的内容，所以 If-Range 始终与未压缩内容的 ETag 比较。
+	var ranges []httpRange
+	var rangeErr error
+	if rh := ctx.Request().Header.Get(header.Range); rh != "" && checkIfRange(ctx.Request(), etag, modtime) != condFalse {
+		if ranges, rangeErr = parseRange(rh, size); rangeErr == nil && sumRangesSize(ranges) > size {
+			ranges = nil // 请求的内容比原始内容还大，直接输出全部内容。
+		}
+	}
+
+	// 只有输出全部内容时才会压缩，需要在比较 ETag 之前确定。
+	h.Set(header.ContentType, ct)
+	if ctx.outputCompressor != nil {
+		if c := ctx.outputCompression(http.StatusOK); len(ranges) > 0 || c == nil || size < int64(c.minSize) {
+			ctx.outputCompressor = nil
+		}
+	}
+
+	if etag != "" {
+		if !o.WeakETag && ctx.outputCompressor != nil {
+			etag = strings.TrimSuffix(etag, `"`) + "-" + ctx.outputCompressor.Name() + `"`
+		}
+		h.Set(header.ETag, etag)
+	}
+
+	switch checkPreconditions(ctx.Request(), etag, modtime) {
+	case http.StatusNotModified:
+		h.Del(header.ContentType)
+		h.Del(header.ContentLength)
+		ctx.WriteHeader(http.StatusNotModified)
+		return
+	case http.StatusPreconditionFailed:
+		ctx.Problem(ProblemPreconditionFailed).Apply(ctx)
+		return
+	}
+
+	if rangeErr != nil {
+		h.Set(header.ContentRange, "bytes */"+strconv.FormatInt(size, 10))
+		ctx.Problem(ProblemRequestedRangeNotSatisfiable).Apply(ctx)
+		return
+	}
+
+	h.Set(header.AcceptRanges, "bytes")
+	if o.Disposition != "" {
+		h.Set(header.ContentDisposition, buildContentDisposition(o.Disposition, path.Base(name)))
+	}
+
+	ctx.outputCharset = nil // 原样输出内容
+	head := ctx.Request().Method == http.MethodHead
+
+	switch len(ranges) {
+	case 0:
+		ctx.contentLength = size // 仅在不压缩时有效
+		ctx.WriteHeader(http.StatusOK)
+		if !head {
+			if _, err = io.Copy(ctx, content); err != nil {
+				ctx.Logs().ERROR().Error(err)
+			}
+		}
+	case 1:
+		ra := ranges[0]
+		h.Set(header.ContentRange, ra.contentRange(size))
+		ctx.contentLength = ra.length
+		ctx.WriteHeader(http.StatusPartialContent)
+		if !head {
+			if err = copyRange(ctx, content, ra); err != nil {
+				ctx.Logs().ERROR().Error(err)
+			}
+		}
+	default:
+		mw := multipart.NewWriter(ctx)
+		h.Set(header.ContentType, multipartByteranges+"; boundary="+mw.Boundary())
+		ctx.WriteHeader(http.StatusPartialContent)
+		if !head {
+			if err = writeMultipartRanges(mw, content, ranges, ct, size); err != nil {
+				ctx.Logs().ERROR().Error(err)
+			}
+		}
+	}
+}
+
+func writeMultipartRanges(mw *multipart.Writer, content io.ReadSeeker, ranges []httpRange, ct string, size int64) error {
+	for _, ra := range ranges {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			header.ContentType:  {ct},
+			header.ContentRange: {ra.contentRange(size)},
+		})
+		if err != nil {
+			return err
+		}
+
+		if err = copyRange(w, content, ra); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+func copyRange(w io.Writer, content io.ReadSeeker, ra httpRange) error {
+	if _, err := content.Seek(ra.start, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.CopyN(w, content, ra.length)
+	return err
+}
+
+// 获取内容的类型
+//
+// 如果需要根据内容判断，会在判断之后将 content 的位置重置到开始处。
+func detectContentType(name string, content io.ReadSeeker, ct string) (string, error) {
+	if ct != "" {
+		return ct, nil
+	}
+
+	if ct = mime.TypeByExtension(path.Ext(name)); ct != "" {
+		return ct, nil
+	}
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(content, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if _, err = content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+func (r httpRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+func sumRangesSize(ranges []httpRange) (size int64) {
+	for _, ra := range ranges {
+		size += ra.length
+	}
+	return size
+}
+
+// 解析 Range 报头
+//
+// 格式错误或是所有范围都无法满足时返回 [errInvalidRange]，
+// 部分范围无法满足时，会忽略这些范围。
+func parseRange(s string, size int64) ([]httpRange, error) {
+	const b = "bytes="
+	if !strings.HasPrefix(s, b) {
+		return nil, errInvalidRange
+	}
+
+	var ranges []httpRange
+	for ra := range strings.SplitSeq(s[len(b):], ",") {
+		if ra = textproto.TrimString(ra); ra == "" {
+			continue
+		}
+
+		start, end, ok := strings.Cut(ra, "-")
+		if !ok {
+			return nil, errInvalidRange
+		}
+		start, end = textproto.TrimString(start), textproto.TrimString(end)
+
+		var r httpRange
+		if start == "" { // -N 表示最后 N 个字节
+			if end == "" || end[0] == '-' {
+				return nil, errInvalidRange
+			}
+			n, err := strconv.ParseInt(end, 10, 64)
+			if err != nil || n < 0 {
+				return nil, errInvalidRange
+			}
+			if n == 0 {
+				continue
+			}
+			n = min(n, size)
+			r.start = size - n
+			r.length = n
+		} else {
+			i, err := strconv.ParseInt(start, 10, 64)
+			if err != nil || i < 0 {
+				return nil, errInvalidRange
+			}
+			if i >= size { // 无法满足的范围
+				continue
+			}
+			r.start = i
+
+			if end == "" { // N- 表示从 N 开始的所有字节
+				r.length = size - r.start
+			} else {
+				j, err := strconv.ParseInt(end, 10, 64)
+				if err != nil || r.start > j {
+					return nil, errInvalidRange
+				}
+				r.length = min(j, size-1) - r.start + 1
+			}
+		}
+		ranges = append(ranges, r)
+	}
+
+	if len(ranges) == 0 {
+		return nil, errInvalidRange
+	}
+	return ranges, nil
+}
+
+// 生成 Content-Disposition 报头
+//
+// 根据 RFC6266 的建议，同时输出 filename 和 filename*，
+// 其中 filename 仅包含 ASCII 字符，非 ASCII 字符以下划线代替。
+func buildContentDisposition(typ, filename string) string {
+	var ascii, ext strings.Builder
+	needExt := false
+	for _, c := range []byte(filename) {
+		switch {
+		case c >= 0x80:
+			needExt = true
+			if c >= 0xc0 { // UTF-8 的首字节
+				ascii.WriteByte('_')
+			}
+		case c == '"' || c == '\\':
+			ascii.WriteByte('\\')
+			ascii.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			needExt = true
+			ascii.WriteByte('_')
+		default:
+			ascii.WriteByte(c)
+		}
+
+		if isAttrChar(c) {
+			ext.WriteByte(c)
+		} else {
+			fmt.Fprintf(&ext, "%%%02X", c)
+		}
+	}
+
+	s := typ + `; filename="` + ascii.String() + `"`
+	if needExt {
+		s += "; filename*=UTF-8''" + ext.String()
+	}
+	return s
+}
+
+// 是否为 RFC5987 中的 attr-char
+func isAttrChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
@@ -0,0 +1,278 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/mux/v9/types"
+)
+
+func TestParseRange(t *testing.T) {
+	a := assert.New(t, false)
+
+	data := []*struct {
+		h      string
+		size   int64
+		ranges []httpRange
+		err    bool
+	}{
+		{h: "bytes=0-4", size: 10, ranges: []httpRange{{0, 5}}},
+		{h: "bytes=5-", size: 10, ranges: []httpRange{{5, 5}}},
+		{h: "bytes=-3", size: 10, ranges: []httpRange{{7, 3}}},
+		{h: "bytes=-20", size: 10, ranges: []httpRange{{0, 10}}},
+		{h: "bytes=8-20", size: 10, ranges: []httpRange{{8, 2}}},
+		{h: "bytes=0-1, 4-5", size: 10, ranges: []httpRange{{0, 2}, {4, 2}}},
+		{h: "bytes=0-1, 20-30", size: 10, ranges: []httpRange{{0, 2}}}, // 忽略无法满足的范围
+		{h: "bytes=20-30", size: 10, err: true},
+		{h: "bytes=-0", size: 10, err: true},
+		{h: "bytes=5-4", size: 10, err: true},
+		{h: "bytes=a-4", size: 10, err: true},
+		{h: "bytes=--4", size: 10, err: true},
+		{h: "bytes=4", size: 10, err: true},
+		{h: "items=0-4", size: 10, err: true},
+		{h: "bytes=", size: 10, err: true},
+	}
+
+	for _, item := range data {
+		ranges, err := parseRange(item.h, item.size)
+		if item.err {
+			a.Error(err, item.h).Empty(ranges)
+		} else {
+			a.NotError(err, item.h).Equal(ranges, item.ranges, item.h)
+		}
+	}
+}
+
+func TestBuildContentDisposition(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(buildContentDisposition("attachment", "abc.txt"), `attachment; filename="abc.txt"`)
+	a.Equal(buildContentDisposition("inline", `a"b.txt`), `inline; filename="a\"b.txt"`)
+	a.Equal(buildContentDisposition("attachment", "中文 1.txt"),
+		`attachment; filename="__ 1.txt"; filename*=UTF-8''%E4%B8%AD%E6%96%87%201.txt`)
+}
+
+func TestServeContent(t *testing.T) {
+	a := assert.New(t, false)
+	s := newTestServer(a)
+
+	const content = "0123456789abcdefghij"
+	modtime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	lastModified := modtime.Format(http.TimeFormat)
+
+	serve := func(method string, o *ServeOptions, h ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/file", nil)
+		r.Header.Set(header.Accept, header.JSON)
+		for i := 0; i < len(h); i += 2 {
+			r.Header.Set(h[i], h[i+1])
+		}
+		ctx := s.NewContext(w, r, types.NewContext())
+		ctx.apply(ServeContent("file.txt", modtime, strings.NewReader(content), o))
+		s.freeContext(ctx)
+		return w
+	}
+
+	o := &ServeOptions{ETag: `"v1"`}
+
+	// 全部内容
+
+	w := serve(http.MethodGet, o)
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Body.String(), content).
+		Equal(w.Header().Get(header.ContentType), "text/plain; charset=utf-8").
+		Equal(w.Header().Get(header.ETag), `"v1"`).
+		Equal(w.Header().Get(header.LastModified), lastModified).
+		Equal(w.Header().Get(header.AcceptRanges), "bytes").
+		Equal(w.Header().Get(header.ContentLength), "20").
+		Empty(w.Header().Get(header.ContentDisposition))
+
+	w = serve(http.MethodHead, o)
+	a.Equal(w.Code, http.StatusOK).Empty(w.Body.String()).
+		Equal(w.Header().Get(header.ContentLength), "20")
+
+	// 压缩
+
+	w = serve(http.MethodGet, o, header.AcceptEncoding, "gzip")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(header.ContentEncoding), "gzip").
+		Equal(w.Header().Get(header.ETag), `"v1-gzip"`).
+		Empty(w.Header().Get(header.ContentLength))
+
+	w = serve(http.MethodGet, o, header.AcceptEncoding, "gzip", header.IfNoneMatch, `"v1-gzip"`)
+	a.Equal(w.Code, http.StatusNotModified)
+
+	w = serve(http.MethodGet, o, header.AcceptEncoding, "gzip", header.IfNoneMatch, `"v1"`)
+	a.Equal(w.Code, http.StatusOK).Equal(w.Header().Get(header.ContentEncoding), "gzip")
+
+	w = serve(http.MethodGet, &ServeOptions{ETag: `"v1"`, WeakETag: true}, header.AcceptEncoding, "gzip")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(header.ContentEncoding), "gzip").
+		Equal(w.Header().Get(header.ETag), `W/"v1"`)
+
+	// 单个范围，不压缩。
+
+	w = serve(http.MethodGet, o, header.Range, "bytes=2-5", header.AcceptEncoding, "gzip")
+	a.Equal(w.Code, http.StatusPartialContent).
+		Equal(w.Body.String(), "2345").
+		Equal(w.Header().Get(header.ContentRange), "bytes 2-5/20").
+		Equal(w.Header().Get(header.ContentLength), "4").
+		Equal(w.Header().Get(header.ETag), `"v1"`).
+		Empty(w.Header().Get(header.ContentEncoding))
+
+	w = serve(http.MethodGet, o, header.Range, "bytes=2-5", header.AcceptEncoding, "gzip", header.IfRange, `"v1"`)
+	a.Equal(w.Code, http.StatusPartialContent).Equal(w.Body.String(), "2345")
+
+	// 多个范围
+
+	w = serve(http.MethodGet, o, header.Range, "bytes=0-1,-2")
+	a.Equal(w.Code, http.StatusPartialContent)
+	mt, params, err := mime.ParseMediaType(w.Header().Get(header.ContentType))
+	a.NotError(err).Equal(mt, multipartByteranges)
+	mr := multipart.NewReader(w.Body, params["boundary"])
+	for _, want := range []struct{ body, contentRange string }{
+		{"01", "bytes 0-1/20"},
+		{"ij", "bytes 18-19/20"},
+	} {
+		p, err := mr.NextPart()
+		a.NotError(err).
+			Equal(p.Header.Get(header.ContentType), "text/plain; charset=utf-8").
+			Equal(p.Header.Get(header.ContentRange), want.contentRange)
+		data, err := io.ReadAll(p)
+		a.NotError(err).Equal(string(data), want.body)
+	}
+	_, err = mr.NextPart()
+	a.Equal(err, io.EOF)
+
+	// 范围之和大于内容，输出全部内容。
+	w = serve(http.MethodGet, o, header.Range, "bytes=0-,0-")
+	a.Equal(w.Code, http.StatusOK).Equal(w.Body.String(), content)
+
+	// 无法满足的范围
+
+	w = serve(http.MethodGet, o, header.Range, "bytes=30-40")
+	a.Equal(w.Code, http.StatusRequestedRangeNotSatisfiable).
+		Equal(w.Header().Get(header.ContentRange), "bytes */20").
+		Equal(w.Header().Get(header.ContentType), "application/problem+json; charset=utf-8").
+		Contains(w.Body.String(), ProblemRequestedRangeNotSatisfiable)
+
+	// If-Range
+
+	w = serve(http.MethodGet, o, header.Range, "bytes=2-5", header.IfRange, `"v1"`)
+	a.Equal(w.Code, http.StatusPartialContent).Equal(w.Body.String(), "2345")
+
+	w = serve(http.MethodGet, o, header.Range, "bytes=2-5", header.IfRange, `"v2"`)
+	a.Equal(w.Code, http.StatusOK).Equal(w.Body.String(), content)
+
+	w = serve(http.MethodGet, o, header.Range, "bytes=2-5", header.IfRange, lastModified)
+	a.Equal(w.Code, http.StatusPartialContent).Equal(w.Body.String(), "2345")
+
+	w = serve(http.MethodGet, o, header.Range, "bytes=2-5", header.IfRange, modtime.Add(time.Hour).Format(http.TimeFormat))
+	a.Equal(w.Code, http.StatusOK).Equal(w.Body.String(), content)
+
+	// 弱验证的 ETag 不能用于 If-Range
+	weak := &ServeOptions{ETag: `"v1"`, WeakETag: true}
+	w = serve(http.MethodGet, weak, header.Range, "bytes=2-5", header.IfRange, `W/"v1"`)
+	a.Equal(w.Code, http.StatusOK).Equal(w.Body.String(), content)
+
+	// If-None-Match
+
+	w = serve(http.MethodGet, o, header.IfNoneMatch, `"v0", "v1"`)
+	a.Equal(w.Code, http.StatusNotModified).Empty(w.Body.String()).
+		Empty(w.Header().Get(header.ContentType))
+
+	w = serve(http.MethodGet, weak, header.IfNoneMatch, `"v1"`)
+	a.Equal(w.Code, http.StatusNotModified).Equal(w.Header().Get(header.ETag), `W/"v1"`)
+
+	w = serve(http.MethodGet, o, header.IfNoneMatch, `"v2"`, header.IfModifiedSince, lastModified)
+	a.Equal(w.Code, http.StatusOK)
+
+	// If-Modified-Since
+
+	w = serve(http.MethodGet, o, header.IfModifiedSince, lastModified)
+	a.Equal(w.Code, http.StatusNotModified)
+
+	w = serve(http.MethodGet, o, header.IfModifiedSince, modtime.Add(-time.Hour).Format(http.TimeFormat))
+	a.Equal(w.Code, http.StatusOK).Equal(w.Body.String(), content)
+
+	// If-Match
+
+	w = serve(http.MethodGet, o, header.IfMatch, `"v1"`)
+	a.Equal(w.Code, http.StatusOK)
+
+	w = serve(http.MethodGet, o, header.IfMatch, `"v2"`)
+	a.Equal(w.Code, http.StatusPreconditionFailed).
+		Contains(w.Body.String(), ProblemPreconditionFailed)
+
+	w = serve(http.MethodGet, o, header.IfUnmodifiedSince, modtime.Add(-time.Hour).Format(http.TimeFormat))
+	a.Equal(w.Code, http.StatusPreconditionFailed)
+
+	// Content-Disposition 和 ContentType
+
+	w = serve(http.MethodGet, &ServeOptions{Disposition: "attachment", ContentType: "application/x-test"})
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(header.ContentDisposition), `attachment; filename="file.txt"`).
+		Equal(w.Header().Get(header.ContentType), "application/x-test").
+		Empty(w.Header().Get(header.ETag))
+
+	// 根据内容判断类型
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/file", nil)
+	ctx := s.NewContext(w, r, types.NewContext())
+	ctx.apply(ServeContent("file", time.Time{}, strings.NewReader("<html><body></body></html>"), nil))
+	s.freeContext(ctx)
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(header.ContentType), "text/html; charset=utf-8").
+		Empty(w.Header().Get(header.LastModified)).
+		Equal(w.Body.String(), "<html><body></body></html>")
+}
+
+func TestServeFile(t *testing.T) {
+	a := assert.New(t, false)
+	s := newTestServer(a)
+
+	fsys := fstest.MapFS{
+		"dir/中文.txt": &fstest.MapFile{Data: []byte("0123456789"), ModTime: time.Now()},
+	}
+
+	serve := func(name string, h ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/file", nil)
+		r.Header.Set(header.Accept, header.JSON)
+		for i := 0; i < len(h); i += 2 {
+			r.Header.Set(h[i], h[i+1])
+		}
+		ctx := s.NewContext(w, r, types.NewContext())
+		ctx.apply(ServeFile(fsys, name, &ServeOptions{Disposition: "attachment"}))
+		s.freeContext(ctx)
+		return w
+	}
+
+	w := serve("dir/中文.txt")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Body.String(), "0123456789").
+		NotEmpty(w.Header().Get(header.LastModified)).
+		Equal(w.Header().Get(header.ContentDisposition), `attachment; filename="__.txt"; filename*=UTF-8''%E4%B8%AD%E6%96%87.txt`)
+
+	w = serve("dir/中文.txt", header.Range, "bytes=-3")
+	a.Equal(w.Code, http.StatusPartialContent).Equal(w.Body.String(), "789")
+
+	w = serve("dir/not-exists.txt")
+	a.Equal(w.Code, http.StatusNotFound)
+
+	w = serve("dir")
+	a.Equal(w.Code, http.StatusNotFound)
+}
@@ -0,0 +1,239 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/issue9/cache"
+	"github.com/issue9/mux/v9/header"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	idempotencyCachePrefix    = "web-idempotency:"
+	idempotencyLockKeySuffix  = ":lock"
+	idempotencyResponseSuffix = ":response"
+)
+
+type (
+	// Idempotency 为非安全的请求方法提供基于 Idempotency-Key 报头的幂等性支持
+	//
+	// 对于包含 Idempotency-Key 报头的请求，第一次请求的响应内容会保存在 [Server.Cache] 之中，
+	// 之后的重复请求直接返回保存的内容，而不再执行处理函数：
+	//   - 第一次请求还未完成时，重复的请求返回 [ProblemConflict]；
+	//   - 相同的 Idempotency-Key 但是提交的内容不同，返回 [ProblemUnprocessableEntity]；
+	//   - 重放的响应会包含 Idempotent-Replayed: true 报头；
+	//
+	// 由 [NewIdempotency] 声明，作为中间件应用于路由项：
+	//
+	//	i := web.NewIdempotency(s, 24*time.Hour, time.Minute, 1<<20, nil)
+	//	router.Post("/orders", handler, i)
+	//
+	// NOTE: 为了比较提交的内容，会将其全部读取到内存，大小受 [BodyLimit] 的限制。
+	// 对于 multipart/form-data 等上传大文件的请求，整个内容都会先缓存在内存中，不建议使用。
+	// 保存的是压缩和字符集转换之前的内容，重放时会根据当前请求的 Accept-Encoding 等报头重新协商。
+	// 5xx 的响应以及超过指定大小的响应不会被保存，客户端可以通过重试再次执行。
+	Idempotency struct {
+		cache     Cache
+		ttl       time.Duration
+		timeout   time.Duration
+		size      int
+		principal func(*Context) string
+	}
+
+	// 保存在缓存中的响应内容
+	idempotencyResponse struct {
+		Fingerprint []byte // 请求内容的哈希值
+		Status      int
+		Header      http.Header
+		Body        []byte
+	}
+
+	// 单次请求的状态
+	idempotencyState struct {
+		key         string
+		fingerprint []byte
+		w           *recordWriter
+	}
+)
+
+// NewIdempotency 声明 [Idempotency] 对象
+//
+// ttl 为响应内容在缓存中的保存时间；
+// timeout 为处理请求的最长时间，超过此值之后，即使第一次请求还未完成，也不再返回 [ProblemConflict]；
+// size 为可保存的响应内容的最大长度，超过此值的响应不会被保存；
+// principal 用于获取当前请求的用户标识，不同用户的 Idempotency-Key 相互独立，为空表示不区分用户；
+func NewIdempotency(s Server, ttl, timeout time.Duration, size int, principal func(*Context) string) *Idempotency {
+	if ttl <= 0 {
+		panic("参数 ttl 必须大于 0")
+	}
+	if timeout <= 0 {
+		panic("参数 timeout 必须大于 0")
+	}
+	if size <= 0 {
+		panic("参数 size 必须大于 0")
+	}
+
+	i := &Idempotency{
+		cache:     NewCache(idempotencyCachePrefix, s.Cache()),
+		ttl:       ttl,
+		timeout:   timeout,
+		size:      size,
+		principal: principal,
+	}
+	s.OnExitContext(i.exit) // 此时所有的内容都已经输出，包括压缩算法缓存的内容。
+	return i
+}
+
+// Middleware 实现 [Middleware] 接口
+//
+// GET、HEAD、OPTIONS 和 TRACE 请求不作任何处理。
+func (i *Idempotency) Middleware(next HandlerFunc, method, pattern, router string) HandlerFunc {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return next
+	}
+
+	return func(ctx *Context) Responser {
+		key := ctx.Request().Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			return next(ctx)
+		}
+
+		data, err := io.ReadAll(ctx.RequestBody())
+		if err != nil {
+			var lerr *BodyLimitError
+			if errors.As(err, &lerr) {
+				return ctx.Error(err, "").WithExtensions(lerr)
+			}
+			return ctx.Error(err, "")
+		}
+		ctx.requestBody = &transformBody{Reader: bytes.NewReader(data), body: &ctx.body}
+
+		var principal string
+		if i.principal != nil {
+			principal = i.principal(ctx)
+		}
+		key = idempotencyHash(router, method, pattern, principal, key)
+		fingerprint := idempotencyFingerprint(ctx.Request(), data)
+
+		if resp := i.replay(ctx, key, fingerprint); resp != nil {
+			return resp
+		}
+
+		_, inc, _, err := i.cache.Counter(key+idempotencyLockKeySuffix, i.timeout)
+		if err != nil {
+			return ctx.Error(err, "")
+		}
+		if n, err := inc(1); err != nil {
+			return ctx.Error(err, "")
+		} else if n > 1 { // 第一次请求还未完成
+			return ctx.Problem(ProblemConflict)
+		}
+
+		// 加锁之后再次检测，防止第一次请求在此期间已经完成。
+		if resp := i.replay(ctx, key, fingerprint); resp != nil {
+			i.unlock(ctx, key)
+			return resp
+		}
+
+		// 保存压缩和字符集转换之前的内容，重放时可以根据请求重新协商。
+		state := &idempotencyState{key: key, fingerprint: fingerprint, w: ctx.record(i.size, true)}
+		ctx.SetVar(i, state)
+
+		return next(ctx)
+	}
+}
+
+// 获取已经保存的响应内容，返回 nil 表示不存在。
+func (i *Idempotency) replay(ctx *Context, key string, fingerprint []byte) Responser {
+	resp := &idempotencyResponse{}
+	if err := i.cache.Get(key+idempotencyResponseSuffix, resp); errors.Is(err, cache.ErrCacheMiss()) {
+		return nil
+	} else if err != nil {
+		return ctx.Error(err, "")
+	}
+
+	if !bytes.Equal(resp.Fingerprint, fingerprint) {
+		return ctx.Problem(ProblemUnprocessableEntity)
+	}
+
+	return ResponserFunc(func(ctx *Context) {
+		ctx.Header().Set(idempotentReplayedHeader, "true")
+		ctx.writeRecorded(resp.Status, resp.Header, resp.Body, true)
+	})
+}
+
+func (i *Idempotency) unlock(ctx *Context, key string) {
+	if err := i.cache.Delete(key + idempotencyLockKeySuffix); err != nil {
+		ctx.Logs().ERROR().Error(err)
+	}
+}
+
+func (i *Idempotency) exit(ctx *Context, _ int) {
+	v, found := ctx.GetVar(i)
+	if !found {
+		return
+	}
+	state := v.(*idempotencyState)
+	defer i.unlock(ctx, state.key)
+
+	w := state.w
+	if !w.recorded() || w.status >= http.StatusInternalServerError {
+		return
+	}
+
+	// 以下报头在重放时根据请求重新生成
+	h := w.header
+	if ctx.outputCompressor != nil { // 由框架压缩的内容
+		h.Del(header.ContentEncoding)
+	}
+	h[header.Vary] = slices.DeleteFunc(h[header.Vary], func(v string) bool {
+		return v == header.AcceptEncoding || v == header.AcceptCharset || v == availableDictionaryHeader
+	})
+	if len(h[header.Vary]) == 0 {
+		h.Del(header.Vary)
+	}
+
+	resp := &idempotencyResponse{
+		Fingerprint: state.fingerprint,
+		Status:      w.status,
+		Header:      h,
+		Body:        w.body.Bytes(),
+	}
+	if err := i.cache.Set(state.key+idempotencyResponseSuffix, resp, i.ttl); err != nil {
+		ctx.Logs().ERROR().Error(err)
+	}
+}
+
+// 生成缓存中的键名
+func idempotencyHash(v ...string) string {
+	h := sha256.New()
+	for _, s := range v {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// 计算请求内容的哈希值，用于判断相同的 Idempotency-Key 是否提交了不同的内容。
+func idempotencyFingerprint(r *http.Request, body []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{0})
+	h.Write([]byte(r.Header.Get(header.ContentType)))
+	h.Write([]byte{0})
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+)
+
+var _ Middleware = &Idempotency{}
+
+func TestNewIdempotency(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+
+	a.PanicString(func() {
+		NewIdempotency(srv, 0, time.Second, 1024, nil)
+	}, "参数 ttl 必须大于 0")
+
+	a.PanicString(func() {
+		NewIdempotency(srv, time.Second, 0, 1024, nil)
+	}, "参数 timeout 必须大于 0")
+
+	a.PanicString(func() {
+		NewIdempotency(srv, time.Second, time.Second, 0, nil)
+	}, "参数 size 必须大于 0")
+}
+
+func TestIdempotency(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+	i := NewIdempotency(srv, time.Minute, time.Minute, 1024, func(ctx *Context) string {
+		return ctx.Request().Header.Get("X-User")
+	})
+
+	var count atomic.Int64
+	block := make(chan struct{})
+	router := srv.Routers().New("idempotency", nil)
+	router.Post("/orders", func(ctx *Context) Responser {
+		n := count.Add(1)
+		obj := &object{}
+		if resp := ctx.Read(true, obj, ProblemBadRequest); resp != nil {
+			return resp
+		}
+
+		switch obj.Name {
+		case "block":
+			<-block
+		case "error":
+			return ctx.Problem(ProblemInternalServerError)
+		}
+		obj.Age = int(n)
+		return Created(obj, "/orders/1")
+	}, i)
+	router.Post("/content-type", func(ctx *Context) Responser {
+		ct, ok := ctx.RequestBody().(interface{ ContentType() string })
+		a.True(ok)
+		return OK(ct.ContentType())
+	}, i)
+	router.Get("/orders", func(ctx *Context) Responser {
+		count.Add(1)
+		return OK("get")
+	}, i)
+
+	post := func(key, body string, h ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewBufferString(body))
+		r.Header.Set(header.Accept, header.JSON)
+		r.Header.Set(header.ContentType, header.JSON)
+		if key != "" {
+			r.Header.Set(idempotencyKeyHeader, key)
+		}
+		for j := 0; j < len(h); j += 2 {
+			r.Header.Set(h[j], h[j+1])
+		}
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// 未指定 Idempotency-Key
+
+	w := post("", `{"name":"n1"}`)
+	a.Equal(w.Code, http.StatusCreated).Equal(count.Load(), 1)
+	w = post("", `{"name":"n1"}`)
+	a.Equal(w.Code, http.StatusCreated).Equal(count.Load(), 2)
+
+	// 重放
+
+	w = post("k1", `{"name":"n1"}`)
+	a.Equal(w.Code, http.StatusCreated).
+		Equal(count.Load(), 3).
+		Equal(w.Body.String(), `{"name":"n1","Age":3}`).
+		Empty(w.Header().Get(idempotentReplayedHeader))
+	id := w.Header().Get(header.XRequestID)
+
+	w = post("k1", `{"name":"n1"}`)
+	a.Equal(w.Code, http.StatusCreated).
+		Equal(count.Load(), 3).
+		Equal(w.Body.String(), `{"name":"n1","Age":3}`).
+		Equal(w.Header().Get(header.Location), "/orders/1").
+		Equal(w.Header().Get(header.ContentType), "application/json; charset=utf-8").
+		Equal(w.Header().Get(idempotentReplayedHeader), "true").
+		NotEqual(w.Header().Get(header.XRequestID), id)
+
+	// 相同的 Idempotency-Key，不同的内容。
+	w = post("k1", `{"name":"n2"}`)
+	a.Equal(w.Code, http.StatusUnprocessableEntity).
+		Equal(count.Load(), 3).
+		Contains(w.Body.String(), ProblemUnprocessableEntity)
+
+	// 不同的用户
+	w = post("k1", `{"name":"n1"}`, "X-User", "u1")
+	a.Equal(w.Code, http.StatusCreated).Equal(count.Load(), 4)
+
+	// 重放时重新协商压缩和字符集
+
+	w = post("k2", `{"name":"n1"}`, header.AcceptEncoding, "gzip")
+	a.Equal(w.Code, http.StatusCreated).
+		Equal(count.Load(), 5).
+		Equal(w.Header().Get(header.ContentEncoding), "gzip")
+	body := w.Body.Bytes()
+	vary := w.Header().Values(header.Vary)
+
+	w = post("k2", `{"name":"n1"}`, header.AcceptEncoding, "gzip")
+	a.Equal(w.Code, http.StatusCreated).
+		Equal(count.Load(), 5).
+		Equal(w.Header().Get(header.ContentEncoding), "gzip").
+		Equal(w.Header().Values(header.Vary), vary).
+		Equal(w.Body.Bytes(), body)
+
+	w = post("k2", `{"name":"n1"}`)
+	a.Equal(w.Code, http.StatusCreated).
+		Equal(count.Load(), 5).
+		Empty(w.Header().Get(header.ContentEncoding)).
+		Equal(w.Body.String(), `{"name":"n1","Age":5}`)
+
+	w = post("k2", `{"name":"n1"}`, header.AcceptCharset, "gbk")
+	a.Equal(w.Code, http.StatusCreated).
+		Equal(count.Load(), 5).
+		Equal(w.Header().Get(header.ContentType), "application/json; charset=gbk").
+		Equal(w.Body.String(), `{"name":"n1","Age":5}`)
+
+	// 5xx 不保存
+
+	w = post("k3", `{"name":"error"}`)
+	a.Equal(w.Code, http.StatusInternalServerError).Equal(count.Load(), 6)
+	w = post("k3", `{"name":"error"}`)
+	a.Equal(w.Code, http.StatusInternalServerError).Equal(count.Load(), 7)
+
+	// 第一次请求未完成
+
+	done := make(chan struct{})
+	go func() {
+		w := post("k4", `{"name":"block"}`)
+		a.Equal(w.Code, http.StatusCreated)
+		close(done)
+	}()
+	for count.Load() != 8 { // 等待第一次请求进入处理函数
+		time.Sleep(time.Millisecond)
+	}
+
+	w = post("k4", `{"name":"block"}`)
+	a.Equal(w.Code, http.StatusConflict).
+		Equal(count.Load(), 8).
+		Contains(w.Body.String(), ProblemConflict)
+
+	close(block)
+	<-done
+	w = post("k4", `{"name":"block"}`)
+	a.Equal(w.Code, http.StatusCreated).
+		Equal(count.Load(), 8).
+		Equal(w.Header().Get(idempotentReplayedHeader), "true")
+
+	// GET 不作处理
+
+	for range 2 {
+		w = httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		r.Header.Set(header.Accept, header.JSON)
+		r.Header.Set(idempotencyKeyHeader, "k5")
+		router.ServeHTTP(w, r)
+		a.Equal(w.Code, http.StatusOK)
+	}
+	a.Equal(count.Load(), 10)
+
+	// 超过大小的内容不保存
+
+	large := `{"name":"` + strings.Repeat("1", 1024) + `"}`
+	w = post("k6", large)
+	a.Equal(w.Code, http.StatusCreated).Equal(count.Load(), 11)
+	w = post("k6", large)
+	a.Equal(w.Code, http.StatusCreated).
+		Equal(count.Load(), 12).
+		Empty(w.Header().Get(idempotentReplayedHeader))
+
+	// 缓存之后的内容依然可以获取 Content-Type
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/content-type", bytes.NewBufferString(`{}`))
+	r.Header.Set(header.Accept, header.JSON)
+	r.Header.Set(header.ContentType, header.JSON+"; charset=utf-8")
+	r.Header.Set(idempotencyKeyHeader, "k7")
+	router.ServeHTTP(w, r)
+	a.Equal(w.Code, http.StatusOK).Equal(w.Body.String(), `"application/json; charset=utf-8"`)
+}
@@ -5,14 +5,15 @@
 package web
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"sync"
 
-	"github.com/issue9/query/v3"
-
+	"github.com/issue9/web/internal/errs"
+	"github.com/issue9/web/internal/nested"
 	"github.com/issue9/web/locales"
 )
 
@@ -278,9 +279,14 @@ func (q *Queries) Problem(id string) Responser { return q.filter.Problem(id) }
 // 具体的文档信息可以参考 [Query]。
 // 如果 v 实现了 [Filter] 接口，则在读取数据之后，会调用该接口方法。
 //
+// 与 mimetype/form 相同，支持 a.b 和 a[b] 两种格式的嵌套键名，
+// 可用于嵌套的结构体和元素为结构体的切片，比如 items[0][name]=x。
+// 仅在第一段对应的字段为结构体或是切片时才作为嵌套键名处理，
+// 其它情况下与 [Query] 的规则相同，由 query 标签完整匹配键名。
+//
 // [Query]: https://github.com/issue9/query
 func (q *Queries) Object(v any) {
-	query.ParseWithLog(q.queries, v, func(field string, err error) {
+	nested.ParseQuery(q.queries, v, func(field string, err error) {
 		var msg LocaleStringer
 		if ls, ok := err.(LocaleStringer); ok {
 			msg = ls
@@ -312,6 +318,8 @@ func (ctx *Context) QueryObject(exitAtError bool, v any, id string) Responser {
 func (ctx *Context) RequestBody() io.Reader { return ctx.requestBody }
 
 // Unmarshal 将提交的内容解码到 v
+//
+// 如果提交的内容超出了 [BodyLimit] 的限制，返回由 [NewError] 包装的 [BodyLimitError]。
 func (ctx *Context) Unmarshal(v any) error {
 	if ctx.Request().ContentLength == 0 {
 		return nil
@@ -320,15 +328,42 @@ func (ctx *Context) Unmarshal(v any) error {
 	if ctx.inputMimetype == nil { // 客户端未指定 content-type，但是又有内容要输出。
 		return NewLocaleError("the client miss content-type header")
 	}
-	return ctx.inputMimetype(ctx.RequestBody(), v)
+
+	err := ctx.inputMimetype(ctx.RequestBody(), v)
+	if ctx.body.err != nil { // 解码方法可能忽略或是改写了读取时超出限制的错误
+		return ctx.body.err
+	}
+	return err
 }
 
 // Read 从客户端读取数据并转换成 v 对象
 //
 // 如果 v 实现了 [Filter] 接口，则在读取数据之后，会调用该接口方法。
 // 如果验证失败，会返回以 id 作为错误代码的 [Problem] 对象。
+// 解码返回的错误如果实现了 [Filter] 接口，也会以相同的方式返回 [Problem] 对象；
+// 如果是由 [NewError] 包装的错误，则以其指定的状态码返回 [Problem] 对象；
+// 提交的内容超出 [BodyLimit] 的限制，则返回 [ProblemRequestEntityTooLarge]，
+// 且 [Problem.Extensions] 为 [BodyLimitError]。
 func (ctx *Context) Read(exitAtError bool, v any, id string) Responser {
 	if err := ctx.Unmarshal(v); err != nil {
+		var lerr *BodyLimitError
+		if errors.As(err, &lerr) {
+			return ctx.Error(err, "").WithExtensions(lerr)
+		}
+
+		var vf Filter
+		if errors.As(err, &vf) { // 解码过程中产生的验证错误，比如上传文件的大小超出限制。
+			f := ctx.NewFilterContext(exitAtError)
+			vf.Filter(f)
+			if p := f.Problem(id); p != nil {
+				return p
+			}
+		}
+
+		var herr *errs.HTTP
+		if errors.As(err, &herr) { // 解码方法指定了状态码，比如 JSON:API 的类型冲突。
+			return ctx.Error(err, "")
+		}
 		return ctx.Error(err, ProblemUnprocessableEntity)
 	}
 
@@ -262,6 +262,23 @@ func TestContext_Object(t *testing.T) {
 	a.NotNil(resp)
 	resp.Apply(ctx)
 	a.Equal(w.Code, 411)
+
+	// 嵌套
+	ctx, w = newContextWithQuery(a, "/queries/nested?page[size]=5&items[1][name]=n1&items.0.name=n0")
+	type item struct {
+		Name string `query:"name"`
+	}
+	o3 := struct {
+		Page *struct {
+			Size int `query:"size"`
+		} `query:"page"`
+		Items []item `query:"items"`
+	}{}
+	resp = ctx.QueryObject(false, &o3, "41110")
+	a.Nil(resp).
+		Equal(w.Code, http.StatusOK).
+		Equal(o3.Page.Size, 5).
+		Equal(o3.Items, []item{{Name: "n0"}, {Name: "n1"}})
 }
 
 func TestContext_Unmarshal(t *testing.T) {
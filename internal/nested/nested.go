@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package nested 处理 form 和查询参数中的嵌套键名
+//
+// 支持以下两种格式，也可以混合使用：
+//   - 点号：a.b.0.c
+//   - 方括号：a[b][0][c]
+//
+// 其中 a[] 表示向切片 a 追加元素，与直接使用 a 作为键名是相同的。
+package nested
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MaxIndex 切片索引的最大值
+//
+// 防止恶意的超大索引导致分配大量的内存。
+const MaxIndex = 1000
+
+// Split 将键名拆分成多段
+//
+// a[b][0].c 和 a.b.0.c 都会被拆分为 [a b 0 c]。
+func Split(key string) []string {
+	names := make([]string, 0, 5)
+	for {
+		i := strings.IndexAny(key, ".[")
+		switch {
+		case i < 0:
+			return append(names, key)
+		case key[i] == '.':
+			names = append(names, key[:i])
+			key = key[i+1:]
+			continue
+		case i > 0 || len(names) == 0:
+			names = append(names, key[:i])
+		}
+
+		key = key[i+1:]
+		j := strings.IndexByte(key, ']')
+		if j < 0 { // 缺少右括号，剩余部分作为最后一段。
+			return append(names, key)
+		}
+		names = append(names, key[:j])
+
+		if key = key[j+1:]; key == "" {
+			return names
+		}
+		if key[0] == '.' {
+			key = key[1:]
+		}
+	}
+}
+
+// Index 将 s 转换为切片的索引
+func Index(s string) (int, error) {
+	index, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if index < 0 || index > MaxIndex {
+		return 0, fmt.Errorf("index %d out of range [0,%d]", index, MaxIndex)
+	}
+	return index, nil
+}
+
+// Group 按键名的第一段对 vals 进行分组
+//
+// 没有嵌套的键名写入 flat，其中 a[] 会被当作 a 处理；
+// 其它的以第一段作为键名，剩余部分作为新的键名写入 nested。
+func Group(vals url.Values) (flat url.Values, nested map[string]url.Values) {
+	flat = make(url.Values, len(vals))
+	nested = make(map[string]url.Values, len(vals))
+	for k, v := range vals {
+		names := Split(k)
+		if len(names) == 1 || (len(names) == 2 && names[1] == "") {
+			flat[names[0]] = append(flat[names[0]], v...)
+			continue
+		}
+
+		sub, found := nested[names[0]]
+		if !found {
+			sub = url.Values{}
+			nested[names[0]] = sub
+		}
+		key := Join(names[1:]...)
+		sub[key] = append(sub[key], v...)
+	}
+	return flat, nested
+}
+
+// Join 以方括号的形式合并 names
+//
+// 第一段不加括号，即 Join("a", "b", "c") 返回 a[b][c]。
+func Join(names ...string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(names[0])
+	for _, name := range names[1:] {
+		b.WriteByte('[')
+		b.WriteString(name)
+		b.WriteByte(']')
+	}
+	return b.String()
+}
+
+var pointerReplacer = strings.NewReplacer("~", "~0", "/", "~1")
+
+// Pointer 将键名转换为 [JSON Pointer]
+//
+// a[b][0].c 和 a.b.0.c 都会被转换为 /a/b/0/c，空的段会被忽略。
+//
+// [JSON Pointer]: https://www.rfc-editor.org/rfc/rfc6901
+func Pointer(key string) string {
+	var b strings.Builder
+	for _, name := range Split(key) {
+		if name != "" {
+			b.WriteByte('/')
+			pointerReplacer.WriteString(&b, name)
+		}
+	}
+	return b.String()
+}
+
+// Indexed 如果 vals 的键名都是索引，按索引顺序返回所有的值
+func Indexed(vals url.Values) ([]string, bool) {
+	type item struct {
+		index int
+		vals  []string
+	}
+
+	items := make([]item, 0, len(vals))
+	for k, v := range vals {
+		index, err := Index(k)
+		if err != nil {
+			return nil, false
+		}
+		items = append(items, item{index: index, vals: v})
+	}
+	slices.SortFunc(items, func(a, b item) int { return cmp.Compare(a.index, b.index) })
+
+	ret := make([]string, 0, len(items))
+	for _, item := range items {
+		ret = append(ret, item.vals...)
+	}
+	return ret, true
+}
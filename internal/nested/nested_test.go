@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package nested
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestSplit(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(Split(""), []string{""}).
+		Equal(Split("a"), []string{"a"}).
+		Equal(Split("a.b.0.c"), []string{"a", "b", "0", "c"}).
+		Equal(Split("a[b][0][c]"), []string{"a", "b", "0", "c"}).
+		Equal(Split("a[b][0].c"), []string{"a", "b", "0", "c"}).
+		Equal(Split("a.b[0].c"), []string{"a", "b", "0", "c"}).
+		Equal(Split("a[]"), []string{"a", ""}).
+		Equal(Split("a[b"), []string{"a", "b"}).
+		Equal(Split("a[b.c]"), []string{"a", "b.c"})
+}
+
+func TestIndex(t *testing.T) {
+	a := assert.New(t, false)
+
+	i, err := Index("5")
+	a.NotError(err).Equal(i, 5)
+
+	_, err = Index("x")
+	a.Error(err)
+
+	_, err = Index("-1")
+	a.Error(err)
+
+	_, err = Index("1001")
+	a.Error(err)
+}
+
+func TestGroup(t *testing.T) {
+	a := assert.New(t, false)
+
+	flat, nested := Group(url.Values{
+		"a":             {"1"},
+		"b[]":           {"2", "3"},
+		"c.d":           {"4"},
+		"c[e]":          {"5"},
+		"items[0].name": {"6"},
+		"items[1][age]": {"7"},
+	})
+	a.Equal(flat, url.Values{"a": {"1"}, "b": {"2", "3"}}).
+		Equal(nested, map[string]url.Values{
+			"c":     {"d": {"4"}, "e": {"5"}},
+			"items": {"0[name]": {"6"}, "1[age]": {"7"}},
+		})
+}
+
+func TestJoin(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Empty(Join()).
+		Equal(Join("a"), "a").
+		Equal(Join("a", "b", "0"), "a[b][0]")
+}
+
+func TestPointer(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Empty(Pointer("")).
+		Equal(Pointer("a"), "/a").
+		Equal(Pointer("a[b][0].c"), "/a/b/0/c").
+		Equal(Pointer("[1].name"), "/1/name").
+		Equal(Pointer("a~b[c/d]"), "/a~0b/c~1d")
+}
+
+func TestIndexed(t *testing.T) {
+	a := assert.New(t, false)
+
+	vals, ok := Indexed(url.Values{"2": {"3"}, "0": {"1"}, "1": {"2"}})
+	a.True(ok).Equal(vals, []string{"1", "2", "3"})
+
+	vals, ok = Indexed(url.Values{"0": {"1"}, "x": {"2"}})
+	a.False(ok).Nil(vals)
+}
@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package nested
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/issue9/query/v3"
+)
+
+// ParseQuery 将查询参数解析至 v 中
+//
+// 在 [query.ParseWithLog] 的基础上增加了对嵌套键名的支持：
+//   - 结构体或是结构体指针类型的字段，比如 a[b]=1；
+//   - 元素为结构体的切片，比如 items[0][name]=1；
+//   - 带索引的切片，比如 a[0]=1&a[1]=2，与 a=1&a=2 相同；
+//
+// 只有第一段对应的字段为结构体或是切片的键名才会被当作嵌套键名，
+// 其它键名原样交由 [query] 处理，比如 query:"a.b" 依然匹配键名 a.b。
+//
+// NOTE: 受 [query] 的限制，嵌套的结构体如果包含了不可比较的字段，比如切片，
+// 那么该字段只能声明为指针类型。
+func ParseQuery(vals url.Values, v any, log func(string, error)) {
+	parse(vals, "", reflect.ValueOf(v), log)
+}
+
+func parse(vals url.Values, prefix string, rv reflect.Value, log func(string, error)) {
+	flat, nested := group(vals, rv.Type())
+	for name, sub := range nested {
+		if values, ok := Indexed(sub); ok { // a[0]=1&a[1]=2 转换为 a=1&a=2
+			flat[name] = append(flat[name], values...)
+			delete(nested, name)
+		}
+	}
+
+	query.ParseWithLog(flat, rv.Interface(), func(name string, err error) { log(join(prefix, name), err) })
+
+	if len(nested) > 0 {
+		parseFields(nested, prefix, rv.Elem(), log)
+	}
+}
+
+// 与 [Group] 相同，但是只对第一段为 t 中结构体或切片字段的键名进行分组。
+func group(vals url.Values, t reflect.Type) (url.Values, map[string]url.Values) {
+	fields := map[string]bool{}
+	if t = indirectType(t); t.Kind() == reflect.Struct {
+		nestable(t, fields)
+	}
+
+	var flat url.Values
+	grouped := make(url.Values, len(vals))
+	for k, v := range vals {
+		if fields[Split(k)[0]] {
+			grouped[k] = v
+			continue
+		}
+
+		if flat == nil {
+			flat = make(url.Values, len(vals))
+		}
+		flat[k] = v
+	}
+
+	f, nested := Group(grouped)
+	for k, v := range flat {
+		f[k] = append(f[k], v...)
+	}
+	return f, nested
+}
+
+// 获取 t 中类型为结构体或切片的字段名
+func nestable(t reflect.Type, fields map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		tf := t.Field(i)
+		if tf.Anonymous {
+			if tf.Type.Kind() == reflect.Struct {
+				nestable(tf.Type, fields)
+			}
+			continue
+		}
+
+		if name := getTagName(tf); name != "" {
+			switch indirectType(tf.Type).Kind() {
+			case reflect.Struct, reflect.Slice:
+				fields[name] = true
+			}
+		}
+	}
+}
+
+func parseFields(nested map[string]url.Values, prefix string, rv reflect.Value, log func(string, error)) {
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		tf := rt.Field(i)
+		if tf.Anonymous {
+			if tf.Type.Kind() == reflect.Struct {
+				parseFields(nested, prefix, rv.Field(i), log)
+			}
+			continue
+		}
+
+		name := getTagName(tf)
+		sub, found := nested[name]
+		if name == "" || !found {
+			continue
+		}
+		name = join(prefix, name)
+
+		fv := rv.Field(i)
+		switch kind := indirectType(tf.Type).Kind(); {
+		case kind == reflect.Struct:
+			parse(sub, name, alloc(fv).Addr(), log)
+		case kind == reflect.Slice && indirectType(tf.Type.Elem()).Kind() == reflect.Struct:
+			parseSlice(sub, name, alloc(fv), log)
+		}
+	}
+}
+
+var errNotIndexed = errors.New("slice element must be indexed")
+
+// 解析元素类型为结构体的切片
+//
+// 与 query 的处理方式相同，指定了参数，则舍弃切片中的旧值。
+func parseSlice(vals url.Values, prefix string, slice reflect.Value, log func(string, error)) {
+	flat, nested := Group(vals)
+	for name := range flat {
+		log(join(prefix, name), errNotIndexed)
+	}
+
+	elemType := slice.Type().Elem()
+	s := reflect.MakeSlice(slice.Type(), 0, len(nested))
+	for key, sub := range nested {
+		index, err := Index(key)
+		if err != nil {
+			log(join(prefix, key), err)
+			continue
+		}
+
+		if l := s.Len(); index >= l {
+			s = reflect.AppendSlice(s, reflect.MakeSlice(slice.Type(), index+1-l, index+1-l))
+		}
+		elem := reflect.New(elemType).Elem()
+		parse(sub, join(prefix, key), alloc(elem).Addr(), log)
+		s.Index(index).Set(elem)
+	}
+	slice.Set(s)
+}
+
+// 为指针类型分配内存，返回最终指向的值。
+func alloc(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// 与 query 的规则相同，返回空值表示忽略该字段。
+func getTagName(field reflect.StructField) string {
+	if unicode.IsLower(rune(field.Name[0])) {
+		return ""
+	}
+
+	tag := field.Tag.Get(query.Tag)
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); strings.TrimSpace(name) != "" {
+		return strings.TrimSpace(name)
+	}
+	return field.Name
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package nested
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+type (
+	item struct {
+		Name string `query:"name"`
+		Age  int    `query:"age"`
+	}
+
+	page struct {
+		Size int `query:"size,10"`
+		Tags *struct {
+			Tags []string `query:"tags"`
+		} `query:"tags"`
+	}
+
+	object struct {
+		page
+		Page   page    `query:"page"`
+		Items  []*item `query:"items"`
+		Items2 []item  `query:"items2"`
+		IDs    []int   `query:"ids"`
+		Name   string  `query:"name"`
+		Dotted string  `query:"a.b"`
+		Braced string  `query:"c[d]"`
+	}
+)
+
+func TestParseQuery(t *testing.T) {
+	a := assert.New(t, false)
+
+	parse := func(query string) (*object, map[string]error) {
+		vals, err := url.ParseQuery(query)
+		a.NotError(err)
+
+		o := &object{}
+		errs := map[string]error{}
+		ParseQuery(vals, o, func(name string, err error) { errs[name] = err })
+		return o, errs
+	}
+
+	o, errs := parse("name=n&ids[0]=1&ids[2]=3&ids[1]=2&page[size]=5&page.tags[tags][]=t1&page.tags.tags[]=t2&size=6&items[1][name]=n1&items.0.age=5&items2[0][name]=n2")
+	a.Empty(errs).
+		Equal(o.Name, "n").
+		Equal(o.IDs, []int{1, 2, 3}).
+		Equal(o.Size, 6).
+		Equal(o.Page.Size, 5).
+		Length(o.Page.Tags.Tags, 2).
+		Equal(o.Items, []*item{{Age: 5}, {Name: "n1"}}).
+		Equal(o.Items2, []item{{Name: "n2"}})
+
+	// 非结构体和切片的字段，键名不作嵌套处理。
+	o, errs = parse("a.b=1&c[d]=2")
+	a.Empty(errs).Equal(o.Dotted, "1").Equal(o.Braced, "2")
+
+	// 未指定索引
+	_, errs = parse("items[name]=n1")
+	a.Equal(slices.Sorted(maps.Keys(errs)), []string{"items.name"})
+	_, errs = parse("items[]=n1&items=n2")
+	a.Equal(slices.Sorted(maps.Keys(errs)), []string{"items"})
+
+	// 类型错误
+	_, errs = parse("page[size]=x&items[0][age]=x&ids[0]=x")
+	a.Equal(slices.Sorted(maps.Keys(errs)), []string{"ids", "items.0.age", "page.size"})
+}
@@ -5,8 +5,12 @@
 package qheader
 
 import (
+	"encoding/base64"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/issue9/mux/v9/header"
@@ -44,6 +48,101 @@ func ParseWithParam(header, param string) (mt, paramValue string) {
 	return t, ""
 }
 
+// ParseParams 解析 ParseQHeader 返回的 [Item.Params]
+//
+// 参数名称会被转换为小写，值会去掉两边的引号，except 指定的参数会被忽略。
+// 如果 ps 中不包含任何参数，返回 nil。
+func ParseParams(ps string, except ...string) map[string]string {
+	var params map[string]string
+	for len(ps) > 0 {
+		var item string
+		item, ps, _ = strings.Cut(ps, ";")
+
+		name, val, _ := strings.Cut(item, "=")
+		if name = strings.ToLower(strings.TrimSpace(name)); name == "" || slices.Contains(except, name) {
+			continue
+		}
+
+		if params == nil {
+			params = make(map[string]string, 3)
+		}
+		params[name] = strings.TrimFunc(val, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+	}
+	return params
+}
+
+// HasDirective 在逗号分隔的报头中查找指定名称的指令
+//
+// values 为报头的所有值，比如 Cache-Control 报头的 [http.Header.Values]，
+// directive 为指令名称，比如 no-transform，不区分大小写，指令的参数会被忽略。
+func HasDirective(values []string, directive string) bool {
+	for _, v := range values {
+		for item := range strings.SplitSeq(v, ",") {
+			name, _, _ := strings.Cut(item, "=")
+			if strings.EqualFold(strings.TrimSpace(name), directive) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DirectiveSeconds 在逗号分隔的报头中查找以秒为单位的指令值
+//
+// 比如 Cache-Control 报头中的 max-age=10 将返回 10 秒，
+// 指令不存在或是值不是非负整数时，ok 返回 false。
+func DirectiveSeconds(values []string, directive string) (d time.Duration, ok bool) {
+	for _, v := range values {
+		for item := range strings.SplitSeq(v, ",") {
+			name, val, found := strings.Cut(item, "=")
+			if !found || !strings.EqualFold(strings.TrimSpace(name), directive) {
+				continue
+			}
+
+			n, err := strconv.ParseUint(strings.Trim(strings.TrimSpace(val), `"`), 10, 32)
+			if err != nil {
+				return 0, false
+			}
+			return time.Duration(n) * time.Second, true
+		}
+	}
+	return 0, false
+}
+
+// ParseList 解析逗号分隔的报头
+//
+// 比如 Vary 报头，返回的各项均为 [http.CanonicalHeaderKey] 格式，且已排序和去重。
+func ParseList(values []string) []string {
+	var items []string
+	for _, v := range values {
+		for item := range strings.SplitSeq(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, http.CanonicalHeaderKey(item))
+			}
+		}
+	}
+	slices.Sort(items)
+	return slices.Compact(items)
+}
+
+// ParseByteSequence 解析结构化报头中的字节序列
+//
+// 字节序列是以冒号包含的 base64 编码内容，比如 Available-Dictionary 报头：
+//
+//	Available-Dictionary: :pZGm1Av0IEBKARczz7exkNYsZb8LzaMrV7J32a2fFG4=:
+func ParseByteSequence(h string) ([]byte, bool) {
+	h = strings.TrimSpace(h)
+	if len(h) < 2 || h[0] != ':' || h[len(h)-1] != ':' {
+		return nil, false
+	}
+
+	data, err := base64.StdEncoding.DecodeString(h[1 : len(h)-1])
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
 // ParseAcceptCharset 根据 Accept-Charset 报头的内容获取其最值的字符集信息
 //
 // 传递 * 获取返回默认的字符集相关信息，即 utf-8
@@ -124,3 +223,56 @@ func InitETag(w http.ResponseWriter, r *http.Request, etag string, weak bool) bo
 
 	return eq
 }
+
+// MatchETag h 中是否包含与 etag 相匹配的值
+//
+// h 为 If-Match、If-None-Match 等报头的值，可以是 * 或是逗号分隔的多个 ETag；
+// etag 为服务端的 ETag，包含双引号，弱验证时带 W/ 前缀，为空表示不存在；
+// weak 是否采用弱比较，强比较时两者都不能是弱验证的值。
+func MatchETag(h, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	for {
+		if h = strings.TrimLeft(h, " \t,"); h == "" {
+			return false
+		}
+
+		if h[0] == '*' {
+			return true
+		}
+
+		var item string
+		if item, h = scanETag(h); item == "" { // 格式错误
+			return false
+		}
+
+		if weak {
+			if strings.TrimPrefix(item, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if item == etag && !strings.HasPrefix(item, "W/") {
+			return true
+		}
+	}
+}
+
+// 从 s 的开头读取一个 ETag，如果格式错误，返回空值。
+func scanETag(s string) (etag, remain string) {
+	start := 0
+	if strings.HasPrefix(s, "W/") {
+		start = 2
+	}
+
+	if len(s)-start < 2 || s[start] != '"' {
+		return "", ""
+	}
+
+	end := strings.IndexByte(s[start+1:], '"')
+	if end < 0 {
+		return "", ""
+	}
+	end += start + 2
+	return s[:end], s[end:]
+}
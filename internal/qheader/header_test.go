@@ -7,6 +7,7 @@ package qheader
 import (
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/issue9/assert/v4"
 	"github.com/issue9/assert/v4/rest"
@@ -55,6 +56,75 @@ func TestParseWithParam(t *testing.T) {
 	a.Equal(v, "application/xml").Equal(p, "")
 }
 
+func TestParseParams(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Nil(ParseParams("")).
+		Nil(ParseParams(";;")).
+		Nil(ParseParams("q=0.9", "q")).
+		Equal(ParseParams(` Version="2"; q=0.9;flag;charset=utf-8`, "q", "charset"), map[string]string{"version": "2", "flag": ""})
+}
+
+func TestHasDirective(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.True(HasDirective([]string{"public, No-Transform"}, "no-transform")).
+		True(HasDirective([]string{"max-age=10", "no-transform"}, "no-transform")).
+		True(HasDirective([]string{"max-age=10"}, "max-age")).
+		False(HasDirective([]string{"public, max-age=10"}, "no-transform")).
+		False(HasDirective(nil, "no-transform"))
+}
+
+func TestDirectiveSeconds(t *testing.T) {
+	a := assert.New(t, false)
+
+	d, ok := DirectiveSeconds([]string{"public, Max-Age=10"}, "max-age")
+	a.True(ok).Equal(d, 10*time.Second)
+
+	d, ok = DirectiveSeconds([]string{"public", `s-maxage="5", max-age=10`}, "s-maxage")
+	a.True(ok).Equal(d, 5*time.Second)
+
+	d, ok = DirectiveSeconds([]string{"max-age=0"}, "max-age")
+	a.True(ok).Equal(d, 0)
+
+	_, ok = DirectiveSeconds([]string{"max-age=-1"}, "max-age")
+	a.False(ok)
+
+	_, ok = DirectiveSeconds([]string{"max-age"}, "max-age")
+	a.False(ok)
+
+	_, ok = DirectiveSeconds(nil, "max-age")
+	a.False(ok)
+}
+
+func TestParseList(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(ParseList([]string{"accept-encoding, Accept", "Accept-Language,accept"}), []string{"Accept", "Accept-Encoding", "Accept-Language"}).
+		Equal(ParseList([]string{"*"}), []string{"*"}).
+		Empty(ParseList([]string{" , "})).
+		Empty(ParseList(nil))
+}
+
+func TestParseByteSequence(t *testing.T) {
+	a := assert.New(t, false)
+
+	data, ok := ParseByteSequence(" :MTIz: ")
+	a.True(ok).Equal(data, []byte("123"))
+
+	data, ok = ParseByteSequence("::")
+	a.True(ok).Empty(data)
+
+	_, ok = ParseByteSequence("MTIz")
+	a.False(ok)
+
+	_, ok = ParseByteSequence(":MTI$:")
+	a.False(ok)
+
+	_, ok = ParseByteSequence(":")
+	a.False(ok)
+}
+
 func TestAcceptCharset(t *testing.T) {
 	a := assert.New(t, false)
 
@@ -171,3 +241,27 @@ func TestInitETag(t *testing.T) {
 	a.False(InitETag(w, r, `"abc"`, false)).
 		Equal(w.Header().Get(header.ETag), `"abc"`)
 }
+
+func TestMatchETag(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.True(MatchETag(`"1"`, `"1"`, false)).
+		True(MatchETag(`"0", "1"`, `"1"`, false)).
+		True(MatchETag(`*`, `"1"`, false)).
+		True(MatchETag(`"a,b"`, `"a,b"`, false)).
+		False(MatchETag(`"0", "2"`, `"1"`, false)).
+		False(MatchETag(`*`, ``, false)).
+		False(MatchETag(`1`, `"1"`, false)).
+		False(MatchETag(`"1`, `"1"`, false))
+
+	// 强比较
+	a.False(MatchETag(`W/"1"`, `"1"`, false)).
+		False(MatchETag(`"1"`, `W/"1"`, false)).
+		False(MatchETag(`W/"1"`, `W/"1"`, false))
+
+	// 弱比较
+	a.True(MatchETag(`W/"1"`, `"1"`, true)).
+		True(MatchETag(`"1"`, `W/"1"`, true)).
+		True(MatchETag(`"0", W/"1"`, `W/"1"`, true)).
+		False(MatchETag(`W/"0"`, `W/"1"`, true))
+}
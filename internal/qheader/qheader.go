@@ -19,9 +19,10 @@ var itemsPool = &sync.Pool{New: func() any { return new([]*Item) }}
 //
 // 比如 zh-cmt;q=0.8, zh-cmn;q=1, 拆分成两个 Item 对象。
 type Item struct {
-	Value string
-	Q     float32
-	Err   error // 如果 Q 解析出错会出现在此
+	Value  string
+	Params string // 原始的参数内容，包含了 q，比如 q=0.8;v=1。
+	Q      float32
+	Err    error // 如果 Q 解析出错会出现在此
 }
 
 func PutQHeader(items *[]*Item) { itemsPool.Put(items) }
@@ -68,6 +69,7 @@ func ParseQHeader(header string, any string) (items []*Item) {
 		// NOTE: 从 pool 取得的值，需要全部覆盖。
 		item := items[index]
 		item.Value = v
+		_, item.Params, _ = strings.Cut(h, ";")
 		item.Q = float32(q)
 		item.Err = err
 	}
@@ -51,6 +51,12 @@ func TestParse(t *testing.T) {
 
 	items = ParseQHeader("utf-8;q=x.9,gbk;q=0.8", "*/*")
 	a.Length(items, 2)
+
+	items = ParseQHeader("application/vnd.x+json;v=2;q=0.9,text/plain", "*/*")
+	a.Length(items, 2).
+		Equal(items[0].Params, "").
+		Equal(items[1].Value, "application/vnd.x+json").
+		Equal(items[1].Params, "v=2;q=0.9")
 }
 
 func TestSortItems(t *testing.T) {
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/issue9/web/internal/qheader"
+)
+
+// MediaType 客户端请求的媒体类型
+//
+// 可用于根据媒体类型进行版本控制，比如以下两种形式：
+//
+//	Accept: application/vnd.example.v2+json
+//	Accept: application/vnd.example+json; version=2
+//
+// Vendor 都为 example，Version 都为 2，Suffix 都为 json。
+type MediaType struct {
+	Name    string            // 媒体类型的名称，不包含参数，比如 application/vnd.example.v2+json；
+	Vendor  string            // vnd. 之后的名称，不包含版本号和结构化后缀，比如 example；
+	Version string            // 版本号，优先采用 version 参数，否则采用 Vendor 中类似于 .v2 的部分；
+	Suffix  string            // 结构化后缀，比如 json；
+	Params  map[string]string // 除 q 和 charset 之外的参数，名称为小写；
+}
+
+func newMediaType(name, params string) *MediaType {
+	t := &MediaType{
+		Name:   name,
+		Params: qheader.ParseParams(params, "q", "charset"),
+	}
+
+	_, subtype, _ := strings.Cut(name, "/")
+	if index := strings.LastIndexByte(subtype, '+'); index >= 0 {
+		t.Suffix = subtype[index+1:]
+		subtype = subtype[:index]
+	}
+
+	if vendor, found := strings.CutPrefix(subtype, "vnd."); found {
+		t.Vendor = vendor
+		if index := strings.LastIndexByte(vendor, '.'); index > 0 && isVersion(vendor[index+1:]) {
+			t.Vendor = vendor[:index]
+			t.Version = vendor[index+2:]
+		}
+	}
+
+	if v, found := t.Params["version"]; found {
+		t.Version = v
+	}
+
+	return t
+}
+
+// 是否为 v2 之类的版本号
+func isVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	return strings.IndexFunc(s[1:], func(r rune) bool { return r < '0' || r > '9' }) < 0
+}
+
+// String 返回包含参数的媒体类型，参数按名称排序。
+func (t *MediaType) String() string {
+	if len(t.Params) == 0 {
+		return t.Name
+	}
+
+	var b strings.Builder
+	b.WriteString(t.Name)
+	for _, k := range slices.Sorted(maps.Keys(t.Params)) {
+		b.WriteString("; ")
+		b.WriteString(k)
+		if v := t.Params[k]; v != "" {
+			b.WriteByte('=')
+			b.WriteString(v)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestMediaType(t *testing.T) {
+	a := assert.New(t, false)
+
+	mt := newMediaType("application/json", "")
+	a.Equal(mt, &MediaType{Name: "application/json"}).
+		Equal(mt.String(), "application/json")
+
+	mt = newMediaType("application/vnd.github.v3+json", "q=0.8")
+	a.Equal(mt, &MediaType{Name: "application/vnd.github.v3+json", Vendor: "github", Version: "3", Suffix: "json"})
+
+	mt = newMediaType("application/vnd.example.v3+json", "Version=4;q=0.8;charset=utf-8;b=2")
+	a.Equal(mt, &MediaType{
+		Name:    "application/vnd.example.v3+json",
+		Vendor:  "example",
+		Version: "4",
+		Suffix:  "json",
+		Params:  map[string]string{"version": "4", "b": "2"},
+	}).Equal(mt.String(), "application/vnd.example.v3+json; b=2; version=4")
+
+	mt = newMediaType("application/vnd.example.vx", "")
+	a.Equal(mt, &MediaType{Name: "application/vnd.example.vx", Vendor: "example.vx"})
+
+	mt = newMediaType("application/problem+xml", "flag")
+	a.Equal(mt, &MediaType{Name: "application/problem+xml", Suffix: "xml", Params: map[string]string{"flag": ""}}).
+		Equal(mt.String(), "application/problem+xml; flag")
+}
@@ -23,3 +23,5 @@ const (
 func Marshal(_ *web.Context, v any) ([]byte, error) { return cbor.Marshal(v) }
 
 func Unmarshal(r io.Reader, v any) error { return cbor.NewDecoder(r).Decode(v) }
+
+func Encode(_ *web.Context, w io.Writer, v any) error { return cbor.NewEncoder(w).Encode(v) }
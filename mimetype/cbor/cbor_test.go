@@ -16,9 +16,11 @@ import (
 var (
 	_ web.MarshalFunc   = Marshal
 	_ web.UnmarshalFunc = Unmarshal
+	_ web.EncodeFunc    = Encode
 )
 
 func TestCBOR(t *testing.T) {
 	a := assert.New(t, false)
 	mimetypetest.Test(a, Marshal, Unmarshal)
+	mimetypetest.TestEncode(a, Encode, Unmarshal)
 }
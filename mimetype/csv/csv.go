@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package csv 提供 CSV 和 TSV 格式的序列化方法
+//
+// 第一行为表头，之后每一行对应一个对象。可以通过 csv 标签指定表头的名称：
+//
+//	type User struct {
+//	    Name string `csv:"name"`
+//	    Age int
+//	    Password string `csv:"-"`
+//	}
+//
+// 编码时 v 可以是以下类型：
+//   - 实现了 [Marshaler] 接口的对象，比如 [web.Problem]；
+//   - [][]string 原样输出；
+//   - 元素为结构体的切片或数组，表头由 csv 标签决定；
+//   - 元素为 map 的切片或数组，表头为所有键名按字母排序之后的结果，键名必须为字符串；
+//   - 单个结构体或是 map，输出为只有一行数据的表格；
+//
+// 解码时 v 可以是以下类型：
+//   - 实现了 [Unmarshaler] 接口的对象；
+//   - *[][]string 包含了表头在内的所有内容；
+//   - 指向元素为结构体或是 map 的切片指针，表头中不存在于结构体的列会被忽略；
+//   - 结构体指针，仅读取第一行数据；
+//
+// 字段的值如果实现了 [encoding.TextMarshaler] 和 [encoding.TextUnmarshaler]，会优先调用。
+// 嵌入结构体的处理规则与 encoding/json 相同。
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+
+	"github.com/issue9/web"
+)
+
+const (
+	Mimetype    = "text/csv"
+	TSVMimetype = "text/tab-separated-values"
+)
+
+// Marshaler 自定义 CSV 输出需要实现的接口
+type Marshaler interface {
+	// MarshalCSV 返回包括表头在内的所有记录
+	MarshalCSV() ([][]string, error)
+}
+
+// Unmarshaler 自定义 CSV 解码需要实现的接口
+type Unmarshaler interface {
+	// UnmarshalCSV 传入包括表头在内的所有记录
+	UnmarshalCSV([][]string) error
+}
+
+type codec rune
+
+const (
+	csvCodec codec = ','
+	tsvCodec codec = '\t'
+)
+
+// Marshal 将 v 编码为 CSV
+func Marshal(ctx *web.Context, v any) ([]byte, error) { return csvCodec.marshal(ctx, v) }
+
+// Unmarshal 从 r 中解码 CSV 内容至 v
+func Unmarshal(r io.Reader, v any) error { return csvCodec.unmarshal(r, v) }
+
+// Encode 将 v 编码为 CSV 并写入 w
+func Encode(_ *web.Context, w io.Writer, v any) error { return csvCodec.encode(w, v) }
+
+// MarshalTSV 将 v 编码为 TSV
+func MarshalTSV(ctx *web.Context, v any) ([]byte, error) { return tsvCodec.marshal(ctx, v) }
+
+// UnmarshalTSV 从 r 中解码 TSV 内容至 v
+func UnmarshalTSV(r io.Reader, v any) error { return tsvCodec.unmarshal(r, v) }
+
+// EncodeTSV 将 v 编码为 TSV 并写入 w
+func EncodeTSV(_ *web.Context, w io.Writer, v any) error { return tsvCodec.encode(w, v) }
+
+func (c codec) marshal(_ *web.Context, v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := c.encode(buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c codec) encode(w io.Writer, v any) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = rune(c)
+	if err := writeRecords(cw, v); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (c codec) unmarshal(r io.Reader, v any) error {
+	cr := csv.NewReader(r)
+	cr.Comma = rune(c)
+	if c == tsvCodec { // TSV 一般不会对引号进行转义
+		cr.LazyQuotes = true
+	}
+	return readRecords(cr, v)
+}
@@ -0,0 +1,262 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package csv
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/mimetype"
+	"github.com/issue9/web/mimetype/json"
+	"github.com/issue9/web/server"
+	"github.com/issue9/web/server/servertest"
+)
+
+var (
+	_ web.MarshalFunc   = Marshal
+	_ web.UnmarshalFunc = Unmarshal
+	_ web.EncodeFunc    = Encode
+	_ web.MarshalFunc   = MarshalTSV
+	_ web.UnmarshalFunc = UnmarshalTSV
+	_ web.EncodeFunc    = EncodeTSV
+	_ Marshaler         = &web.Problem{}
+)
+
+type (
+	Base struct {
+		ID int64 `csv:"id"`
+	}
+
+	base struct {
+		Hidden string
+	}
+
+	user struct {
+		*Base
+		base
+		Name     string     `csv:"name"`
+		Age      int        `csv:"age,omitempty"`
+		Created  time.Time  `csv:"created"`
+		Deleted  *time.Time `csv:"deleted"`
+		Password string     `csv:"-"`
+		unexport bool
+	}
+)
+
+const usersCSV = "id,Hidden,name,age,created,deleted\n1,h,\"a,b\",18,2025-01-02T00:00:00Z,\n,,c,0,0001-01-01T00:00:00Z,\n"
+
+var created = time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+func TestMarshal(t *testing.T) {
+	a := assert.New(t, false)
+
+	users := []*user{
+		{Base: &Base{ID: 1}, base: base{Hidden: "h"}, Name: "a,b", Age: 18, Created: created, Password: "p"},
+		{Name: "c"},
+	}
+	data, err := Marshal(nil, users)
+	a.NotError(err).Equal(string(data), usersCSV)
+
+	data, err = MarshalTSV(nil, users)
+	a.NotError(err).Equal(string(data), strings.ReplaceAll(strings.ReplaceAll(usersCSV, ",", "\t"), "\"a\tb\"", "a,b"))
+
+	// 单个对象
+	data, err = Marshal(nil, users[1])
+	a.NotError(err).Equal(string(data), "id,Hidden,name,age,created,deleted\n,,c,0,0001-01-01T00:00:00Z,\n")
+
+	// 空切片依然输出表头
+	data, err = Marshal(nil, []user{})
+	a.NotError(err).Equal(string(data), "id,Hidden,name,age,created,deleted\n")
+
+	// map
+	data, err = Marshal(nil, []map[string]any{{"b": 1, "a": "x"}, {"c": true}, nil})
+	a.NotError(err).Equal(string(data), "a,b,c\nx,1,\n,,true\n,,\n")
+
+	data, err = Marshal(nil, map[string]int{"b": 1, "a": 2})
+	a.NotError(err).Equal(string(data), "a,b\n2,1\n")
+
+	// [][]string
+	data, err = Marshal(nil, [][]string{{"a", "b"}, {"1", "2"}})
+	a.NotError(err).Equal(string(data), "a,b\n1,2\n")
+
+	// 不支持的类型
+	_, err = Marshal(nil, 5)
+	a.ErrorIs(err, mimetype.ErrUnsupported())
+	_, err = Marshal(nil, []int{5})
+	a.ErrorIs(err, mimetype.ErrUnsupported())
+	_, err = Marshal(nil, map[int]int{5: 5})
+	a.Error(err)
+}
+
+func TestUnmarshal(t *testing.T) {
+	a := assert.New(t, false)
+
+	users := []*user{}
+	a.NotError(Unmarshal(strings.NewReader("\ufeff"+usersCSV), &users))
+	a.Length(users, 2).
+		Equal(users[0], &user{Base: &Base{ID: 1}, base: base{Hidden: "h"}, Name: "a,b", Age: 18, Created: created}).
+		Equal(users[1], &user{Name: "c"})
+
+	users2 := []user{}
+	a.NotError(UnmarshalTSV(strings.NewReader("name\tage\tunknown\na\"b\t18\tx\n"), &users2))
+	a.Equal(users2, []user{{Name: "a\"b", Age: 18}})
+
+	u := &user{}
+	a.NotError(Unmarshal(strings.NewReader(usersCSV), u))
+	a.Equal(u, &user{Base: &Base{ID: 1}, base: base{Hidden: "h"}, Name: "a,b", Age: 18, Created: created})
+
+	maps := []map[string]int{}
+	a.NotError(Unmarshal(strings.NewReader("a,b\n1,\n3,4\n"), &maps))
+	a.Equal(maps, []map[string]int{{"a": 1, "b": 0}, {"a": 3, "b": 4}})
+
+	records := [][]string{}
+	a.NotError(Unmarshal(strings.NewReader("\ufeffa,b\n1,2\n"), &records))
+	a.Equal(records, [][]string{{"a", "b"}, {"1", "2"}})
+
+	// 空内容
+	users = []*user{}
+	a.NotError(Unmarshal(strings.NewReader(""), &users)).Empty(users)
+
+	// 类型错误
+	err := Unmarshal(strings.NewReader("name,age\na,1\nb,x\n"), &users)
+	fe := &web.FieldError{}
+	a.True(errors.As(err, &fe)).Equal(fe.Field, "[1].age")
+
+	// 列数不一致
+	a.Error(Unmarshal(strings.NewReader("name,age\na\n"), &users))
+
+	// 不支持的类型
+	a.ErrorIs(Unmarshal(strings.NewReader("a\n1\n"), users), mimetype.ErrUnsupported())
+	a.ErrorIs(Unmarshal(strings.NewReader("a\n1\n"), &[]int{}), mimetype.ErrUnsupported())
+}
+
+func TestProblem(t *testing.T) {
+	a := assert.New(t, false)
+
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Codec: web.NewCodec().
+			AddMimetype(json.Mimetype, json.Marshal, json.Unmarshal, json.ProblemMimetype, true, true).
+			AddMimetype(Mimetype, Marshal, Unmarshal, "", true, true, Encode).
+			AddMimetype(TSVMimetype, MarshalTSV, UnmarshalTSV, "", true, true, EncodeTSV),
+	})
+	a.NotError(err).NotNil(s)
+
+	r := s.Routers().New("default", nil)
+	r.Get("/users", func(ctx *web.Context) web.Responser {
+		return web.OK([]*user{{Base: &Base{ID: 1}, base: base{Hidden: "h"}, Name: "a,b", Age: 18, Created: created}, {Name: "c"}})
+	})
+	r.Post("/users", func(ctx *web.Context) web.Responser {
+		users := []*user{}
+		if resp := ctx.Read(false, &users, web.ProblemUnprocessableEntity); resp != nil {
+			return resp
+		}
+		return web.OK(len(users))
+	})
+	r.Get("/problem", func(ctx *web.Context) web.Responser {
+		return ctx.Problem(web.ProblemBadRequest).WithParam("p1", "r1").WithParam("p2", "r2").
+			WithExtensions(map[string]int{"a": 1})
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	servertest.Get(a, "http://localhost:8080/users").
+		Header(header.Accept, "text/csv;q=0.9,application/json;q=0.1").
+		Do(nil).
+		Status(http.StatusOK).
+		Header(header.ContentType, "text/csv; charset=utf-8").
+		StringBody(usersCSV)
+
+	servertest.Get(a, "http://localhost:8080/users").
+		Header(header.Accept, TSVMimetype).
+		Do(nil).
+		Status(http.StatusOK).
+		Header(header.ContentType, "text/tab-separated-values; charset=utf-8")
+
+	servertest.Post(a, "http://localhost:8080/users", []byte(usersCSV)).
+		Header(header.ContentType, Mimetype).
+		Header(header.Accept, json.Mimetype).
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody("2")
+
+	servertest.Post(a, "http://localhost:8080/users", []byte("name,age\nx,x\n")).
+		Header(header.ContentType, Mimetype).
+		Header(header.Accept, Mimetype).
+		Do(nil).
+		Status(http.StatusUnprocessableEntity).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			a.True(bytes.HasPrefix(body, []byte("type,title,detail,instance,status,params,extensions\n")))
+		})
+
+	servertest.Get(a, "http://localhost:8080/problem").
+		Header(header.Accept, Mimetype).
+		Do(nil).
+		Status(http.StatusBadRequest).
+		Header(header.ContentType, "text/csv; charset=utf-8").
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			records := [][]string{}
+			a.NotError(Unmarshal(bytes.NewReader(body), &records)).
+				Length(records, 2).
+				Equal(records[0], []string{"type", "title", "detail", "instance", "status", "params", "extensions"}).
+				Equal(records[1][4], "400").
+				Equal(records[1][5], "p1: r1\np2: r2").
+				Equal(records[1][6], `{"a":1}`)
+		})
+}
+
+func TestGetFields(t *testing.T) {
+	a := assert.New(t, false)
+
+	type (
+		inner struct {
+			ID   int `csv:"id"`
+			Name string
+		}
+
+		named struct{ X int }
+
+		outer struct {
+			inner // ID 与 Base.ID 层级相同且都指定了名称，都被忽略；Name 被 outer.Name 覆盖。
+			*Base
+			named `csv:"n"` // 指定了名称的嵌入结构体作为普通字段
+			Name  string
+		}
+
+		unexported struct {
+			*base
+			Name string
+		}
+	)
+
+	names := func(fields []*field) []string {
+		ret := make([]string, 0, len(fields))
+		for _, f := range fields {
+			ret = append(ret, f.name)
+		}
+		return ret
+	}
+	a.Equal(names(getFields(reflect.TypeFor[outer]())), []string{"n", "Name"})
+
+	// 未导出的嵌入指针
+	data, err := Marshal(nil, &unexported{base: &base{Hidden: "h"}, Name: "n"})
+	a.NotError(err).Equal(string(data), "Hidden,Name\nh,n\n")
+
+	u := &unexported{}
+	a.NotError(Unmarshal(strings.NewReader("Name\nn\n"), u)).Equal(u.Name, "n")
+	err = Unmarshal(strings.NewReader("Hidden,Name\nh,n\n"), u)
+	fe := &web.FieldError{}
+	a.True(errors.As(err, &fe)).Equal(fe.Field, "Hidden")
+}
@@ -0,0 +1,443 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package csv
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/issue9/conv"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/mimetype"
+)
+
+// Tag 在 struct tag 中的标签名称
+const Tag = "csv"
+
+const bom = "\ufeff"
+
+var errUnexportedPointer = errors.New("无法为未导出的嵌入指针分配内存")
+
+type field struct {
+	name  string
+	index []int
+}
+
+func writeRecords(w *csv.Writer, v any) error {
+	switch obj := v.(type) {
+	case Marshaler:
+		records, err := obj.MarshalCSV()
+		if err != nil {
+			return err
+		}
+		return writeAll(w, records)
+	case [][]string:
+		return writeAll(w, obj)
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return mimetype.ErrUnsupported()
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Map: // 单个对象当作只有一个元素的切片处理
+		list := reflect.MakeSlice(reflect.SliceOf(rv.Type()), 0, 1)
+		rv = reflect.Append(list, rv)
+	case reflect.Slice, reflect.Array:
+	default:
+		return mimetype.ErrUnsupported()
+	}
+
+	switch t := indirect(rv.Type().Elem()); t.Kind() {
+	case reflect.Struct:
+		return writeStructs(w, rv, getFields(t))
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return errors.New("map 类型的键值只能是字符串")
+		}
+		return writeMaps(w, rv)
+	default:
+		return mimetype.ErrUnsupported()
+	}
+}
+
+func writeAll(w *csv.Writer, records [][]string) error {
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeStructs(w *csv.Writer, list reflect.Value, fields []*field) error {
+	record := make([]string, len(fields))
+	for i, f := range fields {
+		record[i] = f.name
+	}
+	if err := w.Write(record); err != nil {
+		return err
+	}
+
+	for i := range list.Len() {
+		elem := indirectValue(list.Index(i))
+		for j, f := range fields {
+			if elem.Kind() != reflect.Struct { // 空指针
+				record[j] = ""
+				continue
+			}
+
+			fv, err := elem.FieldByIndexErr(f.index) // 嵌入的指针可能为空
+			if err != nil {
+				record[j] = ""
+				continue
+			}
+
+			if record[j], err = format(fv); err != nil {
+				return err
+			}
+		}
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMaps(w *csv.Writer, list reflect.Value) error {
+	keys := map[string]struct{}{}
+	for i := range list.Len() {
+		elem := indirectValue(list.Index(i))
+		if elem.Kind() != reflect.Map {
+			continue
+		}
+		for iter := elem.MapRange(); iter.Next(); {
+			keys[iter.Key().String()] = struct{}{}
+		}
+	}
+	header := slices.Sorted(maps.Keys(keys))
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(header))
+	for i := range list.Len() {
+		elem := indirectValue(list.Index(i))
+		for j, key := range header {
+			if elem.Kind() != reflect.Map {
+				record[j] = ""
+				continue
+			}
+
+			v := elem.MapIndex(reflect.ValueOf(key).Convert(elem.Type().Key()))
+			if !v.IsValid() {
+				record[j] = ""
+				continue
+			}
+
+			var err error
+			if record[j], err = format(v); err != nil {
+				return err
+			}
+		}
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func format(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+			data, err := m.MarshalText()
+			return string(data), err
+		}
+		v = v.Elem()
+	}
+
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		data, err := m.MarshalText()
+		return string(data), err
+	}
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+			data, err := m.MarshalText()
+			return string(data), err
+		}
+	}
+
+	if v.Kind() == reflect.String {
+		return v.String(), nil
+	}
+	return fmt.Sprint(v.Interface()), nil
+}
+
+func readRecords(r *csv.Reader, v any) error {
+	switch obj := v.(type) {
+	case Unmarshaler:
+		records, err := readAll(r)
+		if err != nil {
+			return err
+		}
+		return obj.UnmarshalCSV(records)
+	case *[][]string:
+		records, err := readAll(r)
+		if err != nil {
+			return err
+		}
+		*obj = records
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return mimetype.ErrUnsupported()
+	}
+	rv = alloc(rv.Elem())
+
+	header, err := r.Read()
+	switch {
+	case errors.Is(err, io.EOF): // 空内容
+		return nil
+	case err != nil:
+		return err
+	}
+	header[0] = strings.TrimPrefix(header[0], bom)
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		record, err := r.Read()
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+		return setStruct(rv, header, record, getFields(rv.Type()), "")
+	case reflect.Slice:
+	default:
+		return mimetype.ErrUnsupported()
+	}
+
+	t := indirect(rv.Type().Elem())
+	var fields []*field
+	switch t.Kind() {
+	case reflect.Struct:
+		fields = getFields(t)
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return errors.New("map 类型的键值只能是字符串")
+		}
+	default:
+		return mimetype.ErrUnsupported()
+	}
+
+	for row := 0; ; row++ {
+		record, err := r.Read()
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		prefix := "[" + strconv.Itoa(row) + "]."
+		if fields != nil {
+			err = setStruct(alloc(elem), header, record, fields, prefix)
+		} else {
+			err = setMap(alloc(elem), header, record, prefix)
+		}
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.Append(rv, elem))
+	}
+}
+
+func readAll(r *csv.Reader) ([][]string, error) {
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], bom)
+	}
+	return records, nil
+}
+
+func setStruct(rv reflect.Value, header, record []string, fields []*field, prefix string) error {
+	for i, name := range header {
+		if i >= len(record) {
+			break
+		}
+
+		index := slices.IndexFunc(fields, func(f *field) bool { return f.name == name })
+		if index < 0 || record[i] == "" {
+			continue
+		}
+
+		fv := rv
+		for _, x := range fields[index].index { // 嵌入的指针需要分配内存
+			if fv.Kind() == reflect.Pointer && fv.IsNil() && !fv.CanSet() {
+				return web.NewFieldError(prefix+name, errUnexportedPointer)
+			}
+			fv = alloc(fv).Field(x)
+		}
+		if err := parse(fv, record[i]); err != nil {
+			return web.NewFieldError(prefix+name, err)
+		}
+	}
+	return nil
+}
+
+func setMap(rv reflect.Value, header, record []string, prefix string) error {
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMap(rv.Type()))
+	}
+
+	for i, name := range header {
+		if i >= len(record) {
+			break
+		}
+
+		v := reflect.New(rv.Type().Elem()).Elem()
+		if record[i] != "" {
+			if err := parse(v, record[i]); err != nil {
+				return web.NewFieldError(prefix+name, err)
+			}
+		}
+		rv.SetMapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()), v)
+	}
+	return nil
+}
+
+func parse(v reflect.Value, val string) error {
+	v = alloc(v)
+	if m, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return m.UnmarshalText([]byte(val))
+	}
+	if v.Kind() == reflect.String {
+		v.SetString(val)
+		return nil
+	}
+	return conv.Value(val, v)
+}
+
+// 获取结构体中可导出的字段
+//
+// 与 encoding/json 的规则相同：
+//   - 未指定名称的嵌入结构体会被展开，包括未导出的嵌入结构体中可导出的字段；
+//   - 指定了名称的嵌入结构体作为普通字段处理；
+//   - 同名的字段以层级浅的为准，层级相同时以指定了名称的为准，否则都被忽略。
+func getFields(t reflect.Type) []*field {
+	type candidate struct {
+		field
+		depth  int
+		tagged bool
+	}
+
+	var candidates []*candidate
+	visiting := map[reflect.Type]bool{}
+	var walk func(reflect.Type, []int)
+	walk = func(t reflect.Type, index []int) {
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		for i := range t.NumField() {
+			f := t.Field(i)
+			ft := indirect(f.Type)
+			if f.Anonymous {
+				if !f.IsExported() && ft.Kind() != reflect.Struct {
+					continue
+				}
+			} else if !f.IsExported() {
+				continue
+			}
+
+			name, _, _ := strings.Cut(f.Tag.Get(Tag), ",")
+			if name = strings.TrimSpace(name); name == "-" {
+				continue
+			}
+
+			idx := append(slices.Clip(index), i)
+			if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				if !visiting[ft] { // 防止循环嵌入
+					walk(ft, idx)
+				}
+				continue
+			}
+
+			c := &candidate{field: field{name: name, index: idx}, depth: len(idx), tagged: name != ""}
+			if !c.tagged {
+				c.name = f.Name
+			}
+			candidates = append(candidates, c)
+		}
+	}
+	walk(t, nil)
+
+	fields := make([]*field, 0, len(candidates))
+	for _, c := range candidates {
+		dominant := true
+		for _, other := range candidates {
+			if other == c || other.name != c.name {
+				continue
+			}
+			if other.depth < c.depth || (other.depth == c.depth && (other.tagged || !c.tagged)) {
+				dominant = false
+				break
+			}
+		}
+		if dominant {
+			fields = append(fields, &c.field)
+		}
+	}
+	return fields
+}
+
+// 为指针类型分配内存，返回最终指向的值。
+func alloc(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
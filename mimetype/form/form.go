@@ -30,9 +30,20 @@
 //
 // 该方式对数据类型有一定限制：
 //   - 如果是 map 类型，要求键名类型必须为 string；
-//   - 如果是 array 或是 slice，则要求元素类型必须是 go 的基本数据类型，不能是 struct 类型；
+//   - 如果是 array 或是 slice，则要求元素类型必须是 go 的基本数据类型、struct 或是 map；
 //   - 其它基本类型或是实现了 [encoding.TextUnmarshaler] 和 [encoding.TextMarshaler] 接口的类型；
 //
+// # 嵌套
+//
+// 嵌套的 struct 和 map 以及元素为 struct 或 map 的切片，可以使用以下两种格式的键名表示：
+//
+//	user.name=caixw&items.0.name=x
+//	user[name]=caixw&items[0][name]=x
+//
+// 解码时两种格式都支持，也可以混合使用，编码时由 [Syntax] 决定采用哪一种，[Marshal] 采用的是 [Dot]。
+// 元素为基本类型的切片，依然采用重复键名的方式，但是解码时也支持 a[]=1&a[]=2 和 a[0]=1&a[1]=2 的形式。
+// 切片的索引不能大于 1000。
+//
 // # 接口
 //
 // 对于复杂类型，用户可以自定义实现 [Marshaler] 和 [Unmarshaler] 接口进行编解码，
@@ -65,6 +76,16 @@ type Unmarshaler interface {
 	UnmarshalForm([]byte) error
 }
 
+// Syntax 编码时嵌套字段的键名格式
+type Syntax int8
+
+const (
+	Dot     Syntax = iota // a.b.0.c
+	Bracket               // a[b][0][c]
+)
+
+var dotMarshal = NewMarshal(Dot)
+
 // Marshal 针对 www-form-urlencoded 内容的解码实现
 //
 // 按以下顺序解析内容：
@@ -72,21 +93,30 @@ type Unmarshaler interface {
 //   - 如果实现 [encoding.TextMarshaler] 接口，则调用该接口；
 //   - 如果是 [url.Values] 对象，则调用其方法 Encode 解析；
 //   - 否则将对象的字段与 form-data 中的数据进行对比，可以使用 form 指定字段名。
-func Marshal(_ *web.Context, v any) ([]byte, error) {
-	if m, ok := v.(Marshaler); ok {
-		return m.MarshalForm()
-	}
+//
+// 嵌套字段采用 [Dot] 格式。
+func Marshal(ctx *web.Context, v any) ([]byte, error) { return dotMarshal(ctx, v) }
 
-	if vals, ok := v.(url.Values); ok {
-		return []byte(vals.Encode()), nil
-	}
+// NewMarshal 声明嵌套字段采用 s 格式的编码方法
+//
+// 除了嵌套字段的格式，其它与 [Marshal] 相同。
+func NewMarshal(s Syntax) web.MarshalFunc {
+	return func(_ *web.Context, v any) ([]byte, error) {
+		if m, ok := v.(Marshaler); ok {
+			return m.MarshalForm()
+		}
 
-	vals, err := marshal(v)
-	if err != nil {
-		return nil, err
-	}
+		if vals, ok := v.(url.Values); ok {
+			return []byte(vals.Encode()), nil
+		}
+
+		vals, err := marshal(v, s)
+		if err != nil {
+			return nil, err
+		}
 
-	return []byte(vals.Encode()), nil
+		return []byte(vals.Encode()), nil
+	}
 }
 
 // Unmarshal 针对 www-form-urlencoded 内容的编码实现
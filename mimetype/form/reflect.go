@@ -10,10 +10,13 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
 	"unicode"
 
 	"github.com/issue9/conv"
+
+	"github.com/issue9/web/internal/nested"
 )
 
 // Tag 在 struct tag 中的标签名称
@@ -22,9 +25,9 @@ const Tag = "form"
 // 将 v 转换成 form-data 格式的数据
 //
 // NOTE: form-data 中不需要考虑 omitempty 的情况，因为无法处理数组和切片在有没有 omitempty 下的区别。
-func marshal(v any) (url.Values, error) {
+func marshal(v any, s Syntax) (url.Values, error) {
 	objs := map[string]reflect.Value{}
-	if err := getFields(objs, "", reflect.ValueOf(v)); err != nil {
+	if err := s.getFields(objs, "", reflect.ValueOf(v)); err != nil {
 		return nil, err
 	}
 
@@ -68,7 +71,7 @@ func marshal(v any) (url.Values, error) {
 func unmarshal(vals url.Values, obj any) error {
 	val := reflect.ValueOf(obj)
 	for k, v := range vals {
-		if err := setField(val, strings.Split(k, "."), v); err != nil {
+		if err := setField(val, nested.Split(k), v); err != nil {
 			return err
 		}
 	}
@@ -96,11 +99,18 @@ func setField(obj reflect.Value, names []string, val []string) error {
 		return conv.Value(val, obj)
 	}
 
+	if len(names) == 1 && names[0] == "" { // a[] 与 a 相同
+		names = nil
+	}
+
 	if len(names) == 0 {
 		if k := obj.Kind(); k != reflect.Slice && k != reflect.Array {
 			return unmarshalByInterface(obj, val[0])
 		}
 
+		if isIndexedSlice(obj.Type()) {
+			return errors.New("元素为结构体或是 map 的切片必须指定索引")
+		}
 		chkSliceType(obj)
 		slice := obj
 		for i := 0; i < len(val); i++ {
@@ -126,6 +136,8 @@ func setField(obj reflect.Value, names []string, val []string) error {
 			obj.Set(reflect.MakeMap(obj.Type()))
 		}
 		return setMapField(obj, names, val)
+	case reflect.Slice, reflect.Array:
+		return setSliceField(obj, names, val)
 	case reflect.Func, reflect.Chan:
 		return nil
 	default:
@@ -170,7 +182,33 @@ func setMapField(obj reflect.Value, names []string, val []string) error {
 	return nil
 }
 
-func getFields(kv map[string]reflect.Value, name string, rv reflect.Value) error {
+func setSliceField(obj reflect.Value, names []string, val []string) error {
+	index, err := nested.Index(names[0])
+	if err != nil {
+		return err
+	}
+
+	if l := obj.Len(); index >= l {
+		if obj.Kind() == reflect.Array {
+			return fmt.Errorf("索引 %d 超出了数组的长度 %d", index, l)
+		}
+		obj.Set(reflect.AppendSlice(obj, reflect.MakeSlice(obj.Type(), index+1-l, index+1-l)))
+	}
+	return setField(obj.Index(index), names[1:], val)
+}
+
+func (s Syntax) join(parent, name string) string {
+	switch {
+	case parent == "":
+		return name
+	case s == Bracket:
+		return parent + "[" + name + "]"
+	default:
+		return parent + "." + name
+	}
+}
+
+func (s Syntax) getFields(kv map[string]reflect.Value, name string, rv reflect.Value) error {
 	for rv.Kind() == reflect.Ptr {
 		if rv.IsNil() {
 			rv.Set(reflect.New(rv.Type().Elem()))
@@ -184,12 +222,24 @@ func getFields(kv map[string]reflect.Value, name string, rv reflect.Value) error
 
 	switch rv.Kind() {
 	case reflect.Struct:
-		return getStructFields(kv, name, rv)
+		return s.getStructFields(kv, name, rv)
 	case reflect.Map:
 		if rv.Type().Key().Kind() != reflect.String {
 			return errors.New("map 类型的键值只能是字符串")
 		}
-		return getMapFields(kv, name, rv)
+		return s.getMapFields(kv, name, rv)
+	case reflect.Slice, reflect.Array:
+		if !isIndexedSlice(rv.Type()) {
+			kv[name] = rv
+			return nil
+		}
+
+		for i := 0; i < rv.Len(); i++ {
+			if err := s.getFields(kv, s.join(name, strconv.Itoa(i)), rv.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
 	case reflect.Chan, reflect.Func:
 		return nil
 	default:
@@ -198,13 +248,13 @@ func getFields(kv map[string]reflect.Value, name string, rv reflect.Value) error
 	}
 }
 
-func getStructFields(kv map[string]reflect.Value, parent string, rv reflect.Value) error {
+func (s Syntax) getStructFields(kv map[string]reflect.Value, parent string, rv reflect.Value) error {
 	rtype := rv.Type()
 	for i := 0; i < rtype.NumField(); i++ {
 		field := rtype.Field(i)
 
 		if field.Anonymous {
-			if err := getFields(kv, parent, rv.Field(i)); err != nil {
+			if err := s.getFields(kv, parent, rv.Field(i)); err != nil {
 				return err
 			}
 			continue
@@ -214,10 +264,7 @@ func getStructFields(kv map[string]reflect.Value, parent string, rv reflect.Valu
 		if name == "-" {
 			continue
 		}
-		if parent != "" {
-			name = parent + "." + name
-		}
-		if err := getFields(kv, name, rv.Field(i)); err != nil {
+		if err := s.getFields(kv, s.join(parent, name), rv.Field(i)); err != nil {
 			return err
 		}
 	}
@@ -225,14 +272,9 @@ func getStructFields(kv map[string]reflect.Value, parent string, rv reflect.Valu
 	return nil
 }
 
-func getMapFields(kv map[string]reflect.Value, parent string, rv reflect.Value) error {
+func (s Syntax) getMapFields(kv map[string]reflect.Value, parent string, rv reflect.Value) error {
 	for iter := rv.MapRange(); iter.Next(); {
-		name := iter.Key().String()
-		if parent != "" {
-			name = parent + "." + name
-		}
-
-		if err := getFields(kv, name, iter.Value()); err != nil {
+		if err := s.getFields(kv, s.join(parent, iter.Key().String()), iter.Value()); err != nil {
 			return err
 		}
 	}
@@ -253,6 +295,16 @@ func parseTag(sf reflect.StructField) string {
 	}
 }
 
+// 元素为结构体或是 map 的切片，需要以索引的方式表示每一个元素。
+func isIndexedSlice(t reflect.Type) bool {
+	t = t.Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	k := t.Kind()
+	return k == reflect.Struct || k == reflect.Map
+}
+
 func chkSliceType(v reflect.Value) {
 	k := v.Type().Elem().Kind()
 	if (k < reflect.Bool || k > reflect.Float64) && k != reflect.String {
@@ -6,6 +6,7 @@ package form
 
 import (
 	"encoding"
+	"net/url"
 	"strings"
 	"testing"
 
@@ -148,3 +149,82 @@ func TestMarshalWithFormTag(t *testing.T) {
 	nestObj := &nestObject{}
 	a.NotError(Unmarshal(strings.NewReader(nestString), nestObj)).Equal(nestObj, nestData)
 }
+
+type sliceObject struct {
+	Items  []*TagObject         `form:"items"`
+	Maps   map[string][]*Sex    `form:"maps"`
+	Arr    [2]map[string]string `form:"arr"`
+	Values []int                `form:"values"`
+}
+
+func TestMarshal_nested(t *testing.T) {
+	a := assert.New(t, false)
+
+	obj := &sliceObject{
+		Items: []*TagObject{
+			{Name: "n1", Age: 1, Friend: []string{"f1", "f2"}},
+			{Name: "n2", Sex: 1},
+		},
+		Arr:    [2]map[string]string{{"k": "v1"}, {"k": "v2"}},
+		Values: []int{1, 2},
+	}
+
+	data, err := Marshal(nil, obj)
+	a.NotError(err)
+	vals, err := url.ParseQuery(string(data))
+	a.NotError(err).Equal(vals, url.Values{
+		"items.0.Name":   {"n1"},
+		"items.0.age":    {"1"},
+		"items.0.friend": {"f1", "f2"},
+		"items.0.sex":    {"male"},
+		"items.1.Name":   {"n2"},
+		"items.1.age":    {"0"},
+		"items.1.sex":    {"female"},
+		"arr.0.k":        {"v1"},
+		"arr.1.k":        {"v2"},
+		"values":         {"1", "2"},
+	})
+
+	data, err = NewMarshal(Bracket)(nil, obj)
+	a.NotError(err)
+	vals, err = url.ParseQuery(string(data))
+	a.NotError(err).Equal(vals, url.Values{
+		"items[0][Name]":   {"n1"},
+		"items[0][age]":    {"1"},
+		"items[0][friend]": {"f1", "f2"},
+		"items[0][sex]":    {"male"},
+		"items[1][Name]":   {"n2"},
+		"items[1][age]":    {"0"},
+		"items[1][sex]":    {"female"},
+		"arr[0][k]":        {"v1"},
+		"arr[1][k]":        {"v2"},
+		"values":           {"1", "2"},
+	})
+
+	// 混合格式
+	obj2 := &sliceObject{}
+	a.NotError(Unmarshal(strings.NewReader(string(data)+"&items.1.friend=f3&arr.1[j]=v3"), obj2))
+	a.Equal(obj2.Items, []*TagObject{
+		{Name: "n1", Age: 1, Friend: []string{"f1", "f2"}},
+		{Name: "n2", Sex: 1, Friend: []string{"f3"}},
+	}).
+		Equal(obj2.Arr[0], map[string]string{"k": "v1"}).
+		Equal(obj2.Arr[1], map[string]string{"k": "v2", "j": "v3"}).
+		Equal(obj2.Values, []int{1, 2})
+
+	female := Sex(1)
+	obj2 = &sliceObject{}
+	a.NotError(Unmarshal(strings.NewReader("values[1]=2&values[0]=1&maps[k][1]=female"), obj2))
+	a.Equal(obj2.Values, []int{1, 2}).
+		Equal(obj2.Maps, map[string][]*Sex{"k": {nil, &female}})
+
+	obj2 = &sliceObject{}
+	a.NotError(Unmarshal(strings.NewReader("values[]=1&values[]=2"), obj2))
+	a.Equal(obj2.Values, []int{1, 2})
+
+	// 索引错误
+	a.Error(Unmarshal(strings.NewReader("items[x][name]=1"), &sliceObject{}))
+	a.Error(Unmarshal(strings.NewReader("items[1001][name]=1"), &sliceObject{}))
+	a.Error(Unmarshal(strings.NewReader("arr[2][k]=1"), &sliceObject{}))
+	a.Error(Unmarshal(strings.NewReader("items=1"), &sliceObject{}))
+}
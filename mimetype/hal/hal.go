@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package hal 提供 [HAL] 格式的序列化方法
+//
+// 对象本身按 JSON 进行编码，如果对象实现了 [Linker] 或是 [Embedder]，
+// 会在编码后的对象中添加 _links 和 _embedded 字段：
+//
+//	type User struct {
+//	    ID   int    `json:"id"`
+//	    Name string `json:"name"`
+//	}
+//
+//	func (u *User) HALLinks(*web.Context) hal.Links {
+//	    return hal.Links{"self": {{Href: "/users/" + strconv.Itoa(u.ID)}}}
+//	}
+//
+// [web.Problem] 会以 [vnd.error] 的格式输出，其中 params 的每一项作为 _embedded.errors 的元素。
+//
+// 解码时与 JSON 相同，_links 和 _embedded 会被忽略。
+//
+// [HAL]: https://datatracker.ietf.org/doc/html/draft-kelly-json-hal
+// [vnd.error]: https://github.com/blongden/vnd.error
+package hal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/internal/nested"
+)
+
+const (
+	Mimetype        = "application/hal+json"
+	ProblemMimetype = "application/vnd.error+json"
+)
+
+type (
+	// Link HAL 中的链接对象
+	Link struct {
+		Href        string `json:"href"`
+		Templated   bool   `json:"templated,omitempty"`
+		Type        string `json:"type,omitempty"`
+		Deprecation string `json:"deprecation,omitempty"`
+		Name        string `json:"name,omitempty"`
+		Profile     string `json:"profile,omitempty"`
+		Title       string `json:"title,omitempty"`
+		HrefLang    string `json:"hreflang,omitempty"`
+	}
+
+	// Links 链接列表
+	//
+	// 键名为关系名称，比如 self、next 等。只有一个元素时输出为对象，否则输出为数组。
+	Links map[string][]*Link
+
+	// Linker 需要输出 _links 的对象实现此接口
+	Linker interface {
+		HALLinks(*web.Context) Links
+	}
+
+	// Embedder 需要输出 _embedded 的对象实现此接口
+	Embedder interface {
+		// HALEmbedded 返回内嵌的资源
+		//
+		// 键名为关系名称，键值可以是单个对象或是切片，
+		// 其中的对象如果实现了 [Linker] 或 [Embedder] 也会被包装。
+		HALEmbedded(*web.Context) map[string]any
+	}
+
+	// vnd.error 格式的错误信息
+	vndError struct {
+		Message    string    `json:"message"`
+		Path       string    `json:"path,omitempty"`
+		Logref     string    `json:"logref,omitempty"`
+		Detail     string    `json:"detail,omitempty"`
+		Status     int       `json:"status,omitempty"`
+		Extensions any       `json:"extensions,omitempty"`
+		Links      Links     `json:"_links,omitempty"`
+		Embedded   *embedded `json:"_embedded,omitempty"`
+	}
+
+	embedded struct {
+		Errors []*vndError `json:"errors"`
+	}
+)
+
+func (l Links) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(l))
+	for rel, links := range l {
+		if len(links) == 1 {
+			m[rel] = links[0]
+		} else {
+			m[rel] = links
+		}
+	}
+	return json.Marshal(m)
+}
+
+func (l *Links) UnmarshalJSON(data []byte) error {
+	m := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	*l = make(Links, len(m))
+	for rel, raw := range m {
+		var links []*Link
+		if len(raw) > 0 && raw[0] == '{' {
+			links = []*Link{{}}
+			if err := json.Unmarshal(raw, links[0]); err != nil {
+				return err
+			}
+		} else if err := json.Unmarshal(raw, &links); err != nil {
+			return err
+		}
+		(*l)[rel] = links
+	}
+	return nil
+}
+
+func Marshal(ctx *web.Context, v any) ([]byte, error) {
+	if p, ok := v.(*web.Problem); ok {
+		return json.Marshal(newError(p))
+	}
+	return marshal(ctx, v)
+}
+
+func Unmarshal(r io.Reader, v any) error { return json.NewDecoder(r).Decode(v) }
+
+func Encode(ctx *web.Context, w io.Writer, v any) error {
+	data, err := Marshal(ctx, v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func marshal(ctx *web.Context, v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var links Links
+	if l, ok := v.(Linker); ok {
+		links = l.HALLinks(ctx)
+	}
+	var items map[string]any
+	if e, ok := v.(Embedder); ok {
+		items = e.HALEmbedded(ctx)
+	}
+	if len(links) == 0 && len(items) == 0 {
+		return data, nil
+	}
+
+	if len(data) < 2 || data[0] != '{' {
+		return nil, fmt.Errorf("%T 不是对象，无法添加 _links 和 _embedded", v)
+	}
+
+	buf := bytes.NewBuffer(data[:len(data)-1]) // 去掉最后的 }
+	comma := len(data) > 2                     // json.Marshal 输出的空对象为 {}
+
+	if len(links) > 0 {
+		l, err := json.Marshal(links)
+		if err != nil {
+			return nil, err
+		}
+
+		if comma {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"_links":`)
+		buf.Write(l)
+		comma = true
+	}
+
+	if len(items) > 0 {
+		if comma {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"_embedded":{`)
+		for i, rel := range slices.Sorted(maps.Keys(items)) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(rel)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+
+			if err := marshalEmbedded(ctx, buf, items[rel]); err != nil {
+				return nil, err
+			}
+		}
+		buf.WriteByte('}')
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// 切片中的每个元素都需要单独包装
+func marshalEmbedded(ctx *web.Context, buf *bytes.Buffer, v any) error {
+	rv := reflect.ValueOf(v)
+	if k := rv.Kind(); k != reflect.Slice && k != reflect.Array {
+		data, err := marshal(ctx, v)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		return nil
+	}
+
+	buf.WriteByte('[')
+	for i := range rv.Len() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		data, err := marshal(ctx, rv.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+func newError(p *web.Problem) *vndError {
+	e := &vndError{
+		Message:    p.Title,
+		Logref:     p.Type,
+		Detail:     p.Detail,
+		Status:     p.Status,
+		Extensions: p.Extensions,
+	}
+
+	if p.Instance != "" {
+		e.Links = Links{"about": {{Href: p.Instance}}}
+	}
+
+	if len(p.Params) > 0 {
+		e.Embedded = &embedded{Errors: make([]*vndError, 0, len(p.Params))}
+		for _, param := range p.Params {
+			e.Embedded.Errors = append(e.Embedded.Errors, &vndError{
+				Message: param.Reason,
+				Path:    nested.Pointer(param.Name),
+			})
+		}
+	}
+
+	return e
+}
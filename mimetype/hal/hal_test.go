@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package hal
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+	"github.com/issue9/web/server"
+	"github.com/issue9/web/server/servertest"
+)
+
+var (
+	_ web.MarshalFunc   = Marshal
+	_ web.UnmarshalFunc = Unmarshal
+	_ web.EncodeFunc    = Encode
+	_ openapi.Envelope  = Envelope
+)
+
+type (
+	order struct {
+		ID    int    `json:"id"`
+		Total int    `json:"total"`
+		Items []item `json:"-"`
+	}
+
+	item struct {
+		Name string `json:"name"`
+	}
+
+	empty struct{}
+)
+
+func (o *order) HALLinks(*web.Context) Links {
+	return Links{
+		"self":  {{Href: "/orders/" + strconv.Itoa(o.ID)}},
+		"items": {{Href: "/items/1"}, {Href: "/items/2"}},
+	}
+}
+
+func (o *order) HALEmbedded(*web.Context) map[string]any {
+	return map[string]any{"items": o.Items}
+}
+
+func (i item) HALLinks(*web.Context) Links {
+	return Links{"self": {{Href: "/items/" + i.Name}}}
+}
+
+func (e *empty) HALLinks(*web.Context) Links {
+	return Links{"self": {{Href: "/empty", Templated: true}}}
+}
+
+func TestMarshal(t *testing.T) {
+	a := assert.New(t, false)
+
+	data, err := Marshal(nil, &order{ID: 1, Total: 5, Items: []item{{Name: "a"}, {Name: "b"}}})
+	a.NotError(err).
+		Equal(string(data), `{"id":1,"total":5,"_links":{"items":[{"href":"/items/1"},{"href":"/items/2"}],"self":{"href":"/orders/1"}},"_embedded":{"items":[{"name":"a","_links":{"self":{"href":"/items/a"}}},{"name":"b","_links":{"self":{"href":"/items/b"}}}]}}`)
+
+	data, err = Marshal(nil, &empty{})
+	a.NotError(err).
+		Equal(string(data), `{"_links":{"self":{"href":"/empty","templated":true}}}`)
+
+	// 未实现接口
+	data, err = Marshal(nil, []int{1, 2})
+	a.NotError(err).Equal(string(data), `[1,2]`)
+
+	p := &web.Problem{Type: "400", Title: "bad request", Status: 400, Instance: "/orders/1", Params: []web.ProblemParam{{Name: "items[0].name", Reason: "r1"}}}
+	data, err = Marshal(nil, p)
+	a.NotError(err).
+		Equal(string(data), `{"message":"bad request","logref":"400","status":400,"_links":{"about":{"href":"/orders/1"}},"_embedded":{"errors":[{"message":"r1","path":"/items/0/name"}]}}`)
+
+	buf := &bytes.Buffer{}
+	a.NotError(Encode(nil, buf, &empty{})).
+		Equal(buf.String(), `{"_links":{"self":{"href":"/empty","templated":true}}}`)
+}
+
+func TestUnmarshal(t *testing.T) {
+	a := assert.New(t, false)
+
+	o := &order{}
+	a.NotError(Unmarshal(strings.NewReader(`{"id":1,"total":5,"_links":{"self":{"href":"/orders/1"}}}`), o)).
+		Equal(o, &order{ID: 1, Total: 5})
+}
+
+func TestLinks(t *testing.T) {
+	a := assert.New(t, false)
+
+	l := Links{}
+	a.NotError(json.Unmarshal([]byte(`{"self":{"href":"/1"},"items":[{"href":"/2"},{"href":"/3","name":"3"}]}`), &l)).
+		Equal(l, Links{
+			"self":  {{Href: "/1"}},
+			"items": {{Href: "/2"}, {Href: "/3", Name: "3"}},
+		})
+
+	a.Error(json.Unmarshal([]byte(`{"self":1}`), &l))
+}
+
+func TestEnvelope(t *testing.T) {
+	a := assert.New(t, false)
+
+	s := &openapi.Schema{Type: openapi.TypeObject}
+	e := Envelope(s, false)
+	a.Length(e.AllOf, 2).
+		Equal(e.AllOf[0], s)
+
+	s = &openapi.Schema{Type: openapi.TypeArray}
+	a.Equal(Envelope(s, false), s)
+
+	e = Envelope(s, true)
+	a.Equal(e.Type, openapi.TypeObject).
+		Equal(e.Required, []string{"message"})
+}
+
+func TestServer(t *testing.T) {
+	a := assert.New(t, false)
+
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Codec:      web.NewCodec().AddMimetype(Mimetype, Marshal, Unmarshal, ProblemMimetype, true, true, Encode),
+	})
+	a.NotError(err).NotNil(s)
+
+	r := s.Routers().New("default", nil)
+	r.Get("/orders/1", func(ctx *web.Context) web.Responser {
+		return web.OK(&order{ID: 1, Total: 5})
+	})
+	r.Get("/problem", func(ctx *web.Context) web.Responser {
+		return ctx.Problem(web.ProblemBadRequest).WithParam("total", "r1")
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	servertest.Get(a, "http://localhost:8080/orders/1").
+		Header(header.Accept, Mimetype).
+		Do(nil).
+		Status(http.StatusOK).
+		Header(header.ContentType, Mimetype+"; charset=utf-8").
+		StringBody(`{"id":1,"total":5,"_links":{"items":[{"href":"/items/1"},{"href":"/items/2"}],"self":{"href":"/orders/1"}},"_embedded":{"items":[]}}`)
+
+	servertest.Get(a, "http://localhost:8080/problem").
+		Header(header.Accept, Mimetype).
+		Do(nil).
+		Status(http.StatusBadRequest).
+		Header(header.ContentType, ProblemMimetype+"; charset=utf-8").
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			e := &vndError{}
+			a.NotError(json.Unmarshal(body, e)).
+				Equal(e.Status, http.StatusBadRequest).
+				Equal(e.Embedded.Errors, []*vndError{{Message: "r1", Path: "/total"}})
+		})
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package hal
+
+import (
+	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+)
+
+// Envelope 将 s 包装为 HAL 格式的 [openapi.Schema]
+//
+// 可作为 [openapi.WithEnvelope] 的参数：
+//
+//	openapi.WithEnvelope(hal.Mimetype, hal.Envelope)
+func Envelope(s *openapi.Schema, problem bool) *openapi.Schema {
+	if problem {
+		return errorSchema(true)
+	}
+
+	if s == nil || (s.Type != "" && s.Type != openapi.TypeObject) { // 非对象不会添加 _links 和 _embedded
+		return s
+	}
+
+	return &openapi.Schema{
+		AllOf: []*openapi.Schema{
+			s,
+			{
+				Type: openapi.TypeObject,
+				Properties: map[string]*openapi.Schema{
+					"_links": linksSchema(),
+					"_embedded": {
+						Type:                 openapi.TypeObject,
+						Description:          web.Phrase("hal embedded resources"),
+						AdditionalProperties: &openapi.Schema{},
+					},
+				},
+			},
+		},
+	}
+}
+
+func linksSchema() *openapi.Schema {
+	link := &openapi.Schema{
+		Type:     openapi.TypeObject,
+		Required: []string{"href"},
+		Properties: map[string]*openapi.Schema{
+			"href":        {Type: openapi.TypeString},
+			"templated":   {Type: openapi.TypeBoolean},
+			"type":        {Type: openapi.TypeString},
+			"deprecation": {Type: openapi.TypeString},
+			"name":        {Type: openapi.TypeString},
+			"profile":     {Type: openapi.TypeString},
+			"title":       {Type: openapi.TypeString},
+			"hreflang":    {Type: openapi.TypeString},
+		},
+	}
+
+	return &openapi.Schema{
+		Type:        openapi.TypeObject,
+		Description: web.Phrase("hal links"),
+		AdditionalProperties: &openapi.Schema{
+			OneOf: []*openapi.Schema{link, {Type: openapi.TypeArray, Items: link}},
+		},
+	}
+}
+
+// root 表示是否为顶层的错误对象，只有顶层对象才有 _embedded。
+func errorSchema(root bool) *openapi.Schema {
+	s := &openapi.Schema{
+		Type:     openapi.TypeObject,
+		Required: []string{"message"},
+		Properties: map[string]*openapi.Schema{
+			"message": {Type: openapi.TypeString},
+			"path":    {Type: openapi.TypeString},
+			"logref":  {Type: openapi.TypeString},
+		},
+	}
+
+	if root {
+		s.Description = web.Phrase("problem response schema desc")
+		s.Properties["detail"] = &openapi.Schema{Type: openapi.TypeString}
+		s.Properties["status"] = &openapi.Schema{Type: openapi.TypeInteger}
+		s.Properties["extensions"] = &openapi.Schema{}
+		s.Properties["_links"] = linksSchema()
+		s.Properties["_embedded"] = &openapi.Schema{
+			Type: openapi.TypeObject,
+			Properties: map[string]*openapi.Schema{
+				"errors": {Type: openapi.TypeArray, Items: errorSchema(false)},
+			},
+		}
+	}
+
+	return s
+}
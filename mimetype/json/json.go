@@ -6,8 +6,10 @@
 package json
 
 import (
+	"encoding"
 	"encoding/json"
 	"io"
+	"reflect"
 
 	"github.com/issue9/mux/v9/header"
 
@@ -19,6 +21,129 @@ const (
 	ProblemMimetype = "application/problem+json"
 )
 
+var (
+	marshalerType     = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	boolType          = reflect.TypeFor[bool]()
+)
+
 func Marshal(_ *web.Context, v any) ([]byte, error) { return json.Marshal(v) }
 
 func Unmarshal(r io.Reader, v any) error { return json.NewDecoder(r).Decode(v) }
+
+// Encode 以流的形式编码 v
+//
+// 切片、数组和 [iter.Seq] 会逐个元素编码并写入 w，其它类型与 [Marshal] 相同。
+// 输出内容与 [Marshal] 完全一致。
+func Encode(_ *web.Context, w io.Writer, v any) error { return encode(w, reflect.ValueOf(v)) }
+
+func encode(w io.Writer, rv reflect.Value) error {
+	for rv.IsValid() && rv.Kind() == reflect.Pointer && !implementsMarshaler(rv.Type()) {
+		if rv.IsNil() {
+			break
+		}
+		rv = rv.Elem()
+	}
+
+	if !rv.IsValid() || implementsMarshaler(rv.Type()) {
+		return marshalTo(w, rv)
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.IsNil() || rv.Type().Elem().Kind() == reflect.Uint8 { // []byte 编码为 base64
+			return marshalTo(w, rv)
+		}
+		fallthrough
+	case reflect.Array:
+		return encodeArray(w, rv.Len(), func(i int) reflect.Value {
+			if e := rv.Index(i); e.CanAddr() { // 与 encoding/json 相同，可寻址的元素会调用指针接收者的方法。
+				return e.Addr()
+			}
+			return rv.Index(i)
+		})
+	case reflect.Func:
+		if isSeq(rv.Type()) {
+			return encodeSeq(w, rv)
+		}
+	}
+
+	return marshalTo(w, rv)
+}
+
+func encodeArray(w io.Writer, l int, elem func(int) reflect.Value) error {
+	if _, err := w.Write([]byte{'['}); err != nil {
+		return err
+	}
+	for i := range l {
+		if i > 0 {
+			if _, err := w.Write([]byte{','}); err != nil {
+				return err
+			}
+		}
+		if err := marshalTo(w, elem(i)); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{']'})
+	return err
+}
+
+func encodeSeq(w io.Writer, rv reflect.Value) error {
+	if rv.IsNil() {
+		_, err := w.Write([]byte("null"))
+		return err
+	}
+
+	if _, err := w.Write([]byte{'['}); err != nil {
+		return err
+	}
+
+	var err error
+	first := true
+	yieldType := rv.Type().In(0)
+	yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+		if !first {
+			if _, err = w.Write([]byte{','}); err != nil {
+				return []reflect.Value{reflect.ValueOf(false)}
+			}
+		}
+		first = false
+		err = marshalTo(w, args[0])
+		return []reflect.Value{reflect.ValueOf(err == nil)}
+	})
+	rv.Call([]reflect.Value{yield})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte{']'})
+	return err
+}
+
+func marshalTo(w io.Writer, rv reflect.Value) error {
+	var v any
+	if rv.IsValid() {
+		v = rv.Interface()
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func implementsMarshaler(t reflect.Type) bool {
+	return t.Implements(marshalerType) || t.Implements(textMarshalerType)
+}
+
+// 是否为 func(func(T) bool) 形式的 [iter.Seq]
+func isSeq(t reflect.Type) bool {
+	if t.NumIn() != 1 || t.NumOut() != 0 {
+		return false
+	}
+	y := t.In(0)
+	return y.Kind() == reflect.Func && y.NumIn() == 1 && y.NumOut() == 1 && y.Out(0) == boolType
+}
@@ -4,9 +4,94 @@
 
 package json
 
-import "github.com/issue9/web"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/web"
+)
 
 var (
 	_ web.MarshalFunc   = Marshal
 	_ web.UnmarshalFunc = Unmarshal
+	_ web.EncodeFunc    = Encode
 )
+
+type object struct {
+	Name string `json:"name"`
+	Age  int    `json:"age,omitempty"`
+}
+
+type ptrMarshaler struct{ V int }
+
+func (p *ptrMarshaler) MarshalJSON() ([]byte, error) { return []byte(`"ptr"`), nil }
+
+type errMarshaler struct{}
+
+func (errMarshaler) MarshalJSON() ([]byte, error) { return nil, errors.New("err") }
+
+func TestEncode(t *testing.T) {
+	a := assert.New(t, false)
+
+	var nilSlice []int
+	var nilPtr *object
+	data := []any{
+		nil,
+		1,
+		"<html>",
+		&object{Name: "n"},
+		nilSlice,
+		nilPtr,
+		[]int{},
+		[]int{1, 2, 3},
+		&[]int{1, 2},
+		[2]string{"a", "b"},
+		[]byte("bytes"),
+		[]*object{{Name: "1"}, nil, {Name: "2", Age: 2}},
+		[]any{1, "2", nil, &object{}},
+		[]ptrMarshaler{{V: 1}, {V: 2}},
+		[]time.Time{time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		map[string][]int{"a": {1}},
+	}
+
+	for i, v := range data {
+		want, err := json.Marshal(v)
+		a.NotError(err, "at %d", i)
+
+		buf := &bytes.Buffer{}
+		a.NotError(Encode(nil, buf, v), "at %d", i).
+			Equal(buf.String(), string(want), "at %d", i)
+	}
+
+	// iter.Seq
+
+	buf := &bytes.Buffer{}
+	a.NotError(Encode(nil, buf, slices.Values([]*object{{Name: "1"}, {Name: "2"}})))
+	a.Equal(buf.String(), `[{"name":"1"},{"name":"2"}]`)
+
+	buf.Reset()
+	a.NotError(Encode(nil, buf, slices.Values([]int{})))
+	a.Equal(buf.String(), `[]`)
+
+	// 编码出错时中止迭代
+	count := 0
+	seq := func(yield func(any) bool) {
+		for _, v := range []any{1, errMarshaler{}, 3} {
+			count++
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	buf.Reset()
+	a.Error(Encode(nil, buf, seq)).Equal(count, 2)
+
+	buf.Reset()
+	a.Error(Encode(nil, buf, []any{1, errMarshaler{}}))
+}
@@ -0,0 +1,410 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package jsonapi 提供 [JSON:API] 格式的序列化方法
+//
+// 实现了 [Resource] 接口的对象会被包装为资源对象，
+// 对象本身按 JSON 编码之后作为 attributes 字段，其中的 id 和 type 字段会被去掉。
+// 如果对象还实现了 [Linker] 和 [Relationer]，会同时输出 links 和 relationships 字段：
+//
+//	type Article struct {
+//	    ID    int    `json:"id"`
+//	    Title string `json:"title"`
+//	}
+//
+//	func (a *Article) JSONAPIType() string { return "articles" }
+//	func (a *Article) JSONAPIID() string { return strconv.Itoa(a.ID) }
+//
+// 编码时 v 可以是以下类型：
+//   - [Resource] 或是元素为 [Resource] 的切片，作为文档的 data 字段；
+//   - [*Document] 可以自定义文档顶层的 links、meta 和 included 字段；
+//   - [web.Problem] 以 errors 字段输出，params 中的每一项对应一个错误对象；
+//   - nil 或是 [Resource] 的空指针输出为 {"data":null}；
+//   - 其它类型作为文档的 meta 字段；
+//
+// NOTE: [web.Context.Render] 在 body 为 nil 时只输出状态码，并不会调用编码方法，
+// 需要输出 {"data":null} 时可以采用 [Resource] 的空指针或是 &[Document]{}。
+//
+// 解码时仅支持单个资源对象，attributes 的内容按 JSON 解码至 v，
+// 如果 v 实现了 [Resource]，还会比对 type 字段，不一致时返回 409 错误。
+// id 和 relationships 可以通过 [IDSetter] 和 [RelationshipsSetter] 获取。
+//
+// [JSON:API]: https://jsonapi.org/format/
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/internal/nested"
+)
+
+const (
+	Mimetype        = "application/vnd.api+json"
+	ProblemMimetype = Mimetype
+)
+
+var resourceType = reflect.TypeFor[Resource]()
+
+type (
+	// Resource 作为资源对象输出的对象需要实现此接口
+	Resource interface {
+		JSONAPIType() string
+		JSONAPIID() string
+	}
+
+	// Linker 需要输出 links 的资源对象实现此接口
+	Linker interface {
+		JSONAPILinks(*web.Context) Links
+	}
+
+	// Relationer 需要输出 relationships 的资源对象实现此接口
+	Relationer interface {
+		// JSONAPIRelationships 返回资源的关联对象，键名为关联的名称。
+		JSONAPIRelationships(*web.Context) map[string]*Relationship
+	}
+
+	// IDSetter 解码时需要获取 id 的对象实现此接口
+	IDSetter interface {
+		SetJSONAPIID(string) error
+	}
+
+	// RelationshipsSetter 解码时需要获取 relationships 的对象实现此接口
+	RelationshipsSetter interface {
+		SetJSONAPIRelationships(map[string]*Relationship) error
+	}
+
+	// Link JSON:API 中的链接对象
+	//
+	// 如果只有 Href 字段，输出为字符串。
+	Link struct {
+		Href        string `json:"href"`
+		Rel         string `json:"rel,omitempty"`
+		DescribedBy string `json:"describedby,omitempty"`
+		Title       string `json:"title,omitempty"`
+		Type        string `json:"type,omitempty"`
+		HrefLang    string `json:"hreflang,omitempty"`
+		Meta        any    `json:"meta,omitempty"`
+	}
+
+	// Links 链接列表，键名为链接的名称，比如 self、related 等。
+	Links map[string]*Link
+
+	// Identifier 资源的标识
+	Identifier struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	}
+
+	// Relationship 资源的关联对象
+	//
+	// Links、Data 和 Meta 都为空时，data 输出为 null，表示空的一对一关联。
+	Relationship struct {
+		Links Links `json:"links,omitempty"`
+		Data  any   `json:"data,omitempty"` // *Identifier 或是 []*Identifier
+		Meta  any   `json:"meta,omitempty"`
+	}
+
+	// Document 自定义顶层文档
+	Document struct {
+		Data     any // [Resource] 或是元素为 [Resource] 的切片
+		Included []Resource
+		Links    Links
+		Meta     any
+	}
+
+	document struct {
+		Data     json.RawMessage   `json:"data,omitempty"`
+		Errors   []*errorObject    `json:"errors,omitempty"`
+		Meta     any               `json:"meta,omitempty"`
+		Links    Links             `json:"links,omitempty"`
+		Included []*resourceObject `json:"included,omitempty"`
+	}
+
+	resourceObject struct {
+		Type          string                   `json:"type"`
+		ID            string                   `json:"id,omitempty"`
+		Attributes    json.RawMessage          `json:"attributes,omitempty"`
+		Relationships map[string]*Relationship `json:"relationships,omitempty"`
+		Links         Links                    `json:"links,omitempty"`
+	}
+
+	errorObject struct {
+		ID     string       `json:"id,omitempty"`
+		Status string       `json:"status,omitempty"`
+		Code   string       `json:"code,omitempty"`
+		Title  string       `json:"title,omitempty"`
+		Detail string       `json:"detail,omitempty"`
+		Source *errorSource `json:"source,omitempty"`
+		Meta   any          `json:"meta,omitempty"`
+	}
+
+	errorSource struct {
+		Pointer string `json:"pointer,omitempty"`
+	}
+)
+
+func (l *Link) MarshalJSON() ([]byte, error) {
+	if l.Rel == "" && l.DescribedBy == "" && l.Title == "" && l.Type == "" && l.HrefLang == "" && l.Meta == nil {
+		return json.Marshal(l.Href)
+	}
+
+	type link Link
+	return json.Marshal((*link)(l))
+}
+
+func (l *Link) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &l.Href)
+	}
+
+	type link Link
+	return json.Unmarshal(data, (*link)(l))
+}
+
+func (r *Relationship) MarshalJSON() ([]byte, error) {
+	if r.Data == nil && len(r.Links) == 0 && r.Meta == nil {
+		return []byte(`{"data":null}`), nil
+	}
+
+	type relationship Relationship
+	return json.Marshal((*relationship)(r))
+}
+
+func (r *Relationship) UnmarshalJSON(data []byte) error {
+	var rr struct {
+		Links Links           `json:"links,omitempty"`
+		Data  json.RawMessage `json:"data,omitempty"`
+		Meta  any             `json:"meta,omitempty"`
+	}
+	if err := json.Unmarshal(data, &rr); err != nil {
+		return err
+	}
+
+	r.Links = rr.Links
+	r.Meta = rr.Meta
+	r.Data = nil
+	switch {
+	case len(rr.Data) == 0 || string(rr.Data) == "null":
+	case rr.Data[0] == '[':
+		ids := []*Identifier{}
+		if err := json.Unmarshal(rr.Data, &ids); err != nil {
+			return err
+		}
+		r.Data = ids
+	default:
+		id := &Identifier{}
+		if err := json.Unmarshal(rr.Data, id); err != nil {
+			return err
+		}
+		r.Data = id
+	}
+	return nil
+}
+
+func Marshal(ctx *web.Context, v any) ([]byte, error) {
+	doc := &document{}
+
+	switch vv := v.(type) {
+	case *web.Problem:
+		doc.Errors = newErrors(vv)
+	case *Document:
+		data, ok, err := marshalData(ctx, vv.Data)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("Document.Data 的类型 %T 不是 Resource", vv.Data)
+		}
+		doc.Data = data
+		doc.Links = vv.Links
+		doc.Meta = vv.Meta
+
+		for _, r := range vv.Included {
+			obj, err := newResourceObject(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			doc.Included = append(doc.Included, obj)
+		}
+	default:
+		data, ok, err := marshalData(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			doc.Data = data
+		} else {
+			doc.Meta = v
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+func Unmarshal(r io.Reader, v any) error {
+	var doc struct {
+		Data *struct {
+			Type          string                   `json:"type"`
+			ID            string                   `json:"id"`
+			Attributes    json.RawMessage          `json:"attributes"`
+			Relationships map[string]*Relationship `json:"relationships"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+
+	data := doc.Data
+	if data == nil {
+		return errors.New("缺少 data 字段")
+	}
+
+	if res, ok := v.(Resource); ok && res.JSONAPIType() != data.Type {
+		return web.NewError(http.StatusConflict, web.NewLocaleError("resource type %s does not match %s", data.Type, res.JSONAPIType()))
+	}
+
+	if len(data.Attributes) > 0 {
+		if err := json.Unmarshal(data.Attributes, v); err != nil {
+			return err
+		}
+	}
+
+	if s, ok := v.(IDSetter); ok && data.ID != "" {
+		if err := s.SetJSONAPIID(data.ID); err != nil {
+			return err
+		}
+	}
+
+	if s, ok := v.(RelationshipsSetter); ok && len(data.Relationships) > 0 {
+		return s.SetJSONAPIRelationships(data.Relationships)
+	}
+	return nil
+}
+
+func Encode(ctx *web.Context, w io.Writer, v any) error {
+	data, err := Marshal(ctx, v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// 将 v 转换为 data 字段的内容，如果 v 不是资源对象，返回 false。
+func marshalData(ctx *web.Context, v any) (json.RawMessage, bool, error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || (rv.Kind() == reflect.Pointer && rv.IsNil()) {
+		return json.RawMessage("null"), true, nil
+	}
+
+	if r, ok := v.(Resource); ok {
+		obj, err := newResourceObject(ctx, r)
+		if err != nil {
+			return nil, false, err
+		}
+		data, err := json.Marshal(obj)
+		return data, true, err
+	}
+
+	if k := rv.Kind(); k != reflect.Slice && k != reflect.Array {
+		return nil, false, nil
+	}
+	if et := rv.Type().Elem(); !et.Implements(resourceType) && !reflect.PointerTo(et).Implements(resourceType) {
+		return nil, false, nil
+	}
+
+	objs := make([]*resourceObject, 0, rv.Len())
+	for i := range rv.Len() {
+		elem := rv.Index(i)
+		if !elem.Type().Implements(resourceType) {
+			if elem.CanAddr() {
+				elem = elem.Addr()
+			} else { // 以值传递的数组，元素无法取地址。
+				ptr := reflect.New(elem.Type())
+				ptr.Elem().Set(elem)
+				elem = ptr
+			}
+		}
+
+		obj, err := newResourceObject(ctx, elem.Interface().(Resource))
+		if err != nil {
+			return nil, false, err
+		}
+		objs = append(objs, obj)
+	}
+	data, err := json.Marshal(objs)
+	return data, true, err
+}
+
+func newResourceObject(ctx *web.Context, r Resource) (*resourceObject, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+	delete(attrs, "id") // id 和 type 不能出现在 attributes 中
+	delete(attrs, "type")
+
+	obj := &resourceObject{Type: r.JSONAPIType(), ID: r.JSONAPIID()}
+	if len(attrs) > 0 {
+		if obj.Attributes, err = json.Marshal(attrs); err != nil {
+			return nil, err
+		}
+	}
+
+	if l, ok := r.(Linker); ok {
+		obj.Links = l.JSONAPILinks(ctx)
+	}
+	if rel, ok := r.(Relationer); ok {
+		obj.Relationships = rel.JSONAPIRelationships(ctx)
+	}
+
+	return obj, nil
+}
+
+func newErrors(p *web.Problem) []*errorObject {
+	newError := func() *errorObject {
+		return &errorObject{
+			ID:     p.Instance,
+			Status: strconv.Itoa(p.Status),
+			Code:   p.Type,
+			Title:  p.Title,
+			Detail: p.Detail,
+			Meta:   p.Extensions,
+		}
+	}
+
+	if len(p.Params) == 0 {
+		return []*errorObject{newError()}
+	}
+
+	errs := make([]*errorObject, 0, len(p.Params))
+	for _, param := range p.Params {
+		e := newError()
+		e.Detail = param.Reason
+		e.Source = &errorSource{Pointer: pointer(param.Name)}
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// 以 / 开头的表示已经是 JSON Pointer，否则指向 attributes 中的字段。
+func pointer(name string) string {
+	if strings.HasPrefix(name, "/") {
+		return name
+	}
+	return "/data/attributes" + nested.Pointer(name)
+}
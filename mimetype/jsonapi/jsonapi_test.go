@@ -0,0 +1,270 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/internal/errs"
+	"github.com/issue9/web/openapi"
+	"github.com/issue9/web/server"
+	"github.com/issue9/web/server/servertest"
+)
+
+var (
+	_ web.MarshalFunc   = Marshal
+	_ web.UnmarshalFunc = Unmarshal
+	_ web.EncodeFunc    = Encode
+	_ openapi.Envelope  = Envelope
+)
+
+type (
+	article struct {
+		ID       int    `json:"id"`
+		Title    string `json:"title"`
+		AuthorID string `json:"-"`
+	}
+
+	tag struct {
+		Name string `json:"name"`
+	}
+)
+
+func (a *article) JSONAPIType() string { return "articles" }
+
+func (a *article) JSONAPIID() string { return strconv.Itoa(a.ID) }
+
+func (a *article) JSONAPILinks(*web.Context) Links {
+	return Links{"self": {Href: "/articles/" + a.JSONAPIID()}}
+}
+
+func (a *article) JSONAPIRelationships(*web.Context) map[string]*Relationship {
+	if a.AuthorID == "" {
+		return map[string]*Relationship{"author": {}}
+	}
+	return map[string]*Relationship{
+		"author": {
+			Links: Links{"related": {Href: "/people/" + a.AuthorID, Title: "author"}},
+			Data:  &Identifier{Type: "people", ID: a.AuthorID},
+		},
+	}
+}
+
+func (a *article) SetJSONAPIID(id string) (err error) {
+	a.ID, err = strconv.Atoi(id)
+	return err
+}
+
+func (a *article) SetJSONAPIRelationships(rels map[string]*Relationship) error {
+	if author, found := rels["author"]; found && author.Data != nil {
+		a.AuthorID = author.Data.(*Identifier).ID
+	}
+	return nil
+}
+
+func (t tag) JSONAPIType() string { return "tags" }
+
+func (t tag) JSONAPIID() string { return t.Name }
+
+func TestMarshal(t *testing.T) {
+	a := assert.New(t, false)
+
+	data, err := Marshal(nil, &article{ID: 1, Title: "t1", AuthorID: "9"})
+	a.NotError(err).
+		Equal(string(data), `{"data":{"type":"articles","id":"1","attributes":{"title":"t1"},"relationships":{"author":{"links":{"related":{"href":"/people/9","title":"author"}},"data":{"type":"people","id":"9"}}},"links":{"self":"/articles/1"}}}`)
+
+	data, err = Marshal(nil, &article{ID: 2})
+	a.NotError(err).
+		Equal(string(data), `{"data":{"type":"articles","id":"2","attributes":{"title":""},"relationships":{"author":{"data":null}},"links":{"self":"/articles/2"}}}`)
+
+	// 值类型的切片
+	data, err = Marshal(nil, []tag{{Name: "go"}, {Name: "web"}})
+	a.NotError(err).
+		Equal(string(data), `{"data":[{"type":"tags","id":"go","attributes":{"name":"go"}},{"type":"tags","id":"web","attributes":{"name":"web"}}]}`)
+
+	data, err = Marshal(nil, [1]article{{ID: 3}})
+	a.NotError(err).
+		Equal(string(data), `{"data":[{"type":"articles","id":"3","attributes":{"title":""},"relationships":{"author":{"data":null}},"links":{"self":"/articles/3"}}]}`)
+
+	data, err = Marshal(nil, []*article{})
+	a.NotError(err).Equal(string(data), `{"data":[]}`)
+
+	data, err = Marshal(nil, nil)
+	a.NotError(err).Equal(string(data), `{"data":null}`)
+
+	data, err = Marshal(nil, (*article)(nil))
+	a.NotError(err).Equal(string(data), `{"data":null}`)
+
+	data, err = Marshal(nil, map[string]int{"count": 5})
+	a.NotError(err).Equal(string(data), `{"meta":{"count":5}}`)
+
+	data, err = Marshal(nil, &Document{
+		Data:     []*article{{ID: 1, AuthorID: "9"}},
+		Included: []Resource{tag{Name: "go"}},
+		Links:    Links{"next": {Href: "/articles?page=2"}},
+		Meta:     map[string]int{"total": 10},
+	})
+	a.NotError(err).
+		Equal(string(data), `{"data":[{"type":"articles","id":"1","attributes":{"title":""},"relationships":{"author":{"links":{"related":{"href":"/people/9","title":"author"}},"data":{"type":"people","id":"9"}}},"links":{"self":"/articles/1"}}],"meta":{"total":10},"links":{"next":"/articles?page=2"},"included":[{"type":"tags","id":"go","attributes":{"name":"go"}}]}`)
+
+	data, err = Marshal(nil, &Document{Data: 5})
+	a.Error(err).Nil(data)
+
+	p := &web.Problem{Type: "422", Title: "title", Status: 422, Instance: "/articles/1", Params: []web.ProblemParam{
+		{Name: "title", Reason: "r1"},
+		{Name: "/data/type", Reason: "r2"},
+	}}
+	data, err = Marshal(nil, p)
+	a.NotError(err).
+		Equal(string(data), `{"errors":[{"id":"/articles/1","status":"422","code":"422","title":"title","detail":"r1","source":{"pointer":"/data/attributes/title"}},{"id":"/articles/1","status":"422","code":"422","title":"title","detail":"r2","source":{"pointer":"/data/type"}}]}`)
+
+	p = &web.Problem{Type: "404", Title: "not found", Detail: "detail", Status: 404}
+	data, err = Marshal(nil, p)
+	a.NotError(err).
+		Equal(string(data), `{"errors":[{"status":"404","code":"404","title":"not found","detail":"detail"}]}`)
+
+	buf := &bytes.Buffer{}
+	a.NotError(Encode(nil, buf, nil)).Equal(buf.String(), `{"data":null}`)
+}
+
+func TestUnmarshal(t *testing.T) {
+	a := assert.New(t, false)
+
+	art := &article{}
+	a.NotError(Unmarshal(strings.NewReader(`{"data":{"type":"articles","id":"5","attributes":{"title":"t5"},"relationships":{"author":{"data":{"type":"people","id":"9"}}}}}`), art)).
+		Equal(art, &article{ID: 5, Title: "t5", AuthorID: "9"})
+
+	// 类型不匹配
+	err := Unmarshal(strings.NewReader(`{"data":{"type":"tags","attributes":{"title":"t5"}}}`), &article{})
+	var herr *errs.HTTP
+	a.True(errors.As(err, &herr)).
+		Equal(herr.Status, http.StatusConflict)
+
+	// 缺少 data
+	a.Error(Unmarshal(strings.NewReader(`{"meta":{}}`), &article{}))
+
+	// 非资源对象
+	m := map[string]int{}
+	a.NotError(Unmarshal(strings.NewReader(`{"data":{"type":"any","attributes":{"count":1}}}`), &m)).
+		Equal(m, map[string]int{"count": 1})
+}
+
+func TestRelationship(t *testing.T) {
+	a := assert.New(t, false)
+
+	r := &Relationship{}
+	a.NotError(json.Unmarshal([]byte(`{"data":[{"type":"tags","id":"1"}],"links":{"self":"/1"}}`), r)).
+		Equal(r.Data, []*Identifier{{Type: "tags", ID: "1"}}).
+		Equal(r.Links, Links{"self": {Href: "/1"}})
+
+	a.NotError(json.Unmarshal([]byte(`{"data":null}`), r)).
+		Nil(r.Data).
+		Nil(r.Links)
+
+	data, err := json.Marshal(&Relationship{Meta: map[string]int{"count": 1}})
+	a.NotError(err).Equal(string(data), `{"meta":{"count":1}}`)
+}
+
+func TestEnvelope(t *testing.T) {
+	a := assert.New(t, false)
+
+	s := &openapi.Schema{Type: openapi.TypeObject}
+	e := Envelope(s, false)
+	a.Equal(e.Required, []string{"data"}).
+		Equal(e.Properties["data"].Properties["attributes"], s)
+
+	s = &openapi.Schema{Type: openapi.TypeArray, Items: &openapi.Schema{Ref: &openapi.Ref{Ref: "article"}}}
+	e = Envelope(s, false)
+	a.Equal(e.Properties["data"].Type, openapi.TypeArray).
+		Equal(e.Properties["data"].Items.Properties["attributes"], s.Items)
+
+	s = &openapi.Schema{Type: openapi.TypeInteger}
+	e = Envelope(s, false)
+	a.Equal(e.Required, []string{"meta"}).
+		Equal(e.Properties["meta"], s)
+
+	e = Envelope(s, true)
+	a.Equal(e.Required, []string{"errors"})
+
+	a.Nil(Envelope(nil, false))
+}
+
+func TestServer(t *testing.T) {
+	a := assert.New(t, false)
+
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Codec:      web.NewCodec().AddMimetype(Mimetype, Marshal, Unmarshal, ProblemMimetype, true, true, Encode),
+	})
+	a.NotError(err).NotNil(s)
+
+	r := s.Routers().New("default", nil)
+	r.Post("/articles", func(ctx *web.Context) web.Responser {
+		art := &article{}
+		if resp := ctx.Read(false, art, web.ProblemUnprocessableEntity); resp != nil {
+			return resp
+		}
+		return web.Created(art, "")
+	})
+	r.Get("/articles/empty", func(ctx *web.Context) web.Responser {
+		return web.OK((*article)(nil))
+	})
+	r.Get("/articles/document", func(ctx *web.Context) web.Responser {
+		return web.OK(&Document{})
+	})
+	r.Get("/articles/nil", func(ctx *web.Context) web.Responser {
+		return web.OK(nil)
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	servertest.Post(a, "http://localhost:8080/articles", []byte(`{"data":{"type":"articles","attributes":{"title":"t1"}}}`)).
+		Header(header.ContentType, Mimetype).
+		Header(header.Accept, Mimetype).
+		Do(nil).
+		Status(http.StatusCreated).
+		Header(header.ContentType, Mimetype+"; charset=utf-8").
+		StringBody(`{"data":{"type":"articles","id":"0","attributes":{"title":"t1"},"relationships":{"author":{"data":null}},"links":{"self":"/articles/0"}}}`)
+
+	servertest.Post(a, "http://localhost:8080/articles", []byte(`{"data":{"type":"tags","attributes":{"title":"t1"}}}`)).
+		Header(header.ContentType, Mimetype).
+		Header(header.Accept, Mimetype).
+		Do(nil).
+		Status(http.StatusConflict).
+		Header(header.ContentType, Mimetype+"; charset=utf-8").
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			a.True(bytes.HasPrefix(body, []byte(`{"errors":[{`)))
+		})
+
+	servertest.Get(a, "http://localhost:8080/articles/empty").
+		Header(header.Accept, Mimetype).
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`{"data":null}`)
+
+	servertest.Get(a, "http://localhost:8080/articles/document").
+		Header(header.Accept, Mimetype).
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`{"data":null}`)
+
+	// nil 不经过编码方法
+	servertest.Get(a, "http://localhost:8080/articles/nil").
+		Header(header.Accept, Mimetype).
+		Do(nil).
+		Status(http.StatusOK).
+		BodyEmpty()
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package jsonapi
+
+import (
+	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+)
+
+// Envelope 将 s 包装为 JSON:API 文档的 [openapi.Schema]
+//
+// 可作为 [openapi.WithEnvelope] 的参数：
+//
+//	openapi.WithEnvelope(jsonapi.Mimetype, jsonapi.Envelope)
+//
+// 对象和元素为对象的数组会被当作资源对象放在 data 字段中，其它类型则放在 meta 字段中。
+func Envelope(s *openapi.Schema, problem bool) *openapi.Schema {
+	if problem {
+		return &openapi.Schema{
+			Type:        openapi.TypeObject,
+			Description: web.Phrase("problem response schema desc"),
+			Required:    []string{"errors"},
+			Properties: map[string]*openapi.Schema{
+				"errors": {Type: openapi.TypeArray, Items: errorSchema()},
+			},
+		}
+	}
+
+	if s == nil {
+		return nil
+	}
+
+	doc := &openapi.Schema{
+		Type: openapi.TypeObject,
+		Properties: map[string]*openapi.Schema{
+			"links": linksSchema(),
+		},
+	}
+
+	switch {
+	case s.Type == "" || s.Type == openapi.TypeObject: // 带 Ref 的对象 Type 为空
+		doc.Required = []string{"data"}
+		doc.Properties["data"] = resourceSchema(s)
+	case s.Type == openapi.TypeArray && s.Items != nil && (s.Items.Type == "" || s.Items.Type == openapi.TypeObject):
+		doc.Required = []string{"data"}
+		doc.Properties["data"] = &openapi.Schema{Type: openapi.TypeArray, Items: resourceSchema(s.Items)}
+	default:
+		doc.Required = []string{"meta"}
+		doc.Properties["meta"] = s
+	}
+
+	return doc
+}
+
+func resourceSchema(attrs *openapi.Schema) *openapi.Schema {
+	return &openapi.Schema{
+		Type:     openapi.TypeObject,
+		Required: []string{"type"},
+		Properties: map[string]*openapi.Schema{
+			"type":       {Type: openapi.TypeString},
+			"id":         {Type: openapi.TypeString},
+			"attributes": attrs,
+			"relationships": {
+				Type:        openapi.TypeObject,
+				Description: web.Phrase("jsonapi relationships"),
+				AdditionalProperties: &openapi.Schema{
+					Type: openapi.TypeObject,
+					Properties: map[string]*openapi.Schema{
+						"links": linksSchema(),
+						"data": {
+							OneOf: []*openapi.Schema{
+								identifierSchema(),
+								{Type: openapi.TypeArray, Items: identifierSchema()},
+							},
+						},
+						"meta": {Type: openapi.TypeObject},
+					},
+				},
+			},
+			"links": linksSchema(),
+		},
+	}
+}
+
+func identifierSchema() *openapi.Schema {
+	return &openapi.Schema{
+		Type:     openapi.TypeObject,
+		Required: []string{"type", "id"},
+		Properties: map[string]*openapi.Schema{
+			"type": {Type: openapi.TypeString},
+			"id":   {Type: openapi.TypeString},
+		},
+	}
+}
+
+func linksSchema() *openapi.Schema {
+	return &openapi.Schema{
+		Type:        openapi.TypeObject,
+		Description: web.Phrase("jsonapi links"),
+		AdditionalProperties: &openapi.Schema{
+			OneOf: []*openapi.Schema{
+				{Type: openapi.TypeString},
+				{
+					Type:     openapi.TypeObject,
+					Required: []string{"href"},
+					Properties: map[string]*openapi.Schema{
+						"href":        {Type: openapi.TypeString},
+						"rel":         {Type: openapi.TypeString},
+						"describedby": {Type: openapi.TypeString},
+						"title":       {Type: openapi.TypeString},
+						"type":        {Type: openapi.TypeString},
+						"hreflang":    {Type: openapi.TypeString},
+						"meta":        {Type: openapi.TypeObject},
+					},
+				},
+			},
+		},
+	}
+}
+
+func errorSchema() *openapi.Schema {
+	return &openapi.Schema{
+		Type: openapi.TypeObject,
+		Properties: map[string]*openapi.Schema{
+			"id":     {Type: openapi.TypeString},
+			"status": {Type: openapi.TypeString},
+			"code":   {Type: openapi.TypeString},
+			"title":  {Type: openapi.TypeString},
+			"detail": {Type: openapi.TypeString},
+			"source": {
+				Type: openapi.TypeObject,
+				Properties: map[string]*openapi.Schema{
+					"pointer": {Type: openapi.TypeString},
+				},
+			},
+			"meta": {},
+		},
+	}
+}
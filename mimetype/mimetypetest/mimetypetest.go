@@ -26,20 +26,37 @@ var inst = &web.Problem{
 }
 
 func Test(a *assert.Assertion, m web.MarshalFunc, u web.UnmarshalFunc) {
-	s, err := server.NewHTTP("test", "1.0", &server.Options{
-		HTTPServer: &http.Server{Addr: ":8080"},
-		Language:   language.English,
-	})
-	a.NotError(err).NotNil(s)
-
-	s.Routers().New("main", nil).Get("/path", func(ctx *web.Context) web.Responser {
+	test(a, func(ctx *web.Context) {
 		data, err := m(ctx, inst)
 		a.NotError(err).NotNil(data)
 
 		inst2 := &web.Problem{}
 		a.NotError(u(bytes.NewBuffer(data), inst2))
 		a.Equal(inst, inst2)
+	})
+}
+
+// TestEncode 测试流式的编码方法 e 与 u 是否匹配
+func TestEncode(a *assert.Assertion, e web.EncodeFunc, u web.UnmarshalFunc) {
+	test(a, func(ctx *web.Context) {
+		buf := &bytes.Buffer{}
+		a.NotError(e(ctx, buf, inst)).NotZero(buf.Len())
+
+		inst2 := &web.Problem{}
+		a.NotError(u(buf, inst2))
+		a.Equal(inst, inst2)
+	})
+}
 
+func test(a *assert.Assertion, f func(*web.Context)) {
+	s, err := server.NewHTTP("test", "1.0", &server.Options{
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Language:   language.English,
+	})
+	a.NotError(err).NotNil(s)
+
+	s.Routers().New("main", nil).Get("/path", func(ctx *web.Context) web.Responser {
+		f(ctx)
 		return web.OK(nil)
 	})
 
@@ -0,0 +1,679 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package msgpack
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// 最大的嵌套层次，防止恶意的内容导致栈溢出。
+const maxDepth = 10000
+
+type numberKind int8
+
+const (
+	intNumber numberKind = iota
+	uintNumber
+	floatNumber
+)
+
+type number struct {
+	kind numberKind
+	i    int64
+	u    uint64
+	f    float64
+}
+
+type decoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("参数 v 必须是非空的指针")
+	}
+
+	d := &decoder{data: data}
+	if err := d.decodeValue(rv.Elem()); err != nil {
+		return err
+	}
+	if d.pos < len(d.data) {
+		return errors.New("存在多余的内容")
+	}
+	return nil
+}
+
+func (d *decoder) decodeValue(v reflect.Value) error {
+	if d.depth++; d.depth > maxDepth {
+		return errors.New("超出了最大的嵌套层次")
+	}
+	defer func() { d.depth-- }()
+
+	code, err := d.peek()
+	if err != nil {
+		return err
+	}
+
+	if code == formatNil {
+		d.pos++
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			v.SetZero()
+		}
+		return nil
+	}
+
+	if v.Kind() != reflect.Pointer && v.CanAddr() {
+		switch pv := v.Addr().Interface().(type) {
+		case Unmarshaler:
+			start := d.pos
+			if err := d.skip(); err != nil {
+				return err
+			}
+			return pv.UnmarshalMsgpack(d.data[start:d.pos])
+		case *time.Time:
+			if isExt(code) {
+				t, err := d.readTime()
+				if err != nil {
+					return err
+				}
+				*pv = t
+				return nil
+			}
+			// 字符串由 encoding.TextUnmarshaler 处理
+		}
+
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok && isString(code) {
+			s, err := d.readString()
+			if err != nil {
+				return err
+			}
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeValue(v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return fmt.Errorf("无法解码至非空接口 %s", v.Type())
+		}
+		val, err := d.decodeAny()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(val))
+	case reflect.Bool:
+		switch code {
+		case formatTrue:
+			v.SetBool(true)
+		case formatFalse:
+			v.SetBool(false)
+		default:
+			return d.typeError(code, v.Type())
+		}
+		d.pos++
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := d.readNumber()
+		if err != nil {
+			return err
+		}
+
+		var i int64
+		switch n.kind {
+		case intNumber:
+			i = n.i
+		case uintNumber:
+			if n.u > math.MaxInt64 {
+				return overflowError(n.u, v.Type())
+			}
+			i = int64(n.u)
+		default:
+			return d.typeError(code, v.Type())
+		}
+		if v.OverflowInt(i) {
+			return overflowError(i, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := d.readNumber()
+		if err != nil {
+			return err
+		}
+
+		var u uint64
+		switch n.kind {
+		case intNumber:
+			if n.i < 0 {
+				return overflowError(n.i, v.Type())
+			}
+			u = uint64(n.i)
+		case uintNumber:
+			u = n.u
+		default:
+			return d.typeError(code, v.Type())
+		}
+		if v.OverflowUint(u) {
+			return overflowError(u, v.Type())
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		n, err := d.readNumber()
+		if err != nil {
+			return err
+		}
+
+		switch n.kind {
+		case intNumber:
+			v.SetFloat(float64(n.i))
+		case uintNumber:
+			v.SetFloat(float64(n.u))
+		default:
+			v.SetFloat(n.f)
+		}
+	case reflect.String:
+		s, err := d.readString()
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 && (isString(code) || isBinary(code)) {
+			data, err := d.readBytes()
+			if err != nil {
+				return err
+			}
+			v.SetBytes(data)
+			return nil
+		}
+
+		l, err := d.readArrayLen()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.MakeSlice(v.Type(), l, l))
+		for i := range l {
+			if err := d.decodeValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 && (isString(code) || isBinary(code)) {
+			data, err := d.readBytes()
+			if err != nil {
+				return err
+			}
+			v.SetZero()
+			reflect.Copy(v, reflect.ValueOf(data))
+			return nil
+		}
+
+		l, err := d.readArrayLen()
+		if err != nil {
+			return err
+		}
+		v.SetZero()
+		for i := range l {
+			if i >= v.Len() { // 超出数组长度的部分被忽略
+				err = d.skip()
+			} else {
+				err = d.decodeValue(v.Index(i))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		l, err := d.readMapLen()
+		if err != nil {
+			return err
+		}
+
+		t := v.Type()
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(t, l))
+		}
+		for range l {
+			key := reflect.New(t.Key()).Elem()
+			if err := d.decodeValue(key); err != nil {
+				return err
+			}
+			val := reflect.New(t.Elem()).Elem()
+			if err := d.decodeValue(val); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, val)
+		}
+	case reflect.Struct:
+		l, err := d.readMapLen()
+		if err != nil {
+			return err
+		}
+
+		fields := getFields(v.Type())
+		for range l {
+			name, err := d.readString()
+			if err != nil {
+				return err
+			}
+
+			f := findField(fields, name)
+			if f == nil {
+				if err := d.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			fv := v
+			for _, i := range f.index { // 嵌入的指针需要分配内存
+				if fv.Kind() == reflect.Pointer {
+					if fv.IsNil() {
+						fv.Set(reflect.New(fv.Type().Elem()))
+					}
+					fv = fv.Elem()
+				}
+				fv = fv.Field(i)
+			}
+			if err := d.decodeValue(fv); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的类型 %s", v.Type())
+	}
+
+	return nil
+}
+
+// 解码至 any 类型
+func (d *decoder) decodeAny() (any, error) {
+	code, err := d.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case code == formatNil:
+		d.pos++
+		return nil, nil
+	case code == formatTrue || code == formatFalse:
+		d.pos++
+		return code == formatTrue, nil
+	case isString(code):
+		return d.readString()
+	case isBinary(code):
+		return d.readBytes()
+	case isExt(code):
+		return d.readTime()
+	case isArray(code):
+		l, err := d.readArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		items := make([]any, 0, l)
+		for range l {
+			item, err := d.decodeAnyElem()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case isMap(code):
+		l, err := d.readMapLen()
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]any, l)
+		for range l {
+			key, err := d.decodeAnyElem()
+			if err != nil {
+				return nil, err
+			}
+			val, err := d.decodeAnyElem()
+			if err != nil {
+				return nil, err
+			}
+
+			if s, ok := key.(string); ok {
+				m[s] = val
+			} else {
+				m[fmt.Sprint(key)] = val
+			}
+		}
+		return m, nil
+	default:
+		n, err := d.readNumber()
+		if err != nil {
+			return nil, err
+		}
+		switch n.kind {
+		case intNumber:
+			return n.i, nil
+		case uintNumber:
+			if n.u <= math.MaxInt64 {
+				return int64(n.u), nil
+			}
+			return n.u, nil
+		default:
+			return n.f, nil
+		}
+	}
+}
+
+func (d *decoder) decodeAnyElem() (any, error) {
+	if d.depth++; d.depth > maxDepth {
+		return nil, errors.New("超出了最大的嵌套层次")
+	}
+	defer func() { d.depth-- }()
+	return d.decodeAny()
+}
+
+// 跳过一个完整的对象
+func (d *decoder) skip() error {
+	if d.depth++; d.depth > maxDepth {
+		return errors.New("超出了最大的嵌套层次")
+	}
+	defer func() { d.depth-- }()
+
+	code, err := d.peek()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case isString(code) || isBinary(code):
+		_, err = d.readBytes()
+		return err
+	case isExt(code):
+		_, _, err = d.readExt()
+		return err
+	case isArray(code):
+		l, err := d.readArrayLen()
+		if err != nil {
+			return err
+		}
+		for range l {
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case isMap(code):
+		l, err := d.readMapLen()
+		if err != nil {
+			return err
+		}
+		for range l * 2 {
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case code == formatNil || code == formatTrue || code == formatFalse:
+		d.pos++
+		return nil
+	default:
+		_, err = d.readNumber()
+		return err
+	}
+}
+
+func (d *decoder) peek() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return d.data[d.pos], nil
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return data, nil
+}
+
+// 读取 size 个字节表示的长度
+func (d *decoder) readUintN(size int) (uint64, error) {
+	data, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return uint64(data[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(data)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(data)), nil
+	default:
+		return binary.BigEndian.Uint64(data), nil
+	}
+}
+
+func (d *decoder) readNumber() (number, error) {
+	code, err := d.peek()
+	if err != nil {
+		return number{}, err
+	}
+	d.pos++
+
+	switch {
+	case code <= posFixintMax:
+		return number{kind: intNumber, i: int64(code)}, nil
+	case code >= negFixintMin:
+		return number{kind: intNumber, i: int64(int8(code))}, nil
+	}
+
+	switch code {
+	case uint8Fmt, uint16Fmt, uint32Fmt, uint64Fmt:
+		u, err := d.readUintN(1 << (code - uint8Fmt))
+		return number{kind: uintNumber, u: u}, err
+	case int8Fmt:
+		u, err := d.readUintN(1)
+		return number{kind: intNumber, i: int64(int8(u))}, err
+	case int16Fmt:
+		u, err := d.readUintN(2)
+		return number{kind: intNumber, i: int64(int16(u))}, err
+	case int32Fmt:
+		u, err := d.readUintN(4)
+		return number{kind: intNumber, i: int64(int32(u))}, err
+	case int64Fmt:
+		u, err := d.readUintN(8)
+		return number{kind: intNumber, i: int64(u)}, err
+	case float32Fmt:
+		u, err := d.readUintN(4)
+		return number{kind: floatNumber, f: float64(math.Float32frombits(uint32(u)))}, err
+	case float64Fmt:
+		u, err := d.readUintN(8)
+		return number{kind: floatNumber, f: math.Float64frombits(u)}, err
+	default:
+		d.pos--
+		return number{}, d.typeError(code, reflect.TypeFor[float64]())
+	}
+}
+
+// 读取字符串或是二进制内容
+func (d *decoder) readBytes() ([]byte, error) {
+	code, err := d.peek()
+	if err != nil {
+		return nil, err
+	}
+	d.pos++
+
+	var l uint64
+	switch {
+	case code >= fixstr && code <= fixstr|31:
+		l = uint64(code &^ fixstr)
+	case code == str8 || code == bin8:
+		l, err = d.readUintN(1)
+	case code == str16 || code == bin16:
+		l, err = d.readUintN(2)
+	case code == str32 || code == bin32:
+		l, err = d.readUintN(4)
+	default:
+		d.pos--
+		return nil, d.typeError(code, reflect.TypeFor[string]())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := d.read(int(l))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (d *decoder) readString() (string, error) {
+	data, err := d.readBytes()
+	return string(data), err
+}
+
+func (d *decoder) readArrayLen() (int, error) {
+	return d.readContainerLen(fixarray, array16, array32, 1)
+}
+
+func (d *decoder) readMapLen() (int, error) {
+	return d.readContainerLen(fixmap, map16, map32, 2)
+}
+
+// 读取数组或是 map 的元素数量
+//
+// min 为每个元素最少占用的字节数，用于防止恶意的长度值导致分配大量的内存。
+func (d *decoder) readContainerLen(fix, f16, f32 byte, min int) (int, error) {
+	code, err := d.peek()
+	if err != nil {
+		return 0, err
+	}
+	d.pos++
+
+	var l uint64
+	switch {
+	case code >= fix && code <= fix|15:
+		l = uint64(code &^ fix)
+	case code == f16:
+		l, err = d.readUintN(2)
+	case code == f32:
+		l, err = d.readUintN(4)
+	default:
+		d.pos--
+		if fix == fixmap {
+			return 0, d.typeError(code, reflect.TypeFor[map[string]any]())
+		}
+		return 0, d.typeError(code, reflect.TypeFor[[]any]())
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if l > uint64((len(d.data)-d.pos)/min) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int(l), nil
+}
+
+func (d *decoder) readExt() (typ byte, data []byte, err error) {
+	code, err := d.peek()
+	if err != nil {
+		return 0, nil, err
+	}
+	d.pos++
+
+	var l uint64
+	switch code {
+	case fixext1, fixext2, fixext4, fixext8, fixext16:
+		l = 1 << (code - fixext1)
+	case ext8:
+		l, err = d.readUintN(1)
+	case ext16:
+		l, err = d.readUintN(2)
+	case ext32:
+		l, err = d.readUintN(4)
+	default:
+		d.pos--
+		return 0, nil, d.typeError(code, timeType)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	t, err := d.readUintN(1)
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err = d.read(int(l))
+	return byte(t), data, err
+}
+
+// 读取 timestamp 扩展类型，返回的时间为 UTC 时区。
+func (d *decoder) readTime() (time.Time, error) {
+	typ, data, err := d.readExt()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if typ != timestampExt {
+		return time.Time{}, fmt.Errorf("不支持的扩展类型 %d", int8(typ))
+	}
+
+	switch len(data) {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0).UTC(), nil
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)).UTC(), nil
+	case 12:
+		nsec := binary.BigEndian.Uint32(data[:4])
+		sec := binary.BigEndian.Uint64(data[4:])
+		return time.Unix(int64(sec), int64(nsec)).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("无效的 timestamp 长度 %d", len(data))
+	}
+}
+
+func (d *decoder) typeError(code byte, t reflect.Type) error {
+	return fmt.Errorf("无法将 0x%x 类型的数据解码至 %s", code, t)
+}
+
+func overflowError(v any, t reflect.Type) error {
+	return fmt.Errorf("%v 超出了 %s 的取值范围", v, t)
+}
+
+func isString(code byte) bool {
+	return (code >= fixstr && code <= fixstr|31) || code == str8 || code == str16 || code == str32
+}
+
+func isBinary(code byte) bool { return code == bin8 || code == bin16 || code == bin32 }
+
+func isArray(code byte) bool {
+	return (code >= fixarray && code <= fixarray|15) || code == array16 || code == array32
+}
+
+func isMap(code byte) bool {
+	return (code >= fixmap && code <= fixmap|15) || code == map16 || code == map32
+}
+
+func isExt(code byte) bool {
+	return (code >= fixext1 && code <= fixext16) || code == ext8 || code == ext16 || code == ext32
+}
@@ -0,0 +1,321 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package msgpack
+
+import (
+	"cmp"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// 格式的类型标记
+const (
+	posFixintMax = 0x7f
+	fixmap       = 0x80
+	fixarray     = 0x90
+	fixstr       = 0xa0
+	formatNil    = 0xc0
+	formatFalse  = 0xc2
+	formatTrue   = 0xc3
+	bin8         = 0xc4
+	bin16        = 0xc5
+	bin32        = 0xc6
+	ext8         = 0xc7
+	ext16        = 0xc8
+	ext32        = 0xc9
+	float32Fmt   = 0xca
+	float64Fmt   = 0xcb
+	uint8Fmt     = 0xcc
+	uint16Fmt    = 0xcd
+	uint32Fmt    = 0xce
+	uint64Fmt    = 0xcf
+	int8Fmt      = 0xd0
+	int16Fmt     = 0xd1
+	int32Fmt     = 0xd2
+	int64Fmt     = 0xd3
+	fixext1      = 0xd4
+	fixext2      = 0xd5
+	fixext4      = 0xd6
+	fixext8      = 0xd7
+	fixext16     = 0xd8
+	str8         = 0xd9
+	str16        = 0xda
+	str32        = 0xdb
+	array16      = 0xdc
+	array32      = 0xdd
+	map16        = 0xde
+	map32        = 0xdf
+	negFixintMin = 0xe0
+
+	timestampExt = 0xff // 即 int8(-1)
+)
+
+var (
+	timeType        = reflect.TypeFor[time.Time]()
+	marshalerType   = reflect.TypeFor[Marshaler]()
+	textMarshalType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) encode(v any) error {
+	if v == nil {
+		e.buf = append(e.buf, formatNil)
+		return nil
+	}
+	return e.encodeValue(reflect.ValueOf(v))
+}
+
+func (e *encoder) encodeValue(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf = append(e.buf, formatNil)
+		return nil
+	}
+
+	if k := v.Kind(); (k == reflect.Pointer || k == reflect.Interface) && v.IsNil() {
+		e.buf = append(e.buf, formatNil)
+		return nil
+	}
+
+	t := v.Type()
+	switch {
+	case t.Implements(marshalerType):
+		data, err := v.Interface().(Marshaler).MarshalMsgpack()
+		if err != nil {
+			return err
+		}
+		e.buf = append(e.buf, data...)
+		return nil
+	case t == timeType:
+		e.writeTime(v.Interface().(time.Time))
+		return nil
+	case t.Implements(textMarshalType):
+		data, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		e.writeString(string(data))
+		return nil
+	case v.CanAddr() && t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(marshalerType):
+		return e.encodeValue(v.Addr())
+	case v.CanAddr() && t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(textMarshalType):
+		return e.encodeValue(v.Addr())
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return e.encodeValue(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, formatTrue)
+		} else {
+			e.buf = append(e.buf, formatFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.writeUint(v.Uint())
+	case reflect.Float32:
+		e.buf = append(e.buf, float32Fmt)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.buf = append(e.buf, float64Fmt)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v.Float()))
+	case reflect.String:
+		e.writeString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, formatNil)
+			return nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			e.writeBinary(v.Bytes())
+			return nil
+		}
+		return e.encodeArray(v)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			e.writeBinary(data)
+			return nil
+		}
+		return e.encodeArray(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf = append(e.buf, formatNil)
+			return nil
+		}
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return fmt.Errorf("不支持的类型 %s", t)
+	}
+	return nil
+}
+
+func (e *encoder) encodeArray(v reflect.Value) error {
+	e.writeLen(v.Len(), fixarray, 15, array16, array32)
+	for i := range v.Len() {
+		if err := e.encodeValue(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) encodeMap(v reflect.Value) error {
+	keys := v.MapKeys()
+	if v.Type().Key().Kind() == reflect.String { // 保证输出的内容是固定的
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return cmp.Compare(a.String(), b.String()) })
+	}
+
+	e.writeLen(len(keys), fixmap, 15, map16, map32)
+	for _, key := range keys {
+		if err := e.encodeValue(key); err != nil {
+			return err
+		}
+		if err := e.encodeValue(v.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	fields := getFields(v.Type())
+	values := make([]reflect.Value, 0, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		fv, err := v.FieldByIndexErr(f.index)
+		if err != nil || (f.omitempty && isEmpty(fv)) { // 嵌入的指针为空
+			continue
+		}
+		values = append(values, fv)
+		names = append(names, f.name)
+	}
+
+	e.writeLen(len(values), fixmap, 15, map16, map32)
+	for i, fv := range values {
+		e.writeString(names[i])
+		if err := e.encodeValue(fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) writeInt(i int64) {
+	switch {
+	case i >= 0:
+		e.writeUint(uint64(i))
+	case i >= -32:
+		e.buf = append(e.buf, byte(i))
+	case i >= math.MinInt8:
+		e.buf = append(e.buf, int8Fmt, byte(i))
+	case i >= math.MinInt16:
+		e.buf = append(e.buf, int16Fmt)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(i))
+	case i >= math.MinInt32:
+		e.buf = append(e.buf, int32Fmt)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(i))
+	default:
+		e.buf = append(e.buf, int64Fmt)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(i))
+	}
+}
+
+func (e *encoder) writeUint(u uint64) {
+	switch {
+	case u <= posFixintMax:
+		e.buf = append(e.buf, byte(u))
+	case u <= math.MaxUint8:
+		e.buf = append(e.buf, uint8Fmt, byte(u))
+	case u <= math.MaxUint16:
+		e.buf = append(e.buf, uint16Fmt)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(u))
+	case u <= math.MaxUint32:
+		e.buf = append(e.buf, uint32Fmt)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(u))
+	default:
+		e.buf = append(e.buf, uint64Fmt)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, u)
+	}
+}
+
+func (e *encoder) writeString(s string) {
+	if l := len(s); l <= math.MaxUint8 && l > 31 {
+		e.buf = append(e.buf, str8, byte(l))
+	} else {
+		e.writeLen(l, fixstr, 31, str16, str32)
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) writeBinary(data []byte) {
+	switch l := len(data); {
+	case l <= math.MaxUint8:
+		e.buf = append(e.buf, bin8, byte(l))
+	case l <= math.MaxUint16:
+		e.buf = append(e.buf, bin16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(l))
+	default:
+		e.buf = append(e.buf, bin32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(l))
+	}
+	e.buf = append(e.buf, data...)
+}
+
+// 写入长度信息
+//
+// l 小于等于 fixMax 时，采用 fix 格式，否则根据大小采用 f16 或是 f32。
+func (e *encoder) writeLen(l int, fix byte, fixMax int, f16, f32 byte) {
+	switch {
+	case l <= fixMax:
+		e.buf = append(e.buf, fix|byte(l))
+	case l <= math.MaxUint16:
+		e.buf = append(e.buf, f16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(l))
+	default:
+		e.buf = append(e.buf, f32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(l))
+	}
+}
+
+// 按 timestamp 扩展类型写入时间
+func (e *encoder) writeTime(t time.Time) {
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	switch {
+	case nsec == 0 && sec >= 0 && sec <= math.MaxUint32: // timestamp 32
+		e.buf = append(e.buf, fixext4, timestampExt)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(sec))
+	case sec >= 0 && sec>>34 == 0: // timestamp 64
+		e.buf = append(e.buf, fixext8, timestampExt)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, nsec<<34|uint64(sec))
+	default: // timestamp 96
+		e.buf = append(e.buf, ext8, 12, timestampExt)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(nsec))
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(sec))
+	}
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
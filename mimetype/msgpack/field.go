@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package msgpack
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Tag 在 struct tag 中的标签名称
+const Tag = "msgpack"
+
+type field struct {
+	name      string
+	index     []int
+	omitempty bool
+}
+
+var fieldsCache sync.Map // map[reflect.Type][]*field
+
+// 获取结构体中可编解码的字段
+func getFields(t reflect.Type) []*field {
+	if fields, found := fieldsCache.Load(t); found {
+		return fields.([]*field)
+	}
+
+	all := appendFields(nil, t, nil, nil)
+
+	// 同名的字段，仅保留嵌套层次最浅的字段，层次相同则保留第一个。
+	fields := make([]*field, 0, len(all))
+	for _, f := range all {
+		index := slices.IndexFunc(fields, func(f2 *field) bool { return f2.name == f.name })
+		switch {
+		case index < 0:
+			fields = append(fields, f)
+		case len(f.index) < len(fields[index].index):
+			fields[index] = f
+		}
+	}
+
+	fieldsCache.Store(t, fields)
+	return fields
+}
+
+// parents 用于防止循环嵌入的结构体导致死循环
+func appendFields(fields []*field, t reflect.Type, index []int, parents []reflect.Type) []*field {
+	parents = append(parents, t)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, omitempty := parseTag(f)
+		if name == "-" || !f.IsExported() { // 未导出的嵌入结构体也无法读写
+			continue
+		}
+
+		idx := append(slices.Clone(index), i)
+		if ft := indirect(f.Type); f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			if !slices.Contains(parents, ft) {
+				fields = appendFields(fields, ft, idx, parents)
+			}
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, &field{name: name, index: idx, omitempty: omitempty})
+	}
+	return fields
+}
+
+// 优先采用 msgpack 标签，不存在时采用 json 标签。
+func parseTag(f reflect.StructField) (name string, omitempty bool) {
+	tag, found := f.Tag.Lookup(Tag)
+	if !found {
+		tag = f.Tag.Get("json")
+	}
+	if tag == "-" {
+		return "-", false
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	return strings.TrimSpace(name), slices.Contains(strings.Split(opts, ","), "omitempty")
+}
+
+func findField(fields []*field, name string) *field {
+	if index := slices.IndexFunc(fields, func(f *field) bool { return f.name == name }); index >= 0 {
+		return fields[index]
+	}
+	if index := slices.IndexFunc(fields, func(f *field) bool { return strings.EqualFold(f.name, name) }); index >= 0 {
+		return fields[index]
+	}
+	return nil
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
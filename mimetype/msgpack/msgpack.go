@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package msgpack [MessagePack] 编码
+//
+// 结构体字段的名称由 msgpack 标签指定，如果不存在则采用 json 标签，
+// 格式与 json 相同，支持 omitempty 选项：
+//
+//	type User struct {
+//	    Name string `msgpack:"name"`
+//	    Age int `json:"age,omitempty"`
+//	    Password string `msgpack:"-"`
+//	}
+//
+// 除了基本类型之外，还有以下规则：
+//   - [time.Time] 采用 timestamp 扩展类型；
+//   - 实现了 [encoding.TextMarshaler] 和 [encoding.TextUnmarshaler] 的类型以字符串的形式编解码，
+//     比如由 web enum 生成的枚举类型；
+//   - 结构体以 map 的形式编码，键名为字段名称；
+//   - 嵌入的结构体会被展开，与 json 相同；
+//   - 解码至 any 类型时，map 解码为 map[string]any，数组解码为 []any，整数解码为 int64 或 uint64；
+//
+// [MessagePack]: https://github.com/msgpack/msgpack/blob/master/spec.md
+package msgpack
+
+import (
+	"io"
+
+	"github.com/issue9/web"
+)
+
+const (
+	Mimetype        = "application/msgpack"
+	ProblemMimetype = "application/problem+msgpack"
+)
+
+// Marshaler 自定义 MessagePack 编码需要实现的接口
+//
+// 返回的内容必须是一个完整的 MessagePack 对象。
+type Marshaler interface {
+	MarshalMsgpack() ([]byte, error)
+}
+
+// Unmarshaler 自定义 MessagePack 解码需要实现的接口
+//
+// 传入的内容为一个完整的 MessagePack 对象。
+type Unmarshaler interface {
+	UnmarshalMsgpack([]byte) error
+}
+
+func Marshal(_ *web.Context, v any) ([]byte, error) {
+	e := &encoder{}
+	if err := e.encode(v); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func Unmarshal(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return unmarshal(data, v)
+}
+
+func Encode(ctx *web.Context, w io.Writer, v any) error {
+	data, err := Marshal(ctx, v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
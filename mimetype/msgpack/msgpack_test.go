@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/web"
+)
+
+var (
+	_ web.MarshalFunc   = Marshal
+	_ web.UnmarshalFunc = Unmarshal
+	_ web.EncodeFunc    = Encode
+)
+
+type (
+	Sex int8
+
+	Base struct {
+		ID int64 `msgpack:"id"`
+	}
+
+	object struct {
+		*Base
+		Name     string         `msgpack:"name"`
+		Age      uint8          `json:"age,omitempty"`
+		Sex      Sex            `msgpack:"sex"`
+		Created  time.Time      `msgpack:"created"`
+		Tags     []string       `msgpack:"tags,omitempty"`
+		Data     []byte         `msgpack:"data"`
+		Attrs    map[string]any `msgpack:"attrs,omitempty"`
+		Ptr      *float64       `msgpack:"ptr"`
+		Ignore   string         `msgpack:"-"`
+		unexport bool
+	}
+
+	custom struct {
+		v string
+	}
+)
+
+func (s Sex) MarshalText() ([]byte, error) {
+	switch s {
+	case 0:
+		return []byte("male"), nil
+	case 1:
+		return []byte("female"), nil
+	default:
+		return nil, errors.New("invalid sex")
+	}
+}
+
+func (s *Sex) UnmarshalText(v []byte) error {
+	switch string(v) {
+	case "male":
+		*s = 0
+	case "female":
+		*s = 1
+	default:
+		return errors.New("invalid sex")
+	}
+	return nil
+}
+
+func (c *custom) MarshalMsgpack() ([]byte, error) { return Marshal(nil, "custom:"+c.v) }
+
+func (c *custom) UnmarshalMsgpack(data []byte) error {
+	var s string
+	if err := unmarshal(data, &s); err != nil {
+		return err
+	}
+	c.v = strings.TrimPrefix(s, "custom:")
+	return nil
+}
+
+func TestMarshal_spec(t *testing.T) {
+	a := assert.New(t, false)
+
+	data := []struct {
+		v   any
+		hex string
+	}{
+		{v: nil, hex: "c0"},
+		{v: true, hex: "c3"},
+		{v: false, hex: "c2"},
+		{v: 0, hex: "00"},
+		{v: 127, hex: "7f"},
+		{v: 128, hex: "cc80"},
+		{v: 256, hex: "cd0100"},
+		{v: 65536, hex: "ce00010000"},
+		{v: uint64(math.MaxUint64), hex: "cfffffffffffffffff"},
+		{v: -1, hex: "ff"},
+		{v: -32, hex: "e0"},
+		{v: -33, hex: "d0df"},
+		{v: -129, hex: "d1ff7f"},
+		{v: -32769, hex: "d2ffff7fff"},
+		{v: int64(math.MinInt64), hex: "d38000000000000000"},
+		{v: float32(1.5), hex: "ca3fc00000"},
+		{v: 1.5, hex: "cb3ff8000000000000"},
+		{v: "", hex: "a0"},
+		{v: "abc", hex: "a3616263"},
+		{v: strings.Repeat("a", 32), hex: "d920" + strings.Repeat("61", 32)},
+		{v: strings.Repeat("a", 256), hex: "da0100" + strings.Repeat("61", 256)},
+		{v: []byte{1, 2}, hex: "c4020102"},
+		{v: [2]byte{1, 2}, hex: "c4020102"},
+		{v: []int{1, 2}, hex: "920102"},
+		{v: make([]int, 16), hex: "dc0010" + strings.Repeat("00", 16)},
+		{v: []int(nil), hex: "c0"},
+		{v: map[string]int{"b": 2, "a": 1}, hex: "82a16101a16202"},
+		{v: map[string]int(nil), hex: "c0"},
+		{v: time.Unix(1, 0), hex: "d6ff00000001"},
+		{v: time.Unix(1, 1), hex: "d7ff0000000400000001"},
+		{v: time.Unix(-1, 0), hex: "c70cff00000000ffffffffffffffff"},
+		{v: Sex(1), hex: "a666656d616c65"},
+		{v: &custom{v: "x"}, hex: "a8637573746f6d3a78"},
+	}
+
+	for _, item := range data {
+		b, err := Marshal(nil, item.v)
+		a.NotError(err).Equal(hex.EncodeToString(b), item.hex, item.v)
+	}
+
+	_, err := Marshal(nil, make(chan int))
+	a.Error(err)
+	_, err = Marshal(nil, Sex(5))
+	a.Error(err)
+}
+
+func TestMarshal(t *testing.T) {
+	a := assert.New(t, false)
+
+	f := 1.5
+	obj := &object{
+		Base:    &Base{ID: 5},
+		Name:    "n1",
+		Sex:     1,
+		Created: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC),
+		Data:    []byte{1, 2, 3},
+		Attrs:   map[string]any{"a": 1, "b": []any{"x", true}, "c": map[string]any{"d": nil}},
+		Ptr:     &f,
+		Ignore:  "i",
+	}
+	data, err := Marshal(nil, obj)
+	a.NotError(err)
+
+	v := &object{}
+	a.NotError(Unmarshal(bytes.NewReader(data), v))
+	a.Equal(v, &object{
+		Base:    &Base{ID: 5},
+		Name:    "n1",
+		Sex:     1,
+		Created: obj.Created,
+		Data:    []byte{1, 2, 3},
+		Attrs:   map[string]any{"a": int64(1), "b": []any{"x", true}, "c": map[string]any{"d": nil}},
+		Ptr:     &f,
+	})
+
+	// 字段名称
+	m := map[string]any{}
+	a.NotError(Unmarshal(bytes.NewReader(data), &m))
+	a.Equal(m["id"], 5).
+		Equal(m["sex"], "female").
+		Equal(m["created"], obj.Created).
+		Equal(m["data"], []byte{1, 2, 3}).
+		NotContains(m, "age").
+		NotContains(m, "tags").
+		NotContains(m, "Ignore")
+
+	// 嵌入的指针为空
+	data, err = Marshal(nil, &object{Name: "n2"})
+	a.NotError(err)
+	v = &object{}
+	a.NotError(Unmarshal(bytes.NewReader(data), v))
+	a.Nil(v.Base).Equal(v.Name, "n2").Nil(v.Data).Nil(v.Ptr)
+
+	// Encode
+	buf := &bytes.Buffer{}
+	a.NotError(Encode(nil, buf, []*custom{{v: "1"}, nil}))
+	cs := []*custom{}
+	a.NotError(Unmarshal(buf, &cs))
+	a.Equal(cs, []*custom{{v: "1"}, nil})
+
+	// web.Problem
+	data, err = Marshal(nil, &web.Problem{Type: "t", Title: "title", Status: 400, Params: []web.ProblemParam{{Name: "n", Reason: "r"}}})
+	a.NotError(err)
+	m = map[string]any{}
+	a.NotError(Unmarshal(bytes.NewReader(data), &m))
+	a.Equal(m["type"], "t").
+		Equal(m["status"], 400).
+		Equal(m["params"], []any{map[string]any{"name": "n", "reason": "r"}}).
+		NotContains(m, "detail").
+		NotContains(m, "XMLName")
+}
+
+func TestUnmarshal(t *testing.T) {
+	a := assert.New(t, false)
+
+	decode := func(h string, v any) error {
+		data, err := hex.DecodeString(h)
+		a.NotError(err)
+		return Unmarshal(bytes.NewReader(data), v)
+	}
+
+	var i8 int8
+	a.NotError(decode("7f", &i8)).Equal(i8, 127)
+	a.Error(decode("cc80", &i8)) // 溢出
+
+	var u uint
+	a.NotError(decode("cd0100", &u)).Equal(u, 256)
+	a.Error(decode("ff", &u)) // 负数
+
+	var f32 float32
+	a.NotError(decode("05", &f32)).Equal(f32, 5)
+
+	var s string
+	a.NotError(decode("c403616263", &s)).Equal(s, "abc") // bin 也可以解码为字符串
+	a.Error(decode("01", &s))
+
+	var arr [2]int
+	a.NotError(decode("93010203", &arr)).Equal(arr, [2]int{1, 2})
+
+	var tm time.Time
+	a.NotError(decode("d6ff00000001", &tm)).Equal(tm, time.Unix(1, 0).UTC())
+	a.NotError(decode("b4"+hex.EncodeToString([]byte("2025-01-02T03:04:05Z")), &tm)).
+		Equal(tm, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	var sex Sex
+	a.NotError(decode("a46d616c65", &sex)).Equal(sex, 0)
+	a.Error(decode("a178", &sex))
+
+	// 大小写不敏感
+	obj := &object{}
+	a.NotError(decode("82a44e414d45a178a178c0", obj)).Equal(obj.Name, "x")
+
+	var any1 any
+	a.NotError(decode("cfffffffffffffffff", &any1)).Equal(any1, uint64(math.MaxUint64))
+
+	// 错误的内容
+	a.ErrorIs(decode("", &any1), io.ErrUnexpectedEOF)
+	a.ErrorIs(decode("a3", &s), io.ErrUnexpectedEOF)
+	a.ErrorIs(decode("ddffffffff", &any1), io.ErrUnexpectedEOF) // 超大的长度值
+	a.Error(decode("0101", &any1))                              // 多余的内容
+	a.Error(decode("c1", &any1))                                // 未定义的类型
+	a.Error(decode("d401ff", &any1))                            // 不支持的扩展
+	a.Error(decode(strings.Repeat("91", maxDepth+1)+"c0", &any1))
+	a.Error(decode("c0", any1))
+}
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package multipart
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/mimetype/form"
+)
+
+var (
+	fileType  = reflect.TypeFor[*File]()
+	filesType = reflect.TypeFor[[]*File]()
+)
+
+// 将 f 的内容写入 v
+//
+// 绑定到 v 的文件会从 f.Files 中移除，剩余的即为未绑定的文件。
+func (f *Form) bind(v any) error {
+	switch vv := v.(type) {
+	case *Form:
+		*vv = *f
+		f.Files = nil
+		return nil
+	case url.Values:
+		for k, v := range f.Values {
+			vv[k] = v
+		}
+		return nil
+	}
+
+	if len(f.Values) > 0 {
+		if err := form.Unmarshal(strings.NewReader(f.Values.Encode()), v); err != nil {
+			return err
+		}
+	}
+
+	if len(f.Files) > 0 {
+		rv := reflect.ValueOf(v)
+		for rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return web.NewLocaleError("can not bind multipart files to %s", rv.Type())
+			}
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Struct {
+			return web.NewLocaleError("can not bind multipart files to %s", rv.Type())
+		}
+		bindFiles(rv, f.Files)
+	}
+
+	return nil
+}
+
+// 将 files 中的文件写入 rv，已经绑定的文件会从 files 中移除。
+func bindFiles(rv reflect.Value, files map[string][]*File) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		field := rv.Field(i)
+
+		if sf.Anonymous {
+			if field.Kind() == reflect.Pointer {
+				if field.IsNil() {
+					if !field.CanSet() {
+						continue
+					}
+					field.Set(reflect.New(field.Type().Elem()))
+				}
+				field = field.Elem()
+			}
+			if field.Kind() == reflect.Struct {
+				bindFiles(field, files)
+			}
+			continue
+		}
+
+		name := parseTag(sf)
+		if name == "-" {
+			continue
+		}
+
+		fs, found := files[name]
+		if !found {
+			continue
+		}
+
+		switch sf.Type {
+		case fileType:
+			field.Set(reflect.ValueOf(fs[0]))
+			if files[name] = fs[1:]; len(files[name]) == 0 {
+				delete(files, name)
+			}
+		case filesType:
+			field.Set(reflect.ValueOf(fs))
+			delete(files, name)
+		}
+	}
+}
+
+// 与 form 包中 form 标签的处理方式相同
+func parseTag(sf reflect.StructField) string {
+	if unicode.IsLower(rune(sf.Name[0])) {
+		return "-"
+	}
+
+	if tag := strings.TrimSpace(sf.Tag.Get(form.Tag)); tag != "" {
+		return tag
+	}
+	return sf.Name
+}
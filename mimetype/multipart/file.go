@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package multipart
+
+import (
+	"errors"
+	"io"
+	"net/textproto"
+	"os"
+)
+
+// File 上传的文件
+type File struct {
+	Field    string // 字段名
+	Filename string // 客户端提交的文件名
+	Header   textproto.MIMEHeader
+	Size     int64
+
+	// 文件的保存路径
+	//
+	// 仅在采用 [TempDir] 时有值，自定义的 [Sink] 也可以设置此值，
+	// 以便在出错时由 [File.Remove] 进行清理。
+	Path string
+}
+
+// Sink 保存文件内容的方法
+//
+// f 为文件的描述信息，r 为文件的内容。
+// 如果 r 返回了超出大小限制的错误，该错误需要原样返回。
+type Sink = func(f *File, r io.Reader) error
+
+// TempDir 将文件保存在 dir 目录下的临时文件中
+//
+// dir 为空表示采用 [os.TempDir]。
+func TempDir(dir string) Sink {
+	return func(f *File, r io.Reader) error {
+		tmp, err := os.CreateTemp(dir, "multipart-*")
+		if err != nil {
+			return err
+		}
+		f.Path = tmp.Name()
+
+		if _, err = io.Copy(tmp, r); err != nil {
+			return errors.Join(err, tmp.Close())
+		}
+		return tmp.Close()
+	}
+}
+
+// Open 打开由 [File.Path] 指定的文件
+func (f *File) Open() (*os.File, error) { return os.Open(f.Path) }
+
+// Remove 删除由 [File.Path] 指定的文件
+//
+// f 为空或是 [File.Path] 为空时，不作任何操作。
+func (f *File) Remove() error {
+	if f == nil || f.Path == "" {
+		return nil
+	}
+
+	if err := os.Remove(f.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	f.Path = ""
+	return nil
+}
+
+var errTooLarge = errors.New("multipart: too large")
+
+// 限制读取大小的 [io.Reader]
+type limitReader struct {
+	r    io.Reader
+	n    int64 // 小于等于零表示不限制
+	read int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.exceeded() {
+		return 0, errTooLarge
+	}
+
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.exceeded() {
+		return n, errTooLarge
+	}
+	return n, err
+}
+
+func (l *limitReader) exceeded() bool { return l.n > 0 && l.read > l.n }
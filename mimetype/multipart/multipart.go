@@ -0,0 +1,269 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package multipart 用于处理 multipart/form-data 编码
+//
+//	type Upload struct {
+//	    Name   string            `form:"name"`
+//	    Avatar *multipart.File   `form:"avatar"`
+//	    Photos []*multipart.File `form:"photos"`
+//	}
+//
+//	func upload(ctx *web.Context) web.Responser {
+//	    u := &Upload{}
+//	    if resp := ctx.Read(true, u, web.ProblemUnprocessableEntity); resp != nil {
+//	        return resp
+//	    }
+//	    defer u.Avatar.Remove()
+//	    ...
+//	}
+//
+// 普通字段与 [form] 采用相同的 form 标签和处理方式，
+// 文件字段的类型只能是 *[File] 或是 []*[File]。
+// 文件内容以流的方式写入由 [Sink] 指定的位置，而不是保存在内存中。
+//
+// 超出大小限制时，返回的错误实现了 [web.Filter] 接口，
+// [web.Context.Read] 会将其转换为以字段名为参数的 [web.Problem]。
+//
+// boundary 从 Content-Type 报头的参数中获取，
+// 如果传入的 [io.Reader] 无法提供 Content-Type 报头（比如直接调用 [Unmarshal]），
+// 则会从内容的第一个分隔行中获取 boundary 的值。
+//
+// [form]: https://pkg.go.dev/github.com/issue9/web/mimetype/form
+package multipart
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/mimetype"
+)
+
+const Mimetype = header.MultipartFormData
+
+// 各项大小限制的默认值
+const (
+	DefaultMaxFileSize  = 32 << 20
+	DefaultMaxTotalSize = 64 << 20
+	DefaultMaxValueSize = 1 << 20
+)
+
+// Options 解码时的选项
+type Options struct {
+	// 单个文件的最大值
+	//
+	// 为零表示采用 [DefaultMaxFileSize]，小于零表示不限制。
+	MaxFileSize int64
+
+	// 所有内容的最大值
+	//
+	// 为零表示采用 [DefaultMaxTotalSize]，小于零表示不限制。
+	MaxTotalSize int64
+
+	// 单个普通字段的最大值
+	//
+	// 普通字段的内容会保存在内存中。为零表示采用 [DefaultMaxValueSize]，小于零表示不限制。
+	MaxValueSize int64
+
+	// 文件内容的保存方式，为空表示采用 TempDir("")。
+	Sink Sink
+}
+
+// Form 表示 multipart/form-data 的所有内容
+//
+// 可以作为 [Unmarshal] 的参数，用于获取未知字段的内容。
+type Form struct {
+	Values url.Values
+	Files  map[string][]*File
+}
+
+var defaultUnmarshal = New(nil)
+
+// Marshal 直接返回 [mimetype.ErrUnsupported]
+func Marshal(*web.Context, any) ([]byte, error) { return nil, mimetype.ErrUnsupported() }
+
+// Unmarshal 采用默认选项的解码方法
+//
+// 各项大小限制采用默认值，文件保存在 [os.TempDir] 中。
+func Unmarshal(r io.Reader, v any) error { return defaultUnmarshal(r, v) }
+
+// New 根据 o 生成解码方法
+//
+// v 可以是 *[Form]、[url.Values] 或是结构体指针，
+// 绑定到 v 的文件由调用方负责清理，未能绑定到 v 的文件（比如 v 中不存在的字段）会被删除，
+// 在返回错误时，所有已经生成的文件都会被删除。
+func New(o *Options) web.UnmarshalFunc {
+	if o == nil {
+		o = &Options{}
+	}
+	o = &Options{
+		MaxFileSize:  sizeLimit(o.MaxFileSize, DefaultMaxFileSize),
+		MaxTotalSize: sizeLimit(o.MaxTotalSize, DefaultMaxTotalSize),
+		MaxValueSize: sizeLimit(o.MaxValueSize, DefaultMaxValueSize),
+		Sink:         o.Sink,
+	}
+	if o.Sink == nil {
+		o.Sink = TempDir("")
+	}
+
+	return func(r io.Reader, v any) error {
+		f, err := o.parse(r)
+		if err != nil {
+			return err
+		}
+
+		err = f.bind(v)
+		f.remove() // 删除未绑定的文件
+		return err
+	}
+}
+
+// 将 v 转换为 [limitReader] 可用的值，零值表示不限制。
+func sizeLimit(v, def int64) int64 {
+	switch {
+	case v == 0:
+		return def
+	case v < 0:
+		return 0
+	default:
+		return v
+	}
+}
+
+func (o *Options) parse(r io.Reader) (*Form, error) {
+	boundary, r, err := getBoundary(r)
+	if err != nil {
+		return nil, err
+	}
+
+	total := &limitReader{r: r, n: o.MaxTotalSize}
+	mr := multipart.NewReader(total, boundary)
+	f := &Form{Values: url.Values{}, Files: map[string][]*File{}}
+	vs := &violations{}
+	tooLarge := func() error {
+		f.remove()
+		return vs.add("", web.Phrase("total size exceeds %d", o.MaxTotalSize))
+	}
+	for {
+		p, err := mr.NextPart()
+		switch {
+		case total.exceeded(): // multipart.Reader 会预读内容，错误可能被其忽略。
+			return nil, tooLarge()
+		case errors.Is(err, io.EOF):
+			if len(vs.items) > 0 {
+				f.remove()
+				return nil, vs
+			}
+			return f, nil
+		case err != nil:
+			f.remove()
+			return nil, err
+		}
+
+		name := p.FormName()
+		if name == "" {
+			p.Close()
+			continue
+		}
+
+		if p.FileName() == "" {
+			data, err := io.ReadAll(&limitReader{r: p, n: o.MaxValueSize})
+			switch {
+			case total.exceeded():
+				return nil, tooLarge()
+			case errors.Is(err, errTooLarge):
+				vs.add(name, web.Phrase("value size exceeds %d", o.MaxValueSize))
+				if _, err := io.Copy(io.Discard, p); err != nil && !errors.Is(err, errTooLarge) {
+					f.remove()
+					return nil, err
+				}
+			case err != nil:
+				f.remove()
+				return nil, err
+			default:
+				f.Values.Add(name, string(data))
+			}
+			continue
+		}
+
+		file := &File{Field: name, Filename: p.FileName(), Header: p.Header}
+		lr := &limitReader{r: p, n: o.MaxFileSize}
+		err = o.Sink(file, lr)
+		file.Size = lr.read
+		switch {
+		case err == nil:
+			f.Files[name] = append(f.Files[name], file)
+		case total.exceeded():
+			file.Remove()
+			return nil, tooLarge()
+		case errors.Is(err, errTooLarge):
+			file.Remove()
+			vs.add(name, web.Phrase("file size exceeds %d", o.MaxFileSize))
+			if _, err := io.Copy(io.Discard, p); err != nil && !errors.Is(err, errTooLarge) { // 丢弃剩余的内容，继续处理下一个字段。
+				f.remove()
+				return nil, err
+			}
+		default:
+			file.Remove()
+			f.remove()
+			return nil, err
+		}
+	}
+}
+
+// 获取 boundary 的值
+//
+// 优先从 Content-Type 报头中获取，返回的 [io.Reader] 用于替代 r 读取后续的内容。
+func getBoundary(r io.Reader) (string, io.Reader, error) {
+	if ct, ok := r.(interface{ ContentType() string }); ok {
+		if _, params, err := mime.ParseMediaType(ct.ContentType()); err == nil && params["boundary"] != "" {
+			return params["boundary"], r, nil
+		}
+		return "", nil, web.NewLocaleError("not found multipart boundary")
+	}
+
+	br := bufio.NewReader(r)
+	boundary, line, err := readBoundary(br)
+	if err != nil {
+		return "", nil, err
+	}
+	return boundary, io.MultiReader(strings.NewReader(line), br), nil
+}
+
+// 从第一个分隔行中读取 boundary
+//
+// 返回的 line 为已经读取的分隔行。
+func readBoundary(r *bufio.Reader) (boundary, line string, err error) {
+	for {
+		line, err = r.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = web.NewLocaleError("not found multipart boundary")
+			}
+			return "", "", err
+		}
+
+		if strings.HasPrefix(line, "--") {
+			if boundary = strings.TrimSpace(line[2:]); boundary != "" {
+				return boundary, line, nil
+			}
+		}
+	}
+}
+
+func (f *Form) remove() {
+	for _, files := range f.Files {
+		for _, file := range files {
+			file.Remove()
+		}
+	}
+}
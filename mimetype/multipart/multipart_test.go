@@ -0,0 +1,255 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package multipart
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web"
+	mj "github.com/issue9/web/mimetype/json"
+	"github.com/issue9/web/server"
+	"github.com/issue9/web/server/servertest"
+)
+
+var (
+	_ web.MarshalFunc   = Marshal
+	_ web.UnmarshalFunc = Unmarshal
+	_ web.Filter        = &violations{}
+)
+
+type Embed struct {
+	Photos []*File `form:"photos"`
+}
+
+type upload struct {
+	Embed
+	Name   string `form:"name"`
+	Age    int
+	Avatar *File `form:"avatar"`
+	Other  *File `form:"-"`
+}
+
+func newBody(a *assert.Assertion, fields map[string]string, files map[string][]string) (*bytes.Buffer, string) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	for k, v := range fields {
+		a.NotError(w.WriteField(k, v))
+	}
+	for k, contents := range files {
+		for _, c := range contents {
+			fw, err := w.CreateFormFile(k, k+".txt")
+			a.NotError(err)
+			_, err = fw.Write([]byte(c))
+			a.NotError(err)
+		}
+	}
+	a.NotError(w.Close())
+	return buf, w.FormDataContentType()
+}
+
+func readFile(a *assert.Assertion, f *File) string {
+	r, err := f.Open()
+	a.NotError(err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	a.NotError(err)
+	return string(data)
+}
+
+func TestUnmarshal(t *testing.T) {
+	a := assert.New(t, false)
+
+	body, _ := newBody(a, map[string]string{"name": "n1", "Age": "18", "Other": "x"}, map[string][]string{
+		"avatar": {"avatar"},
+		"photos": {"p1", "p2"},
+		"Other":  {"other"},
+	})
+	u := &upload{}
+	a.NotError(Unmarshal(body, u))
+	a.Equal(u.Name, "n1").
+		Equal(u.Age, 18).
+		Nil(u.Other).
+		NotNil(u.Avatar).
+		Length(u.Photos, 2)
+	a.Equal(readFile(a, u.Avatar), "avatar").
+		Equal(u.Avatar.Filename, "avatar.txt").
+		Equal(u.Avatar.Field, "avatar").
+		Equal(u.Avatar.Size, 6).
+		Equal(readFile(a, u.Photos[0]), "p1").
+		Equal(readFile(a, u.Photos[1]), "p2")
+
+	path := u.Avatar.Path
+	a.NotError(u.Avatar.Remove()).Empty(u.Avatar.Path)
+	_, err := os.Stat(path)
+	a.ErrorIs(err, os.ErrNotExist)
+	a.NotError(u.Avatar.Remove()) // 多次删除
+
+	// Form
+	body, _ = newBody(a, map[string]string{"name": "n1"}, map[string][]string{"f1": {"f1"}})
+	f := &Form{}
+	a.NotError(Unmarshal(body, f))
+	a.Equal(f.Values, url.Values{"name": []string{"n1"}}).
+		Length(f.Files["f1"], 1)
+	a.NotError(f.Files["f1"][0].Remove())
+
+	// url.Values
+	body, _ = newBody(a, map[string]string{"name": "n1"}, nil)
+	vals := url.Values{}
+	a.NotError(Unmarshal(body, vals))
+	a.Equal(vals, url.Values{"name": []string{"n1"}})
+
+	// 无法绑定文件
+	body, _ = newBody(a, nil, map[string][]string{"f1": {"f1"}})
+	m := map[string]string{}
+	a.Error(Unmarshal(body, &m))
+
+	// 没有分隔符
+	a.Error(Unmarshal(strings.NewReader("abc"), u))
+}
+
+func TestNew(t *testing.T) {
+	a := assert.New(t, false)
+
+	var sunk []string
+	u := New(&Options{
+		MaxFileSize:  5,
+		MaxTotalSize: 1024,
+		Sink: func(f *File, r io.Reader) error {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			sunk = append(sunk, f.Field+"="+string(data))
+			return nil
+		},
+	})
+
+	body, _ := newBody(a, nil, map[string][]string{"avatar": {"12345"}})
+	obj := &upload{}
+	a.NotError(u(body, obj))
+	a.Equal(sunk, []string{"avatar=12345"}).Empty(obj.Avatar.Path)
+
+	// 单个文件超出大小
+	body, _ = newBody(a, map[string]string{"name": "n1"}, map[string][]string{"avatar": {"123456"}, "photos": {"1"}})
+	err := u(body, &upload{})
+	a.Error(err)
+	vs, ok := err.(*violations)
+	a.True(ok, err).Length(vs.items, 1).Equal(vs.items[0].name, "avatar")
+
+	// 总大小超出
+	u = New(&Options{MaxTotalSize: 100, Sink: TempDir(t.TempDir())})
+	body, _ = newBody(a, nil, map[string][]string{"avatar": {strings.Repeat("x", 200)}})
+	err = u(body, &upload{})
+	vs, ok = err.(*violations)
+	a.True(ok, err).Length(vs.items, 1).Empty(vs.items[0].name)
+	body, _ = newBody(a, map[string]string{"name": strings.Repeat("x", 200)}, nil)
+	err = u(body, &upload{})
+	vs, ok = err.(*violations)
+	a.True(ok).Length(vs.items, 1).Empty(vs.items[0].name)
+
+	// 普通字段超出大小
+	u = New(&Options{MaxValueSize: 3, Sink: TempDir(t.TempDir())})
+	body, _ = newBody(a, map[string]string{"name": "1234", "Age": "18"}, nil)
+	err = u(body, &upload{})
+	vs, ok = err.(*violations)
+	a.True(ok, err).Length(vs.items, 1).Equal(vs.items[0].name, "name")
+
+	// 未绑定的文件会被删除
+	dir := t.TempDir()
+	u = New(&Options{Sink: TempDir(dir)})
+	body, _ = newBody(a, nil, map[string][]string{
+		"avatar":  {"a1", "a2"},
+		"Other":   {"other"},
+		"unknown": {"unknown"},
+	})
+	obj = &upload{}
+	a.NotError(u(body, obj)).NotNil(obj.Avatar)
+	entries, err := os.ReadDir(dir)
+	a.NotError(err).Length(entries, 1).Equal(readFile(a, obj.Avatar), "a1")
+	a.NotError(obj.Avatar.Remove())
+
+	body, _ = newBody(a, map[string]string{"name": "n1"}, map[string][]string{"f1": {"f1"}})
+	a.NotError(u(body, url.Values{}))
+	entries, err = os.ReadDir(dir)
+	a.NotError(err).Empty(entries)
+
+	// 小于零表示不限制
+	u = New(&Options{MaxValueSize: -1, MaxTotalSize: -1, Sink: TempDir(t.TempDir())})
+	body, _ = newBody(a, map[string]string{"name": strings.Repeat("x", DefaultMaxValueSize+1)}, nil)
+	obj = &upload{}
+	a.NotError(u(body, obj)).Length(obj.Name, DefaultMaxValueSize+1)
+}
+
+func TestContext_Read(t *testing.T) {
+	a := assert.New(t, false)
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Codec: web.NewCodec().
+			AddMimetype(mj.Mimetype, mj.Marshal, mj.Unmarshal, "", true, true).
+			AddMimetype(Mimetype, Marshal, New(&Options{MaxFileSize: 5}), "", true, false),
+	})
+	a.NotError(err).NotNil(s)
+
+	s.Routers().New("default", nil).Post("/upload", func(ctx *web.Context) web.Responser {
+		u := &upload{}
+		if resp := ctx.Read(false, u, web.ProblemUnprocessableEntity); resp != nil {
+			return resp
+		}
+		defer u.Avatar.Remove()
+		return web.OK(readFile(a, u.Avatar))
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	body, ct := newBody(a, nil, map[string][]string{"avatar": {"12345"}})
+	servertest.Post(a, "http://localhost:8080/upload", body.Bytes()).
+		Header(header.ContentType, ct).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`"12345"`)
+
+	body, ct = newBody(a, nil, map[string][]string{"avatar": {"123456"}})
+	servertest.Post(a, "http://localhost:8080/upload", body.Bytes()).
+		Header(header.ContentType, ct).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusUnprocessableEntity).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			p := &web.Problem{}
+			a.NotError(json.Unmarshal(body, p)).
+				Length(p.Params, 1).
+				Equal(p.Params[0].Name, "avatar")
+		})
+
+	// boundary 由 Content-Type 报头指定，前导内容中的分隔行不影响解析。
+	body, ct = newBody(a, nil, map[string][]string{"avatar": {"12345"}})
+	servertest.Post(a, "http://localhost:8080/upload", append([]byte("--preamble\r\n"), body.Bytes()...)).
+		Header(header.ContentType, ct).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`"12345"`)
+
+	// Content-Type 缺少 boundary
+	body, _ = newBody(a, nil, map[string][]string{"avatar": {"12345"}})
+	servertest.Post(a, "http://localhost:8080/upload", body.Bytes()).
+		Header(header.ContentType, Mimetype).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		NotStatus(http.StatusOK)
+}
@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package multipart
+
+import (
+	"strings"
+
+	"github.com/issue9/web"
+)
+
+// 违反限制条件的错误信息
+//
+// 实现了 [web.Filter] 接口，[web.Context.Read] 会将其转换成 [web.Problem]。
+type violations struct {
+	items []violation
+}
+
+type violation struct {
+	name   string
+	reason web.LocaleStringer
+}
+
+func (v *violations) add(name string, reason web.LocaleStringer) *violations {
+	v.items = append(v.items, violation{name: name, reason: reason})
+	return v
+}
+
+func (v *violations) Error() string {
+	msgs := make([]string, 0, len(v.items))
+	for _, item := range v.items {
+		msg := item.reason.LocaleString(nil)
+		if item.name != "" {
+			msg = item.name + ": " + msg
+		}
+		msgs = append(msgs, msg)
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (v *violations) Filter(f *web.FilterContext) {
+	for _, item := range v.items {
+		f.AddReason(item.name, item.reason)
+	}
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package ndjson
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/internal/status"
+)
+
+// Get 以 GET 请求 path 并以迭代的方式返回 NDJSON 中的每一条记录
+//
+// 提交的请求会将 Accept 报头设置为 [Mimetype]，服务端需要能正确处理该值才行。
+// 有关 pb 的说明可参考 [web.Client.ParseResponse]。
+func Get[T any](c *web.Client, path string, pb web.ProblemBuilder) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		req, err := c.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		req.Header.Set(header.Accept, Mimetype)
+
+		rsp, err := c.Client().Do(req)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		Iter[T](c, rsp, pb)(yield)
+	}
+}
+
+// Iter 以迭代的方式返回 rsp 中的每一条记录
+//
+// 如果 rsp 是表示错误的状态码，将返回由 pb 构建的 [web.Problem] 作为错误信息，
+// 有关 pb 的说明可参考 [web.Client.ParseResponse]。
+// 当迭代结束或是中途退出时，会关闭 rsp.Body。
+func Iter[T any](c *web.Client, rsp *http.Response, pb web.ProblemBuilder) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer rsp.Body.Close()
+		var zero T
+
+		if status.IsProblemStatus(rsp.StatusCode) {
+			err := c.ParseResponse(rsp, nil, pb)
+			if err == nil { // 没有报文内容
+				err = web.NewError(rsp.StatusCode, errors.New(http.StatusText(rsp.StatusCode)))
+			}
+			yield(zero, err)
+			return
+		}
+
+		body, err := c.ReadBody(rsp)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer body.Close()
+
+		d := json.NewDecoder(body)
+		for {
+			var v T
+			if err := d.Decode(&v); err != nil {
+				if !errors.Is(err, io.EOF) {
+					yield(zero, err)
+				}
+				return
+			}
+
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
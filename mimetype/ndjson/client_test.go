@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package ndjson
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/selector"
+	"github.com/issue9/web/server/servertest"
+)
+
+func TestGet(t *testing.T) {
+	a := assert.New(t, false)
+	s := newServer(a)
+
+	objs := []*object{{ID: 1, Name: "1"}, {ID: 2, Name: "2"}, {ID: 3, Name: "3"}}
+	r := s.Routers().New("default", nil)
+	r.Get("/seq", func(*web.Context) web.Responser { return Seq(slices.Values(objs), 1) })
+	r.Get("/problem", func(ctx *web.Context) web.Responser { return ctx.Problem(web.ProblemBadRequest) })
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	sel := selector.NewRoundRobin(false, 1)
+	sel.Update(selector.NewPeer("http://localhost:8080"))
+	c := s.NewClient(nil, sel, Mimetype, json.Marshal)
+
+	var items []*object
+	for v, err := range Get[*object](c, "/seq", nil) {
+		a.NotError(err)
+		items = append(items, v)
+	}
+	a.Equal(items, objs)
+
+	// 中途退出
+	items = items[:0]
+	for v, err := range Get[*object](c, "/seq", nil) {
+		a.NotError(err)
+		items = append(items, v)
+		break
+	}
+	a.Equal(items, objs[:1])
+
+	// problem
+	var count int
+	for v, err := range Get[*object](c, "/problem", nil) {
+		count++
+		a.Nil(v)
+		p, ok := err.(*web.Problem)
+		a.True(ok).Equal(p.Status, 400)
+	}
+	a.Equal(count, 1)
+
+	// 没有报文内容的错误
+	count = 0
+	for _, err := range Get[*object](c, "/not-exists", nil) {
+		count++
+		a.Error(err)
+	}
+	a.Equal(count, 1)
+}
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package ndjson [NDJSON] 格式的序列化方法
+//
+// 每一行都是一个完整的 JSON 对象，适用于流式地输出大量的数据。
+//
+// [NDJSON]: https://github.com/ndjson/ndjson-spec
+package ndjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"reflect"
+
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/internal/qheader"
+)
+
+const (
+	Mimetype        = "application/x-ndjson"
+	ProblemMimetype = "application/problem+json"
+)
+
+// Marshal 将 v 编码为 NDJSON
+//
+// 如果 v 是数组或是切片，那么每个元素占一行，否则 v 作为单独的一行输出。
+func Marshal(ctx *web.Context, v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := Encode(ctx, buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encode 将 v 编码为 NDJSON 并写入 w
+//
+// 对 v 的处理方式与 [Marshal] 相同。
+func Encode(_ *web.Context, w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if !isList(rv) {
+		return enc.Encode(v) // Encode 会在末尾添加换行符
+	}
+
+	for i := range rv.Len() {
+		if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unmarshal 从 r 中解码 NDJSON 的内容至 v
+//
+// 如果 v 是指向切片的指针，那么会将每一行的内容解码为切片的一个元素，
+// 否则仅解码第一行的内容至 v。
+func Unmarshal(r io.Reader, v any) error {
+	d := json.NewDecoder(r)
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Slice || isBytes(rv.Elem()) {
+		return d.Decode(v)
+	}
+
+	slice := rv.Elem()
+	for {
+		elem := reflect.New(slice.Type().Elem())
+		if err := d.Decode(elem.Interface()); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		slice.Set(reflect.Append(slice, elem.Elem()))
+	}
+}
+
+func isList(rv reflect.Value) bool {
+	return (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && !isBytes(rv)
+}
+
+// []byte 和 json.RawMessage 作为单个值处理
+func isBytes(rv reflect.Value) bool {
+	return rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8
+}
+
+// Seq 将 seq 中的元素以 NDJSON 的格式输出
+//
+// 每个元素占一行，内容会经过协商后的压缩算法和字符集处理。
+// flush 表示每输出多少条记录调用一次 [http.ResponseController.Flush]，
+// 如果小于等于 1，表示每条记录都刷新。
+// 当客户端断开连接时，将停止对 seq 的迭代。
+func Seq[T any](seq iter.Seq[T], flush int) web.Responser {
+	return web.ResponserFunc(func(ctx *web.Context) {
+		ctx.Header().Set(header.ContentType, qheader.BuildContentType(Mimetype, ctx.Charset()))
+		ctx.Header().Set(header.CacheControl, header.NoCache)
+		ctx.WriteHeader(http.StatusOK)
+
+		rc := http.NewResponseController(ctx)
+		enc := json.NewEncoder(ctx)
+		done := ctx.Request().Context().Done()
+		var count int
+		for v := range seq {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			if err := enc.Encode(v); err != nil {
+				ctx.Logs().ERROR().Error(err)
+				return
+			}
+
+			if count++; count >= flush {
+				count = 0
+				if err := rc.Flush(); err != nil {
+					ctx.Logs().ERROR().Error(err)
+					return
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package ndjson
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/compressor"
+	"github.com/issue9/web/mimetype/mimetypetest"
+	"github.com/issue9/web/server"
+	"github.com/issue9/web/server/servertest"
+)
+
+var (
+	_ web.MarshalFunc   = Marshal
+	_ web.UnmarshalFunc = Unmarshal
+	_ web.EncodeFunc    = Encode
+)
+
+type object struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestNDJSON(t *testing.T) {
+	a := assert.New(t, false)
+	mimetypetest.Test(a, Marshal, Unmarshal)
+	mimetypetest.TestEncode(a, Encode, Unmarshal)
+
+	objs := []*object{{ID: 1, Name: "1"}, {ID: 2, Name: "2"}}
+	data, err := Marshal(nil, objs)
+	a.NotError(err).Equal(string(data), "{\"id\":1,\"name\":\"1\"}\n{\"id\":2,\"name\":\"2\"}\n")
+
+	var objs2 []*object
+	a.NotError(Unmarshal(bytes.NewBuffer(data), &objs2)).Equal(objs2, objs)
+
+	obj := &object{}
+	a.NotError(Unmarshal(bytes.NewBuffer(data), obj)).Equal(obj, objs[0])
+
+	data, err = Marshal(nil, objs[0])
+	a.NotError(err).Equal(string(data), "{\"id\":1,\"name\":\"1\"}\n")
+
+	data, err = Marshal(nil, []byte("123"))
+	a.NotError(err).Equal(string(data), "\"MTIz\"\n")
+}
+
+func newServer(a *assert.Assertion) web.Server {
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Codec: web.NewCodec().
+			AddMimetype(Mimetype, Marshal, Unmarshal, ProblemMimetype, true, true, Encode).
+			AddCompressor(compressor.NewGzip(gzip.DefaultCompression)),
+	})
+	a.NotError(err).NotNil(s)
+	return s
+}
+
+func TestSeq(t *testing.T) {
+	a := assert.New(t, false)
+	s := newServer(a)
+
+	s.Routers().New("default", nil).Get("/seq", func(ctx *web.Context) web.Responser {
+		return Seq(slices.Values([]*object{{ID: 1, Name: "1"}, {ID: 2, Name: "2"}, {ID: 3, Name: "3"}}), 2)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Routers().Get("default").Get("/infinite", func(*web.Context) web.Responser {
+		return Seq(func(yield func(int) bool) {
+			for i := 0; ; i++ {
+				if !yield(i) {
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}, 0)
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	servertest.Get(a, "http://localhost:8080/seq").
+		Header(header.AcceptEncoding, "").
+		Do(nil).
+		Status(http.StatusOK).
+		Header(header.ContentType, "application/x-ndjson; charset=utf-8").
+		StringBody("{\"id\":1,\"name\":\"1\"}\n{\"id\":2,\"name\":\"2\"}\n{\"id\":3,\"name\":\"3\"}\n")
+
+	// gzip
+
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:8080/seq", nil)
+	a.NotError(err)
+	req.Header.Set(header.AcceptEncoding, "gzip")
+	rsp, err := (&http.Transport{DisableCompression: true}).RoundTrip(req)
+	a.NotError(err).Equal(rsp.Header.Get(header.ContentEncoding), "gzip")
+	r, err := gzip.NewReader(rsp.Body)
+	a.NotError(err)
+	data, err := io.ReadAll(r)
+	a.NotError(err).Equal(string(data), "{\"id\":1,\"name\":\"1\"}\n{\"id\":2,\"name\":\"2\"}\n{\"id\":3,\"name\":\"3\"}\n")
+
+	// 每条记录都会被刷新，且客户端断开之后，服务端退出迭代。
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost:8080/infinite", nil)
+	a.NotError(err)
+	req.Header.Set(header.AcceptEncoding, "gzip")
+	rsp, err = (&http.Transport{DisableCompression: true}).RoundTrip(req)
+	a.NotError(err).Equal(rsp.Header.Get(header.ContentEncoding), "gzip")
+	r, err = gzip.NewReader(rsp.Body)
+	a.NotError(err)
+	buf := make([]byte, 2)
+	_, err = io.ReadFull(r, buf)
+	a.NotError(err).Equal(string(buf), "0\n")
+	cancel()
+}
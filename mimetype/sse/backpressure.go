@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package sse
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/internal/bufpool"
+)
+
+// 丢弃事件时输出日志的最小时间间隔
+const dropLogInterval = 10 * time.Second
+
+// Backpressure 事件源的发送队列已满时的处理策略
+type Backpressure int8
+
+const (
+	BackpressureBlock      Backpressure = iota // 阻塞发送者，直到队列有空间
+	BackpressureDropOldest                     // 丢弃队列中最早的事件
+	BackpressureDropNewest                     // 丢弃当前发送的事件
+	BackpressureDisconnect                     // 丢弃当前发送的事件并断开事件源
+)
+
+// 将 b 写入发送队列
+//
+// 连接断开之后的内容直接丢弃。
+func (s *Source) push(b *bytes.Buffer) {
+	select {
+	case <-s.closed:
+		bufpool.Put(b)
+		return
+	default:
+	}
+
+	switch s.backpressure {
+	case BackpressureDropOldest:
+		for {
+			select {
+			case s.buf <- b:
+				return
+			case <-s.closed:
+				bufpool.Put(b)
+				return
+			default:
+				select {
+				case old := <-s.buf: // buf 不会被关闭，old 不可能为 nil。
+					s.drop(old)
+				default:
+				}
+			}
+		}
+	case BackpressureDropNewest:
+		select {
+		case s.buf <- b:
+		default:
+			s.drop(b)
+		}
+	case BackpressureDisconnect:
+		select {
+		case s.buf <- b:
+		default:
+			s.drop(b)
+			select {
+			case s.exit <- struct{}{}:
+				s.logger.LocaleString(web.Phrase("disconnect slow sse source"))
+			default: // 已经在关闭中
+			}
+		}
+	default:
+		select {
+		case s.buf <- b:
+		case <-s.closed:
+			bufpool.Put(b)
+		}
+	}
+}
+
+// 丢弃事件
+//
+// 过载时可能频繁丢弃，日志在 dropLogInterval 内最多输出一次，内容为累计丢弃的数量。
+func (s *Source) drop(b *bytes.Buffer) {
+	bufpool.Put(b)
+	n := s.dropped.Add(1)
+
+	now := time.Now().UnixNano()
+	last := s.droppedLogged.Load()
+	if now-last >= int64(dropLogInterval) && s.droppedLogged.CompareAndSwap(last, now) {
+		s.logger.LocaleString(web.Phrase("sse source dropped %d events", n))
+	}
+}
+
+// Dropped 因为发送队列已满而被丢弃的事件数量
+func (s *Source) Dropped() uint64 { return s.dropped.Load() }
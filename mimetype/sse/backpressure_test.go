@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package sse
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/logs/v7"
+)
+
+func TestSource_push(t *testing.T) {
+	a := assert.New(t, false)
+	newSource := func(b Backpressure) *Source {
+		return &Source{
+			backpressure: b,
+			logger:       logs.New(logs.NewNopHandler()).WARN(),
+			buf:          make(chan *bytes.Buffer, 2),
+			closed:       make(chan struct{}),
+			exit:         make(chan struct{}, 1),
+		}
+	}
+
+	s := newSource(BackpressureDropNewest)
+	s.push(bytes.NewBufferString("1"))
+	s.push(bytes.NewBufferString("2"))
+	s.push(bytes.NewBufferString("3"))
+	a.Equal(s.Dropped(), 1).
+		Equal((<-s.buf).String(), "1").
+		Equal((<-s.buf).String(), "2")
+
+	s = newSource(BackpressureDropOldest)
+	s.push(bytes.NewBufferString("1"))
+	s.push(bytes.NewBufferString("2"))
+	s.push(bytes.NewBufferString("3"))
+	a.Equal(s.Dropped(), 1).
+		Equal((<-s.buf).String(), "2").
+		Equal((<-s.buf).String(), "3")
+
+	s = newSource(BackpressureDisconnect)
+	s.push(bytes.NewBufferString("1"))
+	s.push(bytes.NewBufferString("2"))
+	s.push(bytes.NewBufferString("3"))
+	s.push(bytes.NewBufferString("4")) // 已经在关闭中，不会阻塞
+	a.Equal(s.Dropped(), 2).Length(s.exit, 1).Length(s.buf, 2)
+
+	// 已经断开的事件源
+	s = newSource(BackpressureBlock)
+	close(s.closed)
+	s.push(bytes.NewBufferString("1"))
+	s.push(bytes.NewBufferString("2"))
+	s.push(bytes.NewBufferString("3")) // 队列已满，但不会阻塞。
+	a.Zero(s.Dropped()).Length(s.buf, 0)
+}
+
+func TestSource_drop(t *testing.T) {
+	a := assert.New(t, false)
+	w := &bytes.Buffer{}
+	s := &Source{logger: logs.New(logs.NewTextHandler(w)).WARN()}
+
+	for range 100 {
+		s.drop(bytes.NewBufferString("1"))
+	}
+	a.Equal(s.Dropped(), 100).
+		Equal(bytes.Count(w.Bytes(), []byte("\n")), 1) // 仅输出一次日志
+}
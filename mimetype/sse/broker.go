@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package sse
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/issue9/cache"
+
+	"github.com/issue9/web"
+)
+
+type (
+	// Broker 事件的分发接口
+	//
+	// [Server] 通过 Broker 发布事件，并从 Broker 接收事件发送给当前实例上的事件源。
+	// 多个 [Server] 实例共用同一个 Broker 的数据源，即可实现跨实例的事件分发。
+	Broker[T comparable] interface {
+		// Publish 发布事件
+		Publish(*Envelope[T]) error
+
+		// Subscribe 订阅事件
+		//
+		// 所有通过 Publish 发布的事件，包括当前实例发布的，都需要传递给 f。
+		// 返回值用于取消订阅。
+		Subscribe(f func(*Envelope[T])) (cancel func())
+	}
+
+	// Envelope 在 [Broker] 中传递的事件
+	Envelope[T comparable] struct {
+		// 接收事件的事件源
+		//
+		// 如果 To 和 Topic 都为空，表示发送给所有的事件源。
+		To    []T
+		Topic string
+
+		Event string
+		Data  []string
+	}
+
+	memoryBroker[T comparable] struct {
+		mux  sync.RWMutex
+		id   int
+		subs map[int]func(*Envelope[T])
+	}
+
+	cacheBroker[T comparable] struct {
+		s        web.Server
+		c        web.Cache
+		ttl      time.Duration
+		interval time.Duration
+	}
+)
+
+// NewMemoryBroker 声明仅作用于当前实例的 [Broker]
+//
+// 这也是 [Server] 默认的 [Broker]。
+func NewMemoryBroker[T comparable]() Broker[T] {
+	return &memoryBroker[T]{subs: make(map[int]func(*Envelope[T]), 1)}
+}
+
+func (b *memoryBroker[T]) Publish(e *Envelope[T]) error {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	for _, f := range b.subs {
+		f(e)
+	}
+	return nil
+}
+
+func (b *memoryBroker[T]) Subscribe(f func(*Envelope[T])) func() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.id++
+	id := b.id
+	b.subs[id] = f
+
+	return func() {
+		b.mux.Lock()
+		defer b.mux.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// NewCacheBroker 声明基于 [web.Server.Cache] 的 [Broker]
+//
+// 所有连接到同一缓存服务的实例之间可以共享事件，订阅者通过轮询缓存获取新的事件。
+// 在两次轮询之内依然未写入缓存的事件会被跳过，且不保证之后才写入的事件的顺序。
+//
+// prefix 为缓存中键名的前缀，共享事件的实例之间需要相同；
+// ttl 为每个事件在缓存中的保存时间，需要大于 interval；
+// interval 为轮询的时间间隔；
+func NewCacheBroker[T comparable](s web.Server, prefix string, ttl, interval time.Duration) Broker[T] {
+	if ttl <= interval {
+		panic("参数 ttl 必须大于 interval")
+	}
+
+	return &cacheBroker[T]{
+		s:        s,
+		c:        web.NewCache(prefix, s.Cache()),
+		ttl:      ttl,
+		interval: interval,
+	}
+}
+
+const cacheBrokerSeqKey = "seq"
+
+func cacheBrokerKey(seq uint64) string { return "event:" + strconv.FormatUint(seq, 10) }
+
+func (b *cacheBroker[T]) Publish(e *Envelope[T]) error {
+	_, f, _, err := b.c.Counter(cacheBrokerSeqKey, cache.Forever)
+	if err != nil {
+		return err
+	}
+
+	seq, err := f(1)
+	if err != nil {
+		return err
+	}
+	return b.c.Set(cacheBrokerKey(seq), e, b.ttl)
+}
+
+func (b *cacheBroker[T]) Subscribe(f func(*Envelope[T])) func() {
+	done := make(chan struct{})
+
+	last, _, _, err := b.c.Counter(cacheBrokerSeqKey, cache.Forever) // 仅接收订阅之后的事件
+	if err != nil {
+		b.s.Logs().ERROR().Error(err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+
+		// 上一次轮询时未找到的事件。计数器与事件的写入并不是原子操作，
+		// 事件可能还未写入，在下一次轮询时再尝试一次，依然不存在则跳过，不会阻塞之后的事件。
+		var retry []uint64
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				seq, _, _, err := b.c.Counter(cacheBrokerSeqKey, cache.Forever)
+				if err != nil {
+					b.s.Logs().ERROR().Error(err)
+					continue
+				}
+
+				for _, n := range retry {
+					if e, _ := b.get(n); e != nil {
+						f(e)
+					}
+				}
+				retry = retry[:0]
+
+				for ; last < seq; last++ {
+					if e, miss := b.get(last + 1); e != nil {
+						f(e)
+					} else if miss {
+						retry = append(retry, last+1)
+					}
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// 获取指定序号的事件
+//
+// 事件不存在时返回 nil，miss 表示是否因为不存在于缓存。
+func (b *cacheBroker[T]) get(seq uint64) (e *Envelope[T], miss bool) {
+	e = &Envelope[T]{}
+	if err := b.c.Get(cacheBrokerKey(seq), e); errors.Is(err, cache.ErrCacheMiss()) {
+		return nil, true
+	} else if err != nil {
+		b.s.Logs().ERROR().Error(err)
+		return nil, false
+	}
+	return e, false
+}
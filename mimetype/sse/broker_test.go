@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package sse
+
+import (
+	sj "encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/cache"
+	"github.com/issue9/cache/caches/memory"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/mimetype/json"
+	"github.com/issue9/web/server"
+	"github.com/issue9/web/server/servertest"
+)
+
+var (
+	_ Broker[int] = &memoryBroker[int]{}
+	_ Broker[int] = &cacheBroker[int]{}
+)
+
+func TestMemoryBroker(t *testing.T) {
+	a := assert.New(t, false)
+	b := NewMemoryBroker[int]()
+
+	var r1, r2 []*Envelope[int]
+	cancel1 := b.Subscribe(func(e *Envelope[int]) { r1 = append(r1, e) })
+	b.Subscribe(func(e *Envelope[int]) { r2 = append(r2, e) })
+
+	e1 := &Envelope[int]{Topic: "t1", Data: []string{"1"}}
+	a.NotError(b.Publish(e1))
+	cancel1()
+	e2 := &Envelope[int]{To: []int{1}, Data: []string{"2"}}
+	a.NotError(b.Publish(e2))
+
+	a.Equal(r1, []*Envelope[int]{e1}).
+		Equal(r2, []*Envelope[int]{e1, e2})
+}
+
+func TestCacheBroker(t *testing.T) {
+	a := assert.New(t, false)
+	c := memory.New() // 两个实例共用同一个缓存
+
+	newServer := func(addr string) (web.Server, *Server[int64]) {
+		s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+			HTTPServer: &http.Server{Addr: addr},
+			Codec:      web.NewCodec().AddMimetype(json.Mimetype, json.Marshal, json.Unmarshal, "", true, true),
+			Cache:      c,
+		})
+		a.NotError(err).NotNil(s)
+
+		a.PanicString(func() {
+			NewCacheBroker[int64](s, "sse_", time.Second, time.Second)
+		}, "参数 ttl 必须大于 interval")
+
+		b := NewCacheBroker[int64](s, "sse_", time.Second, 10*time.Millisecond)
+		e := NewServer[int64](s, 50*time.Millisecond, 0, 10, web.StringPhrase("sse"), WithBroker(b))
+		s.Routers().New("default", nil).Get("/event/{id}", func(ctx *web.Context) web.Responser {
+			id, resp := ctx.PathInt64("id", web.ProblemBadRequest)
+			if resp != nil {
+				return resp
+			}
+
+			_, wait := e.NewSource(id, ctx)
+			a.True(e.Join(id, "room"))
+			wait()
+			return nil
+		})
+		return s, e
+	}
+
+	s1, e1 := newServer(":8080")
+	defer servertest.Run(a, s1)()
+	defer s1.Close(0)
+
+	s2, e2 := newServer(":8081")
+	defer servertest.Run(a, s2)()
+	defer s2.Close(0)
+
+	bodies := make(chan string, 2)
+	for _, url := range []string{"http://localhost:8080/event/1", "http://localhost:8081/event/1"} {
+		go func() {
+			rsp, err := http.Get(url)
+			a.NotError(err).Equal(rsp.StatusCode, http.StatusOK)
+			data, err := io.ReadAll(rsp.Body)
+			a.NotError(err)
+			bodies <- string(data)
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	a.Equal(e1.TopicLen("room"), 1).Equal(e2.TopicLen("room"), 1)
+
+	a.NotError(e1.Publish("room", "msg", 1))
+	time.Sleep(50 * time.Millisecond)
+	a.NotError(e2.Publish("room", "msg", 2)).
+		NotError(e2.Publish("not-exists", "msg", 3))
+	time.Sleep(50 * time.Millisecond)
+	a.NotError(e1.NewEvent("all", sj.Marshal).Broadcast(4))
+	time.Sleep(100 * time.Millisecond)
+
+	// Sent 通过 Broker 发送给所有实例上相同 sid 的事件源
+	e1.NewEvent("sent", sj.Marshal).Sent(func(sid int64, _ string) any { return sid })
+	time.Sleep(100 * time.Millisecond)
+
+	e1.Get(1).Close()
+	e2.Get(1).Close()
+	body := "data:1\nevent:msg\nretry:50\n\ndata:2\nevent:msg\nretry:50\n\ndata:4\nevent:all\nretry:50\n\ndata:1\nevent:sent\nretry:50\n\n"
+	a.Equal(<-bodies, body).Equal(<-bodies, body)
+}
+
+func TestCacheBroker_gap(t *testing.T) {
+	a := assert.New(t, false)
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Cache:      memory.New(),
+	})
+	a.NotError(err).NotNil(s)
+	b := NewCacheBroker[int](s, "sse_", time.Second, 10*time.Millisecond).(*cacheBroker[int])
+
+	received := make(chan *Envelope[int], 10)
+	cancel := b.Subscribe(func(e *Envelope[int]) { received <- e })
+	defer cancel()
+
+	// 计数器已经增加，但是事件一直未写入。
+	_, inc, _, err := b.c.Counter(cacheBrokerSeqKey, cache.Forever)
+	a.NotError(err)
+	_, err = inc(1)
+	a.NotError(err)
+
+	e := &Envelope[int]{Event: "e", Data: []string{"1"}}
+	a.NotError(b.Publish(e))
+
+	select {
+	case got := <-received:
+		a.Equal(got, e)
+	case <-time.After(200 * time.Millisecond): // 远小于 ttl
+		a.TB().Fatal("未跳过缺失的事件")
+	}
+}
@@ -7,10 +7,15 @@ package sse
 import (
 	"bufio"
 	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/issue9/mux/v9/header"
 
@@ -104,3 +109,230 @@ func OnMessage(ctx context.Context, l *web.Logger, url string, c *http.Client, m
 
 	return nil
 }
+
+// State 表示 [Client] 的连接状态
+type State int8
+
+const (
+	StateConnecting State = iota // 正在连接
+	StateOpen                    // 已连接
+	StateClosed                  // 连接已断开
+)
+
+// 未收到服务端的 retry 字段时，重连间隔的最大值。
+const maxBackoff = 30 * time.Second
+
+// Client 可自动重连的 SSE 客户端
+//
+// 断开连接之后，会根据服务端返回的 retry 字段决定重连的间隔，
+// 如果服务端未指定该值，则采用带随机抖动的指数退避算法计算重连的间隔。
+// 重连时会将最后一次收到的消息 ID 作为 Last-Event-ID 报头提交给服务端。
+type Client struct {
+	l          *web.Logger
+	newRequest func(ctx context.Context) (*http.Request, error)
+	do         func(*http.Request) (*http.Response, error)
+	readBody   func(*http.Response) (io.ReadCloser, error)
+	onState    func(State, error)
+
+	retry       time.Duration
+	serverRetry bool                   // retry 是否由服务端指定
+	lastID      atomic.Pointer[string] // 由 Connect 所在的协程写入，可能被其它协程读取。
+}
+
+// NewClient 声明基于 [http.Client] 的 [Client]
+//
+// l 用于记录运行过程的错误信息；
+// c 为空时采用 &http.Client{}；
+// url 为服务端的地址；
+// retry 为默认的重连间隔，服务端返回的 retry 字段会覆盖该值；
+// onState 连接状态发生变化时的回调函数，可以为空，如果是因为错误断开连接，err 不为空；
+func NewClient(l *web.Logger, c *http.Client, url string, retry time.Duration, onState func(s State, err error)) *Client {
+	if c == nil {
+		c = &http.Client{}
+	}
+
+	return newClient(l, retry, onState, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}, c.Do, func(rsp *http.Response) (io.ReadCloser, error) { return rsp.Body, nil })
+}
+
+// NewWebClient 声明基于 [web.Client] 的 [Client]
+//
+// 每次连接都会通过 c 的 selector 重新选择服务端，
+// 其它参数可参考 [NewClient]。
+func NewWebClient(l *web.Logger, c *web.Client, path string, retry time.Duration, onState func(s State, err error)) *Client {
+	return newClient(l, retry, onState, func(ctx context.Context) (*http.Request, error) {
+		req, err := c.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			return nil, err
+		}
+		return req.WithContext(ctx), nil
+	}, c.Client().Do, c.ReadBody)
+}
+
+func newClient(
+	l *web.Logger,
+	retry time.Duration,
+	onState func(State, error),
+	newRequest func(context.Context) (*http.Request, error),
+	do func(*http.Request) (*http.Response, error),
+	readBody func(*http.Response) (io.ReadCloser, error),
+) *Client {
+	if retry <= 0 {
+		panic("参数 retry 必须大于 0")
+	}
+
+	if onState == nil {
+		onState = func(State, error) {}
+	}
+
+	return &Client{
+		l:          l,
+		newRequest: newRequest,
+		do:         do,
+		readBody:   readBody,
+		onState:    onState,
+		retry:      retry,
+	}
+}
+
+// LastEventID 最后一次收到的消息 ID
+func (c *Client) LastEventID() string {
+	if id := c.lastID.Load(); id != nil {
+		return *id
+	}
+	return ""
+}
+
+// Connect 连接服务端并将收到的消息写入 msg
+//
+// 此方法会一直阻塞，断开连接之后会自动重连，直到 ctx 被取消或是服务端返回了无法重连的状态。
+// 服务端返回 204 时，表示不再需要连接，此时返回 nil；
+// 服务端返回其它表示客户端错误的状态码时，返回 [web.NewError] 包装的错误。
+//
+// 从 msg 中取出的 [Message] 对象，在不再需要时可以调用 [Message.Free] 回收。
+//
+// NOTE: 提交的请求中会将 Accept 报头设置为 [Mimetype]，服务端需要能正确处理该值才行。
+func (c *Client) Connect(ctx context.Context, msg chan<- *Message) error {
+	var attempt int
+	for {
+		c.onState(StateConnecting, nil)
+
+		opened, err := c.connect(ctx, msg)
+		c.onState(StateClosed, err)
+
+		var se *stopError
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.As(err, &se):
+			return se.err
+		case opened:
+			attempt = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.delay(attempt)):
+			attempt++
+		}
+	}
+}
+
+// 重连之前的等待时间
+func (c *Client) delay(attempt int) time.Duration {
+	if c.serverRetry {
+		return c.retry
+	}
+
+	d := c.retry << min(attempt, 16)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+	return d/2 + rand.N(d/2+1) // 在 [d/2, d] 之间随机
+}
+
+// 表示不再需要重连的错误
+type stopError struct{ err error }
+
+func (e *stopError) Error() string {
+	if e.err == nil {
+		return "stop"
+	}
+	return e.err.Error()
+}
+
+// 连接服务端并读取数据，直到连接断开。
+//
+// opened 表示是否成功建立了连接。
+func (c *Client) connect(ctx context.Context, msg chan<- *Message) (opened bool, err error) {
+	req, err := c.newRequest(ctx)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set(header.CacheControl, header.NoCache)
+	req.Header.Set(header.Accept, Mimetype)
+	if id := c.LastEventID(); id != "" {
+		req.Header.Set(header.LastEventID, id)
+	}
+
+	rsp, err := c.do(req)
+	if err != nil {
+		return false, err
+	}
+	defer rsp.Body.Close()
+
+	switch {
+	case rsp.StatusCode == http.StatusNoContent:
+		return false, &stopError{}
+	case rsp.StatusCode >= 500:
+		return false, web.NewError(rsp.StatusCode, errors.New(http.StatusText(rsp.StatusCode)))
+	case rsp.StatusCode != http.StatusOK:
+		return false, &stopError{err: web.NewError(rsp.StatusCode, errors.New(http.StatusText(rsp.StatusCode)))}
+	}
+
+	body, err := c.readBody(rsp)
+	if err != nil {
+		return false, err
+	}
+	defer body.Close()
+
+	c.onState(StateOpen, nil)
+
+	s := bufio.NewScanner(body)
+	m := newEmptyMessage()
+	for s.Scan() {
+		if line := s.Text(); line != "" {
+			if err := m.append(line); err != nil {
+				c.l.Error(err)
+			}
+			continue
+		}
+
+		// 有空行，表示已经结束一个会话。
+		if m.ID != "" {
+			id := m.ID
+			c.lastID.Store(&id)
+		}
+		if m.Retry > 0 {
+			c.retry = time.Duration(m.Retry) * time.Millisecond
+			c.serverRetry = true
+		}
+
+		if len(m.Data) == 0 { // 没有 data 字段的消息不需要派发
+			m.Free()
+			m = newEmptyMessage()
+			continue
+		}
+
+		select {
+		case msg <- m:
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+		m = newEmptyMessage()
+	}
+
+	return true, s.Err()
+}
@@ -10,14 +10,18 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/issue9/assert/v4"
 	"github.com/issue9/logs/v7"
+	"github.com/issue9/mux/v9/header"
 
 	"github.com/issue9/web"
 	"github.com/issue9/web/mimetype/nop"
+	"github.com/issue9/web/selector"
 	"github.com/issue9/web/server"
 	"github.com/issue9/web/server/servertest"
 )
@@ -123,3 +127,106 @@ func TestOnMessage(t *testing.T) {
 	a.Equal(<-msg5, &Message{Data: []string{"{\"ID\":5,\"LastID\":\"\"}"}, Event: "se", Retry: 50}).
 		Equal(<-msg6, &Message{Data: []string{"{\"ID\":6,\"LastID\":\"\"}"}, Event: "se", Retry: 50})
 }
+
+func TestClient(t *testing.T) {
+	a := assert.New(t, false)
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Codec:      web.NewCodec().AddMimetype(Mimetype, nop.Marshal, nop.Unmarshal, "", true, true),
+	})
+	a.NotError(err).NotNil(s)
+	e := NewServer[int64](s, 50*time.Millisecond, 0, 10, web.StringPhrase("sse"))
+	a.NotNil(e)
+
+	var count atomic.Int32
+	s.Routers().New("default", nil).Get("/event/{id}", func(ctx *web.Context) web.Responser {
+		id, resp := ctx.PathInt64("id", web.ProblemBadRequest)
+		if resp != nil {
+			return resp
+		}
+
+		switch count.Add(1) {
+		case 1:
+			a.Empty(ctx.Request().Header.Get(header.LastEventID))
+			src, wait := e.NewSource(id, ctx)
+			src.Sent([]string{"1"}, "", "1")
+			src.Sent([]string{"2"}, "", "2")
+			time.AfterFunc(100*time.Millisecond, src.Close)
+			wait()
+		case 2:
+			a.Equal(ctx.Request().Header.Get(header.LastEventID), "2")
+			src, wait := e.NewSource(id, ctx)
+			src.Sent([]string{"3"}, "", "3")
+			time.AfterFunc(100*time.Millisecond, src.Close)
+			wait()
+		default: // 不再需要重连
+			return web.NoContent()
+		}
+		return nil
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	sel := selector.NewRoundRobin(false, 1)
+	sel.Update(selector.NewPeer("http://localhost:8080"))
+
+	var states []State
+	var mux sync.Mutex
+	c := NewWebClient(s.Logs().ERROR(), s.NewClient(nil, sel, Mimetype, sj.Marshal), "/event/5", time.Second, func(s State, err error) {
+		mux.Lock()
+		defer mux.Unlock()
+		states = append(states, s)
+	})
+
+	msg := make(chan *Message, 10)
+	done := make(chan struct{})
+	go func() { // 在 Connect 期间读取 LastEventID，由 -race 检测数据竞争。
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				c.LastEventID()
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+	a.NotError(c.Connect(context.Background(), msg))
+	close(done)
+	a.Equal(c.LastEventID(), "3").
+		Equal(<-msg, &Message{Data: []string{"1"}, ID: "1", Retry: 50}).
+		Equal(<-msg, &Message{Data: []string{"2"}, ID: "2", Retry: 50}).
+		Equal(<-msg, &Message{Data: []string{"3"}, ID: "3", Retry: 50}).
+		Equal(states, []State{
+			StateConnecting, StateOpen, StateClosed,
+			StateConnecting, StateOpen, StateClosed,
+			StateConnecting, StateClosed,
+		})
+
+	// 取消
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a.ErrorIs(NewClient(s.Logs().ERROR(), nil, "http://localhost:8080/event/5", time.Second, nil).Connect(ctx, msg), context.Canceled)
+}
+
+func TestClient_delay(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.PanicString(func() {
+		NewClient(nil, nil, "http://localhost", 0, nil)
+	}, "参数 retry 必须大于 0")
+
+	c := NewClient(nil, nil, "http://localhost", 100*time.Millisecond, nil)
+	d := c.delay(0)
+	a.True(d >= 50*time.Millisecond && d <= 100*time.Millisecond)
+	d = c.delay(2)
+	a.True(d >= 200*time.Millisecond && d <= 400*time.Millisecond)
+	d = c.delay(100)
+	a.True(d >= maxBackoff/2 && d <= maxBackoff)
+
+	// 由服务端指定
+	c.retry = 50 * time.Millisecond
+	c.serverRetry = true
+	a.Equal(c.delay(100), 50*time.Millisecond)
+}
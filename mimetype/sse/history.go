@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package sse
+
+import (
+	"bytes"
+	"cmp"
+	"container/ring"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 单个 sid 的历史事件记录
+//
+// 每个事件名称对应一个固定大小的环形缓冲区，超出大小之后最早的记录会被覆盖。
+// 由 [Server] 持有，与事件源是否处于连接状态无关。
+type history struct {
+	mux   sync.Mutex
+	size  int
+	seq   uint64
+	last  time.Time // 最后一次写入的时间
+	rings map[string]*ring.Ring
+
+	// 事件源加入的主题，断开连接之后依然保留，
+	// 用于记录断开期间发布到这些主题的事件。
+	topics topics
+}
+
+type historyItem struct {
+	seq  uint64
+	id   string
+	data []byte
+}
+
+func newHistory(size int) *history {
+	return &history{
+		size:  size,
+		last:  time.Now(),
+		rings: make(map[string]*ring.Ring, 5),
+	}
+}
+
+// 记录一条事件
+//
+// id 为空时会自动分配一个 ID；render 用于根据最终的 ID 生成输出内容。
+func (h *history) add(event, id string, render func(id string) *bytes.Buffer) *bytes.Buffer {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.seq++
+	h.last = time.Now()
+	if id == "" {
+		id = strconv.FormatUint(h.seq, 10)
+	}
+	b := render(id)
+
+	r, found := h.rings[event]
+	if !found {
+		r = ring.New(h.size)
+	}
+	r.Value = &historyItem{seq: h.seq, id: id, data: bytes.Clone(b.Bytes())}
+	h.rings[event] = r.Next()
+
+	return b
+}
+
+// 在锁内执行 register 并返回 lastID 之后的所有事件
+//
+// 在 register 之后才记录的事件不会包含在返回值中，
+// 保证事件不会既出现在返回值中，又通过 register 注册的事件源发送。
+//
+// lastID 为空时返回 nil；如果 lastID 并不在记录中，比如已经被覆盖或是无效的 ID，则返回所有的记录。
+// 此时无法确定客户端缺失了哪些事件，宁可重复也不遗漏，由客户端根据 ID 去重。
+func (h *history) attach(lastID string, register func()) [][]byte {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	register()
+	if lastID == "" {
+		return nil
+	}
+
+	items := make([]*historyItem, 0, h.size*len(h.rings))
+	var from uint64
+	for _, r := range h.rings {
+		r.Do(func(v any) {
+			if v == nil {
+				return
+			}
+
+			item := v.(*historyItem)
+			items = append(items, item)
+			if item.id == lastID {
+				from = max(from, item.seq)
+			}
+		})
+	}
+
+	slices.SortFunc(items, func(a, b *historyItem) int { return cmp.Compare(a.seq, b.seq) })
+
+	data := make([][]byte, 0, len(items))
+	for _, item := range items {
+		if item.seq > from {
+			data = append(data, item.data)
+		}
+	}
+	return data
+}
+
+func (h *history) lastModified() time.Time {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.last
+}
@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package sse
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestHistory(t *testing.T) {
+	a := assert.New(t, false)
+	h := newHistory(2)
+	render := func(id string) *bytes.Buffer { return bytes.NewBufferString(id) }
+
+	a.Equal(h.add("e1", "", render).String(), "1").
+		Equal(h.add("e2", "", render).String(), "2").
+		Equal(h.add("e1", "x", render).String(), "x").
+		Equal(h.add("e1", "", render).String(), "4") // 覆盖了 e1 的 1
+
+	nop := func() {}
+	a.Equal(h.attach("2", nop), [][]byte{[]byte("x"), []byte("4")}).
+		Equal(h.attach("x", nop), [][]byte{[]byte("4")}).
+		Length(h.attach("4", nop), 0).
+		Nil(h.attach("", nop))
+
+	// 不存在的 ID，返回所有
+	a.Equal(h.attach("1", nop), [][]byte{[]byte("2"), []byte("x"), []byte("4")})
+
+	// register 在锁内执行，其间无法记录新的事件。
+	registered := false
+	replay := h.attach("x", func() {
+		registered = true
+		go h.add("e2", "", render)
+		time.Sleep(50 * time.Millisecond)
+	})
+	a.True(registered).Equal(replay, [][]byte{[]byte("4")})
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package sse
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Option [Server] 的可选项
+type Option func(*options)
+
+type options struct {
+	historySize int
+	historyTTL  time.Duration
+	marshal     MarshalFunc
+
+	backpressure Backpressure
+	broker       any
+}
+
+func buildOptions(o ...Option) *options {
+	opt := &options{}
+	for _, f := range o {
+		f(opt)
+	}
+
+	if opt.marshal == nil {
+		opt.marshal = json.Marshal
+	}
+	return opt
+}
+
+// WithHistory 为每个事件源启用历史记录
+//
+// 客户端重连时，会根据其提交的 Last-Event-ID 报头重新发送之后的所有事件。
+// 历史记录由 [Server] 保存，事件源断开期间通过 [Broker] 发送给它的事件同样会被记录，
+// 但仅限于曾经通过 [Server.NewSource] 连接过的 sid。
+//
+// size 表示每个事件名称可保留的事件数量，小于等于零表示不启用；
+// ttl 表示事件源断开连接之后，其历史记录的保留时间，小于等于零表示一直保留；
+func WithHistory(size int, ttl time.Duration) Option {
+	return func(o *options) {
+		o.historySize = size
+		o.historyTTL = ttl
+	}
+}
+
+// WithMarshal 指定 [Server.Publish] 和 [Server.Broadcast] 的编码方法
+//
+// 如果未指定，则采用 [json.Marshal]。
+func WithMarshal(m MarshalFunc) Option {
+	return func(o *options) { o.marshal = m }
+}
+
+// WithBackpressure 指定事件源的发送队列已满时的处理策略
+//
+// 如果未指定，则为 [BackpressureBlock]。
+func WithBackpressure(b Backpressure) Option {
+	return func(o *options) { o.backpressure = b }
+}
+
+// WithBroker 指定事件的分发方式
+//
+// T 需要与 [NewServer] 的 T 相同。如果未指定，则采用 [NewMemoryBroker]。
+func WithBroker[T comparable](b Broker[T]) Option {
+	return func(o *options) { o.broker = b }
+}
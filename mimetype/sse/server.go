@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/issue9/mux/v9/header"
@@ -28,18 +29,33 @@ type (
 	// T 表示用于区分不同事件源的 ID，比如按用户区分，
 	// 那么该类型可能是 int64 类型的用户 ID 值。
 	Server[T comparable] struct {
-		bufCap  int
-		s       web.Server
-		retry   string
-		sources *xsync.Map[T, *Source]
+		bufCap    int
+		s         web.Server
+		retry     string
+		sources   *xsync.Map[T, *Source]
+		histories *xsync.Map[T, *history]
+		topics    *xsync.Map[string, *xsync.Map[T, *Source]]
+		broker    Broker[T]
+		cancel    func() // 取消 broker 的订阅
+		keepAlive time.Duration
+		o         *options
 	}
 
 	Source struct {
 		last time.Time
 
-		lastID string
-		retry  string
+		lastID  string
+		retry   string
+		history *history
+		topics  topics
+
+		backpressure  Backpressure
+		dropped       atomic.Uint64
+		droppedLogged atomic.Int64 // 最后一次输出丢弃日志的时间
+		logger        *web.Logger
+
 		buf    chan *bytes.Buffer
+		closed chan struct{} // 连接断开之后关闭，buf 本身不会被关闭。
 		exit   chan struct{}
 		done   chan struct{}
 	}
@@ -63,37 +79,135 @@ type (
 //
 // retry 表示反馈给用户的 retry 字段，可以为零值，表示不需要输出该字段；
 // keepAlive 表示心跳包的发送时间间隔，如果小于等于零，表示不会发送；
-// bufCap 每个 SSE 队列可缓存的数据，超过此数量，调用的 Sent 将被阻塞；
+// bufCap 每个 SSE 队列可缓存的数据，超过此数量时的处理方式由 [WithBackpressure] 决定，默认为阻塞；
 // desc 对该 SSE 服务的描述；
-func NewServer[T comparable](s web.Server, retry, keepAlive time.Duration, bufCap int, desc web.LocaleStringer) *Server[T] {
+// o 其它的可选项；
+func NewServer[T comparable](s web.Server, retry, keepAlive time.Duration, bufCap int, desc web.LocaleStringer, o ...Option) *Server[T] {
 	srv := &Server[T]{
-		bufCap:  bufCap,
-		s:       s,
-		retry:   strconv.FormatInt(retry.Milliseconds(), 10),
-		sources: xsync.NewMap[T, *Source](),
+		bufCap:    bufCap,
+		s:         s,
+		retry:     strconv.FormatInt(retry.Milliseconds(), 10),
+		sources:   xsync.NewMap[T, *Source](),
+		histories: xsync.NewMap[T, *history](),
+		topics:    xsync.NewMap[string, *xsync.Map[T, *Source]](),
+		keepAlive: keepAlive,
+		o:         buildOptions(o...),
 	}
 
-	s.Services().AddFunc(desc, srv.serve)
-	if keepAlive > 0 {
-		s.Services().AddTicker(web.Phrase("keep alive for %s", desc), srv.keepAlive, keepAlive, false, false)
+	switch b := srv.o.broker.(type) {
+	case nil:
+		srv.broker = NewMemoryBroker[T]()
+	case Broker[T]:
+		srv.broker = b
+	default:
+		panic("WithBroker 指定的类型与 T 不匹配")
 	}
+	srv.cancel = srv.broker.Subscribe(srv.deliver)
+
+	s.Services().AddFunc(desc, srv.serve)
 
 	return srv
 }
 
-func (srv *Server[T]) keepAlive(now time.Time) error {
+// 清除已经断开连接且超过 historyTTL 未更新的历史记录
+func (srv *Server[T]) cleanHistory(now time.Time) error {
+	srv.histories.Range(func(sid T, h *history) bool {
+		if _, found := srv.sources.Load(sid); !found && now.Sub(h.lastModified()) > srv.o.historyTTL {
+			srv.histories.Delete(sid)
+		}
+		return true
+	})
+	return nil
+}
+
+func (srv *Server[T]) ping(now time.Time) error {
 	for _, v := range srv.Sources() {
 		if v.last.After(now) {
 			b := bufpool.New()
 			b.WriteString(":\n\n")
-			v.buf <- b
+			select {
+			case v.buf <- b:
+			default: // 队列中已有内容，无需心跳包。
+				bufpool.Put(b)
+			}
 		}
 	}
 	return nil
 }
 
+// 将从 [Broker] 接收的事件发送给当前实例上的事件源
+//
+// 如果启用了历史记录，当前未连接的事件源也会记录该事件，以便重连之后重新发送。
+func (srv *Server[T]) deliver(e *Envelope[T]) {
+	switch {
+	case len(e.To) > 0:
+		for _, sid := range e.To {
+			if s := srv.Get(sid); s != nil {
+				s.Sent(e.Data, e.Event, "")
+			} else if h, found := srv.histories.Load(sid); found {
+				srv.record(h, e)
+			}
+		}
+	case e.Topic != "":
+		if m, found := srv.topics.Load(e.Topic); found {
+			m.Range(func(_ T, s *Source) bool {
+				s.Sent(e.Data, e.Event, "")
+				return true
+			})
+		}
+		srv.recordOffline(e, func(h *history) bool { return h.topics.has(e.Topic) })
+	default:
+		for _, s := range srv.Sources() {
+			s.Sent(e.Data, e.Event, "")
+		}
+		srv.recordOffline(e, func(*history) bool { return true })
+	}
+}
+
+// 为当前未连接且符合 filter 的事件源记录历史
+func (srv *Server[T]) recordOffline(e *Envelope[T], filter func(*history) bool) {
+	srv.histories.Range(func(sid T, h *history) bool {
+		if _, found := srv.sources.Load(sid); !found && filter(h) {
+			srv.record(h, e)
+		}
+		return true
+	})
+}
+
+func (srv *Server[T]) record(h *history, e *Envelope[T]) {
+	bufpool.Put(h.add(e.Event, "", func(id string) *bytes.Buffer { return render(e.Data, e.Event, id, srv.retry) }))
+}
+
+// 定时任务的处理方式与 websocket.Server 相同，原因参考其 serve 方法中的说明。
 func (srv *Server[T]) serve(ctx context.Context) error {
-	<-ctx.Done()
+	var keepAlive, clean <-chan time.Time
+	if srv.keepAlive > 0 {
+		t := time.NewTicker(srv.keepAlive)
+		defer t.Stop()
+		keepAlive = t.C
+	}
+	if srv.o.historySize > 0 && srv.o.historyTTL > 0 {
+		t := time.NewTicker(srv.o.historyTTL)
+		defer t.Stop()
+		clean = t.C
+	}
+
+LOOP:
+	for {
+		var err error
+		select {
+		case now := <-keepAlive:
+			err = srv.ping(now)
+		case now := <-clean:
+			err = srv.cleanHistory(now)
+		case <-ctx.Done():
+			break LOOP
+		}
+		if err != nil {
+			srv.s.Logs().ERROR().Error(err)
+		}
+	}
+	srv.cancel()
 
 	srv.sources.Range(func(_ T, v *Source) bool {
 		v.Close() // 此操作最终会从 srv.sources 中删除
@@ -120,6 +234,11 @@ func (srv *Server[T]) Get(sid T) *Source {
 // NOTE: 只有采用此方法声明之后，才有可能通过 [Server.Get] 获取实例。
 // sid 表示是事件源的唯一 ID，如果事件是根据用户进行区分的，那么该值应该是表示用户的 ID 值；
 // wait 当前 s 退出时，wait 才会返回，可以在 [web.Handler] 中阻止路由退出导致的 ctx 被回收。
+//
+// 如果通过 [WithHistory] 启用了历史记录，且客户端提交了 Last-Event-ID 报头，
+// 那么在发送新的事件之前，会先发送该 ID 之后的所有历史事件。
+// 如果该 ID 已经不在历史记录中（被覆盖或是无效的 ID），则发送所有的历史记录，
+// 客户端需要根据 ID 自行去重。
 func (srv *Server[T]) NewSource(sid T, ctx *web.Context) (s *Source, wait func()) {
 	if ss, found := srv.sources.LoadAndDelete(sid); found {
 		ss.Close()
@@ -130,25 +249,46 @@ func (srv *Server[T]) NewSource(sid T, ctx *web.Context) (s *Source, wait func()
 
 		lastID: ctx.Request().Header.Get(header.LastEventID),
 		retry:  srv.retry,
+
+		backpressure: srv.o.backpressure,
+		logger:       srv.s.Logs().WARN(),
+
 		buf:    make(chan *bytes.Buffer, srv.bufCap),
+		closed: make(chan struct{}),
 		exit:   make(chan struct{}, 1),
 		done:   make(chan struct{}, 1),
 	}
-	srv.sources.Store(sid, s)
+	if srv.o.historySize > 0 {
+		s.history, _ = srv.histories.LoadOrCompute(sid, func() (*history, bool) {
+			return newHistory(srv.o.historySize), false
+		})
+	}
+
+	var replay [][]byte
+	if s.history != nil { // 注册与获取历史记录需要在同一个锁中进行，否则在此期间发送的事件会重复。
+		replay = s.history.attach(s.lastID, func() { srv.sources.Store(sid, s) })
+	} else {
+		srv.sources.Store(sid, s)
+	}
 
 	go func() {
-		s.connect(ctx) // 阻塞，出错退出
-		defer func() {
-			close(s.buf) // 退出之前关闭，防止退出之后，依然有数据源源不断地从 Sent 输入。
-			s.buf = nil
-		}()
-		defer srv.sources.Delete(sid) // 如果 connect 返回，说明断开了连接，删除 sources 中的记录。
+		s.connect(ctx, replay) // 阻塞，出错退出
+		defer close(s.closed)  // 之后的 Sent 不再写入 buf，但依然会记录历史。
+		defer srv.sources.Compute(sid, func(old *Source, loaded bool) (*Source, xsync.ComputeOp) {
+			if loaded && old == s { // 如果 connect 返回，说明断开了连接，删除 sources 中的记录。
+				return nil, xsync.DeleteOp
+			}
+			return old, xsync.CancelOp
+		})
+		defer srv.leaveAll(sid, s)
 	}()
 	return s, s.wait
 }
 
 // 和客户端进行连接，如果返回，则表示连接被关闭。
-func (s *Source) connect(ctx *web.Context) {
+//
+// replay 表示在接收新事件之前需要重新发送的历史事件。
+func (s *Source) connect(ctx *web.Context, replay [][]byte) {
 	ctx.Header().Set(header.ContentType, qheader.BuildContentType(Mimetype, header.UTF8))
 	ctx.Header().Set(header.ContentLength, "0")
 	ctx.Header().Set(header.CacheControl, header.NoCache)
@@ -156,6 +296,25 @@ func (s *Source) connect(ctx *web.Context) {
 	ctx.WriteHeader(http.StatusOK) // 根据标准，就是 200。
 
 	rc := http.NewResponseController(ctx)
+
+	if len(replay) > 0 {
+		for _, data := range replay {
+			if _, err := ctx.Write(data); err != nil {
+				ctx.Logs().ERROR().Error(err)
+				s.done <- struct{}{}
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			if errors.Is(err, http.ErrNotSupported) {
+				panic(err) // 不支持功能，直接 panic
+			}
+			ctx.Logs().ERROR().Error(err)
+			s.done <- struct{}{}
+			return
+		}
+	}
 	for {
 		select {
 		case <-ctx.Request().Context().Done():
@@ -210,6 +369,10 @@ func (srv *Server[T]) NewEvent(name string, marshal MarshalFunc) *ServerEvent[T]
 }
 
 // Sent 向所有注册的 [Source] 发送由 f 生成的对象
+//
+// f 作用于当前实例上的事件源，生成的事件以 sid 为目标通过 [Broker] 发布，
+// 所有实例上拥有相同 sid 的事件源都会收到该事件，断开连接的事件源也会记录历史。
+// 如果事件与具体的事件源无关，[ServerEvent.Broadcast] 只需要发布一次事件，效率更高。
 func (e *ServerEvent[T]) Sent(f func(sid T, lastEventID string) any) {
 	for sid, s := range e.server.Sources() {
 		data, err := e.marshal(f(sid, s.LastEventID()))
@@ -218,7 +381,10 @@ func (e *ServerEvent[T]) Sent(f func(sid T, lastEventID string) any) {
 			return
 		}
 
-		s.Sent(strings.Split(string(data), "\n"), e.name, "")
+		env := &Envelope[T]{To: []T{sid}, Event: e.name, Data: strings.Split(string(data), "\n")}
+		if err := e.server.broker.Publish(env); err != nil {
+			e.server.s.Logs().ERROR().Error(err)
+		}
 	}
 }
 
@@ -234,13 +400,22 @@ func (s *Source) LastEventID() string { return s.lastID }
 // Sent 发送消息
 //
 // id 和 event 都可以为空，表示不需要这些值；
+// 如果启用了历史记录，消息会同时被记录，且 id 为空时会自动分配一个 ID。
+// 连接断开之后依然可以调用，此时仅记录历史。
 func (s *Source) Sent(data []string, event, id string) {
-	if s.buf != nil {
-		s.buf <- s.bytes(data, event, id)
+	if s.history == nil {
+		s.push(s.bytes(data, event, id))
+		return
 	}
+
+	s.push(s.history.add(event, id, func(id string) *bytes.Buffer { return s.bytes(data, event, id) }))
 }
 
 func (s *Source) bytes(data []string, event, id string) *bytes.Buffer {
+	return render(data, event, id, s.retry)
+}
+
+func render(data []string, event, id, retry string) *bytes.Buffer {
 	if len(data) == 0 {
 		panic("data 不能为空")
 	}
@@ -262,9 +437,9 @@ func (s *Source) bytes(data []string, event, id string) *bytes.Buffer {
 		w.WriteByte('\n')
 	}
 
-	if s.retry != "" {
+	if retry != "" {
 		w.WriteString("retry:")
-		w.WriteString(s.retry)
+		w.WriteString(retry)
 		w.WriteByte('\n')
 	}
 	w.WriteByte('\n')
@@ -6,6 +6,8 @@ package sse
 
 import (
 	sj "encoding/json"
+	"io"
+	"maps"
 	"net/http"
 	"os"
 	"strconv"
@@ -84,6 +86,138 @@ retry:50
 `)
 }
 
+func TestServer_history(t *testing.T) {
+	a := assert.New(t, false)
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Codec:      web.NewCodec().AddMimetype(json.Mimetype, json.Marshal, json.Unmarshal, "", true, true),
+	})
+	a.NotError(err).NotNil(s)
+	e := NewServer[int64](s, 50*time.Millisecond, 0, 10, web.StringPhrase("sse"), WithHistory(5, time.Second))
+	a.NotNil(e)
+	s.Routers().New("default", nil).Get("/event/{id}", func(ctx *web.Context) web.Responser {
+		id, resp := ctx.PathInt64("id", web.ProblemBadRequest)
+		if resp != nil {
+			return resp
+		}
+
+		src, wait := e.NewSource(id, ctx)
+		if src.LastEventID() == "" {
+			a.True(e.Join(id, "t"))
+			event := src.NewEvent("event", sj.Marshal)
+			a.NotError(event.Sent(1)).
+				NotError(event.Sent(2)).
+				NotError(event.Sent(3))
+		}
+		time.AfterFunc(100*time.Millisecond, src.Close)
+		wait()
+		return nil
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	servertest.Get(a, "http://localhost:8080/event/5").
+		Header(header.Accept, header.JSON).
+		Header(header.AcceptEncoding, "").
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody("data:1\nevent:event\nid:1\nretry:50\n\ndata:2\nevent:event\nid:2\nretry:50\n\ndata:3\nevent:event\nid:3\nretry:50\n\n")
+
+	// 断开期间的事件
+	time.Sleep(50 * time.Millisecond)
+	a.Nil(e.Get(5))
+	a.NotError(e.Publish("t", "topic", 4)).
+		NotError(e.Publish("not-exists", "topic", 5)).
+		NotError(e.broker.Publish(&Envelope[int64]{To: []int64{5}, Event: "to", Data: []string{"6"}}))
+
+	// 重连，发送 1 之后的内容。
+	servertest.Get(a, "http://localhost:8080/event/5").
+		Header(header.Accept, header.JSON).
+		Header(header.AcceptEncoding, "").
+		Header(header.LastEventID, "1").
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody("data:2\nevent:event\nid:2\nretry:50\n\ndata:3\nevent:event\nid:3\nretry:50\n\n" +
+			"data:4\nevent:topic\nid:4\nretry:50\n\ndata:6\nevent:to\nid:5\nretry:50\n\n")
+
+	// 其它 sid 不受影响
+	servertest.Get(a, "http://localhost:8080/event/6").
+		Header(header.Accept, header.JSON).
+		Header(header.AcceptEncoding, "").
+		Header(header.LastEventID, "1").
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody("")
+}
+
+func TestServer_topic(t *testing.T) {
+	a := assert.New(t, false)
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Codec:      web.NewCodec().AddMimetype(json.Mimetype, json.Marshal, json.Unmarshal, "", true, true),
+	})
+	a.NotError(err).NotNil(s)
+	e := NewServer[int64](s, 50*time.Millisecond, 0, 10, web.StringPhrase("sse"))
+	a.NotNil(e)
+	s.Routers().New("default", nil).Get("/event/{id}", func(ctx *web.Context) web.Responser {
+		id, resp := ctx.PathInt64("id", web.ProblemBadRequest)
+		if resp != nil {
+			return resp
+		}
+
+		_, wait := e.NewSource(id, ctx)
+		a.True(e.Join(id, "all"))
+		if id%2 == 1 {
+			a.True(e.Join(id, "odd"))
+		}
+		wait()
+		return nil
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	a.False(e.Join(1, "all")) // 不存在的事件源
+
+	bodies := make(chan [2]string, 2)
+	for _, id := range []string{"1", "2"} {
+		go func() {
+			rsp, err := http.Get("http://localhost:8080/event/" + id)
+			a.NotError(err).Equal(rsp.StatusCode, http.StatusOK)
+			data, err := io.ReadAll(rsp.Body)
+			a.NotError(err)
+			bodies <- [2]string{id, string(data)}
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	a.Equal(e.TopicLen("all"), 2).
+		Equal(e.TopicLen("odd"), 1).
+		Equal(e.TopicLen("not-exists"), 0)
+	topics := maps.Collect(e.Topics())
+	a.Equal(topics, map[string]int{"all": 2, "odd": 1})
+
+	a.NotError(e.Publish("odd", "odd", 1))
+	a.NotError(e.NewEvent("all", sj.Marshal).Publish("all", "all"))
+	a.NotError(e.Publish("not-exists", "odd", 1))
+	e.Leave(1, "all")
+	a.Equal(e.TopicLen("all"), 1)
+	a.NotError(e.Publish("all", "all", 2))
+	time.Sleep(100 * time.Millisecond)
+
+	e.Get(1).Close()
+	e.Get(2).Close()
+	b1, b2 := <-bodies, <-bodies
+	a.Equal(map[string]string{b1[0]: b1[1], b2[0]: b2[1]}, map[string]string{
+		"1": "data:1\nevent:odd\nretry:50\n\ndata:\"all\"\nevent:all\nretry:50\n\n",
+		"2": "data:\"all\"\nevent:all\nretry:50\n\ndata:2\nevent:all\nretry:50\n\n",
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	a.Equal(e.TopicLen("all"), 0).Equal(e.TopicLen("odd"), 0)
+}
+
 func TestSource_bytes(t *testing.T) {
 	a := assert.New(t, false)
 	s := &Source{}
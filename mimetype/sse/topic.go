@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package sse
+
+import (
+	"iter"
+	"strings"
+	"sync"
+
+	"github.com/puzpuzpuz/xsync/v4"
+)
+
+// 事件源订阅的主题
+type topics struct {
+	mux   sync.Mutex
+	names map[string]struct{}
+}
+
+func (t *topics) add(name string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if t.names == nil {
+		t.names = make(map[string]struct{}, 5)
+	}
+	t.names[name] = struct{}{}
+}
+
+func (t *topics) del(name string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	delete(t.names, name)
+}
+
+func (t *topics) has(name string) bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	_, found := t.names[name]
+	return found
+}
+
+func (t *topics) all() []string {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	names := make([]string, 0, len(t.names))
+	for name := range t.names {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Join 将 sid 对应的事件源加入主题 topic
+//
+// 如果 sid 对应的事件源不存在，返回 false。
+// 事件源断开连接之后，会自动退出所有已加入的主题，
+// 但是如果启用了历史记录，断开期间发布到这些主题的事件依然会被记录，直到调用 [Server.Leave]。
+func (srv *Server[T]) Join(sid T, topic ...string) bool {
+	s := srv.Get(sid)
+	if s == nil {
+		return false
+	}
+	if s.history != nil {
+		for _, t := range topic {
+			s.history.topics.add(t)
+		}
+	}
+
+	for _, t := range topic {
+		srv.topics.Compute(t, func(m *xsync.Map[T, *Source], loaded bool) (*xsync.Map[T, *Source], xsync.ComputeOp) {
+			if !loaded {
+				m = xsync.NewMap[T, *Source]()
+			}
+			m.Store(sid, s)
+			return m, xsync.UpdateOp
+		})
+		s.topics.add(t)
+	}
+	return true
+}
+
+// Leave 将 sid 对应的事件源从主题 topic 中退出
+func (srv *Server[T]) Leave(sid T, topic ...string) {
+	s := srv.Get(sid)
+	h, _ := srv.histories.Load(sid)
+	for _, t := range topic {
+		srv.leave(sid, s, t)
+		if h != nil {
+			h.topics.del(t)
+		}
+	}
+}
+
+func (srv *Server[T]) leave(sid T, s *Source, topic string) {
+	srv.topics.Compute(topic, func(m *xsync.Map[T, *Source], loaded bool) (*xsync.Map[T, *Source], xsync.ComputeOp) {
+		if !loaded {
+			return m, xsync.CancelOp
+		}
+
+		m.Compute(sid, func(old *Source, loaded bool) (*Source, xsync.ComputeOp) {
+			if loaded && (s == nil || old == s) { // 可能已经被同 sid 的新事件源替换
+				return nil, xsync.DeleteOp
+			}
+			return old, xsync.CancelOp
+		})
+
+		if m.Size() == 0 {
+			return nil, xsync.DeleteOp
+		}
+		return m, xsync.CancelOp
+	})
+
+	if s != nil {
+		s.topics.del(topic)
+	}
+}
+
+// 退出 s 加入的所有主题
+func (srv *Server[T]) leaveAll(sid T, s *Source) {
+	for _, t := range s.topics.all() {
+		srv.leave(sid, s, t)
+	}
+}
+
+// Topics 返回所有的主题及其订阅者的数量
+func (srv *Server[T]) Topics() iter.Seq2[string, int] {
+	return func(yield func(string, int) bool) {
+		srv.topics.Range(func(key string, value *xsync.Map[T, *Source]) bool {
+			return yield(key, value.Size())
+		})
+	}
+}
+
+// TopicLen 主题 topic 的订阅者数量
+func (srv *Server[T]) TopicLen(topic string) int {
+	if m, found := srv.topics.Load(topic); found {
+		return m.Size()
+	}
+	return 0
+}
+
+// Publish 向主题 topic 的所有订阅者发送事件
+//
+// obj 采用 [WithMarshal] 指定的方法编码，且只会编码一次。
+// 事件通过 [Broker] 发布，所有共享该 [Broker] 的 [Server] 上的订阅者都会收到。
+func (srv *Server[T]) Publish(topic, event string, obj any) error {
+	return srv.publish(topic, event, obj, srv.o.marshal)
+}
+
+// Broadcast 向所有的事件源发送事件
+//
+// obj 采用 [WithMarshal] 指定的方法编码，且只会编码一次。
+// 事件通过 [Broker] 以单个事件的形式发布，由每个 [Server] 实例各自发送给其上的事件源。
+func (srv *Server[T]) Broadcast(event string, obj any) error {
+	return srv.publish("", event, obj, srv.o.marshal)
+}
+
+// topic 为空表示发送给所有的事件源
+func (srv *Server[T]) publish(topic, event string, obj any, marshal MarshalFunc) error {
+	data, err := marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	return srv.broker.Publish(&Envelope[T]{Topic: topic, Event: event, Data: strings.Split(string(data), "\n")})
+}
+
+// Publish 向主题 topic 的所有订阅者发送事件
+//
+// 与 [Server.Publish] 相同，但是采用 e 的事件名称和编码方法。
+func (e *ServerEvent[T]) Publish(topic string, obj any) error {
+	return e.server.publish(topic, e.name, obj, e.marshal)
+}
+
+// Broadcast 向所有的事件源发送事件
+//
+// 与 [Server.Broadcast] 相同，但是采用 e 的事件名称和编码方法。
+func (e *ServerEvent[T]) Broadcast(obj any) error {
+	return e.server.publish("", e.name, obj, e.marshal)
+}
@@ -22,3 +22,5 @@ const (
 func Marshal(_ *web.Context, v any) ([]byte, error) { return xml.Marshal(v) }
 
 func Unmarshal(r io.Reader, v any) error { return xml.NewDecoder(r).Decode(v) }
+
+func Encode(_ *web.Context, w io.Writer, v any) error { return xml.NewEncoder(w).Encode(v) }
@@ -9,4 +9,5 @@ import "github.com/issue9/web"
 var (
 	_ web.MarshalFunc   = Marshal
 	_ web.UnmarshalFunc = Unmarshal
+	_ web.EncodeFunc    = Encode
 )
@@ -25,3 +25,5 @@ const (
 func Marshal(_ *web.Context, v any) ([]byte, error) { return yaml.Marshal(v) }
 
 func Unmarshal(r io.Reader, v any) error { return yaml.NewDecoder(r).Decode(v) }
+
+func Encode(_ *web.Context, w io.Writer, v any) error { return yaml.NewEncoder(w).Encode(v) }
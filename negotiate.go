@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/jellydator/ttlcache/v3"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/language"
+
+	"github.com/issue9/web/compressor"
+	"github.com/issue9/web/internal/qheader"
+)
+
+const (
+	negotiationCacheSize = 500
+
+	// 可用语言可能会在运行过程中增加，缓存项在此时间之后失效。
+	negotiationCacheTTL = time.Minute
+
+	// 参与协商的报头总长度超过此值时不作缓存，防止恶意的超长报头占用大量内存。
+	negotiationMaxKeyLen = 1024
+)
+
+// 参与内容协商的原始报头
+type negotiationKey struct {
+	accept, acceptCharset, acceptEncoding, acceptLanguage string
+}
+
+// 内容协商的结果
+//
+// 客户端一般只会发送少量几种固定的报头组合，所以协商结果可以缓存下来。
+type negotiation struct {
+	// 以下由 Accept 报头协商而来，mimetype 为空表示无法匹配。
+	mimetype                 *mediaType
+	acceptName, acceptParams string
+
+	// 由 Accept-Charset 报头协商而来，charsetName 为空表示无法匹配。
+	charsetName string
+	charset     encoding.Encoding
+
+	// 由 Accept-Encoding 报头协商而来，不受 [Server.CanCompress] 的影响，由使用者自行判断。
+	compressor            compressor.Compressor
+	encodingNotAcceptable bool
+
+	// 由 Accept-Encoding 报头协商而来的基于字典的压缩方法，
+	// 是否采用还取决于 Available-Dictionary 报头。
+	dictionaryCompressor compressor.DictionaryCompressor
+
+	languageTag language.Tag
+}
+
+func newNegotiationCache() *ttlcache.Cache[negotiationKey, *negotiation] {
+	return ttlcache.New(
+		ttlcache.WithCapacity[negotiationKey, *negotiation](negotiationCacheSize),
+		ttlcache.WithTTL[negotiationKey, *negotiation](negotiationCacheTTL),
+		ttlcache.WithDisableTouchOnHit[negotiationKey, *negotiation](),
+	)
+}
+
+// 根据 r 的报头和路由的设置 conf 进行内容协商
+//
+// 结果会被缓存，相同的报头组合直接返回缓存的内容。
+func (s *InternalServer) negotiate(conf *routerConfig, r *http.Request) *negotiation {
+	key := negotiationKey{
+		accept:         r.Header.Get(header.Accept),
+		acceptCharset:  r.Header.Get(header.AcceptCharset),
+		acceptEncoding: r.Header.Get(header.AcceptEncoding),
+		acceptLanguage: r.Header.Get(header.AcceptLanguage),
+	}
+
+	if len(key.accept)+len(key.acceptCharset)+len(key.acceptEncoding)+len(key.acceptLanguage) > negotiationMaxKeyLen {
+		return s.newNegotiation(conf.codec, key)
+	}
+
+	if item := conf.negotiations.Get(key); item != nil {
+		return item.Value()
+	}
+	n := s.newNegotiation(conf.codec, key)
+	conf.negotiations.Set(key, n, ttlcache.DefaultTTL)
+	return n
+}
+
+func (s *InternalServer) newNegotiation(c *Codec, key negotiationKey) *negotiation {
+	n := &negotiation{languageTag: acceptLanguage(s.server, key.acceptLanguage)}
+
+	if n.mimetype, n.acceptName, n.acceptParams = c.accept(key.accept); n.mimetype == nil {
+		return n
+	}
+
+	if n.charsetName, n.charset = qheader.ParseAcceptCharset(key.acceptCharset); n.charsetName == "" {
+		return n
+	}
+
+	n.compressor, n.encodingNotAcceptable = c.acceptEncoding(n.mimetype.name(false), key.acceptEncoding)
+	n.dictionaryCompressor = c.acceptDictionaryEncoding(n.mimetype.name(false), key.acceptEncoding)
+	return n
+}
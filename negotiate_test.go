@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"golang.org/x/text/language"
+)
+
+func TestInternalServer_negotiate(t *testing.T) {
+	a := assert.New(t, false)
+	s := newTestServer(a)
+
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set(header.Accept, header.JSON)
+	r.Header.Set(header.AcceptCharset, "gbk")
+	r.Header.Set(header.AcceptLanguage, "zh-Hant")
+	n := s.negotiate(s.config, r)
+	a.NotNil(n).
+		Equal(n.mimetype.name(false), header.JSON).
+		Equal(n.acceptName, header.JSON).
+		Equal(n.charsetName, "gbk").
+		Equal(n.languageTag, language.MustParse("zh-Hant")).
+		Equal(s.config.negotiations.Len(), 1)
+
+	// 相同的报头返回缓存的对象
+	r = httptest.NewRequest(http.MethodGet, "/other", nil)
+	r.Header.Set(header.Accept, header.JSON)
+	r.Header.Set(header.AcceptCharset, "gbk")
+	r.Header.Set(header.AcceptLanguage, "zh-Hant")
+	a.Equal(s.negotiate(s.config, r), n).Equal(s.config.negotiations.Len(), 1)
+
+	// 无法协商的结果也会被缓存
+	r = httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set(header.Accept, "not/exists")
+	n = s.negotiate(s.config, r)
+	a.Nil(n.mimetype).Equal(s.config.negotiations.Len(), 2)
+
+	// 超长的报头不缓存
+	r = httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set(header.Accept, header.JSON)
+	r.Header.Set(header.AcceptLanguage, strings.Repeat("zh,", negotiationMaxKeyLen))
+	n = s.negotiate(s.config, r)
+	a.Equal(n.mimetype.name(false), header.JSON).
+		Equal(s.config.negotiations.Len(), 2)
+}
@@ -213,12 +213,13 @@ func (resp *Response) buildRenderer(p *message.Printer, d *Document) *responseRe
 	}
 	if resp.Body != nil {
 		for k, v := range d.mediaTypes {
+			body := d.envelope(k, resp.Body, resp.Problem) // envelopes 的键名为 mimetype，需要在修改 k 之前获取。
 			if resp.Problem {
 				k = v
 			}
 			if _, found := content.Get(k); !found {
 				content.Set(k, &mediaTypeRenderer{
-					Schema: resp.Body.build(p),
+					Schema: body.build(p),
 				})
 			}
 		}
@@ -231,6 +232,14 @@ func (resp *Response) buildRenderer(p *message.Printer, d *Document) *responseRe
 	}
 }
 
+// 根据 [WithEnvelope] 对 s 进行包装
+func (d *Document) envelope(mimetype string, s *Schema, problem bool) *Schema {
+	if f, found := d.envelopes[mimetype]; found {
+		return f(s, problem)
+	}
+	return s
+}
+
 func (req *Request) build(p *message.Printer, d *Document) *renderer[requestRenderer] {
 	if req == nil {
 		return nil
@@ -253,7 +262,7 @@ func (req *Request) buildRenderer(p *message.Printer, d *Document) *requestRende
 		for mt := range d.mediaTypes {
 			if _, found := content.Get(mt); !found {
 				content.Set(mt, &mediaTypeRenderer{
-					Schema: req.Body.build(p),
+					Schema: d.envelope(mt, req.Body, false).build(p),
 				})
 			}
 		}
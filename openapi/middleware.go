@@ -9,8 +9,10 @@ import (
 	"net/http"
 	"reflect"
 	"slices"
+	"strconv"
 	"time"
 
+	"github.com/issue9/mux/v9/header"
 	"github.com/issue9/query/v3"
 
 	"github.com/issue9/web"
@@ -319,6 +321,34 @@ func (o *Operation) ResponseEmpty(status string) *Operation {
 	return o.ResponseRef(status, EmptyResponseRef, nil, nil)
 }
 
+// Precondition 声明由 [web.PreconditionFailed] 处理的条件请求
+//
+// 会添加 If-Match 和 If-Unmodified-Since 报头以及 412 状态码的返回对象，
+// required 为 true 时，还会添加 428 状态码的返回对象，与 [web.PreconditionFailed] 的参数相对应。
+func (o *Operation) Precondition(required bool) *Operation {
+	optional := func(p *Parameter) { p.Required = false }
+	o.Header(header.IfMatch, TypeString, web.Phrase("if-match header"), optional).
+		Header(header.IfUnmodifiedSince, TypeString, web.Phrase("if-unmodified-since header"), optional)
+
+	o.problemResponse(strconv.Itoa(http.StatusPreconditionFailed), web.Phrase("precondition failed response"))
+	if required {
+		o.problemResponse(strconv.Itoa(http.StatusPreconditionRequired), web.Phrase("precondition required response"))
+	}
+	return o
+}
+
+// 添加表示错误的返回对象
+//
+// 如果通过 [WithProblemResponse] 声明了错误的返回对象，则引用该对象。
+func (o *Operation) problemResponse(status string, desc web.LocaleStringer) {
+	if _, found := o.Document().components.responses[problemResponseRef]; found {
+		o.ResponseRef(status, problemResponseRef, nil, desc)
+		return
+	}
+
+	o.Response(status, web.Problem{}, desc, func(r *Response) { r.Problem = true })
+}
+
 // CallbackRef 引用 components 中定义的回调对象
 func (o *Operation) CallbackRef(name, ref string, summary, description web.LocaleStringer) *Operation {
 	if _, found := o.Document().components.callbacks[ref]; !found {
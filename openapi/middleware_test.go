@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
 
 	"github.com/issue9/web"
 )
@@ -124,6 +125,29 @@ func TestOperation_Response(t *testing.T) {
 	a.Length(o.Responses, 2)
 }
 
+func TestOperation_Precondition(t *testing.T) {
+	a := assert.New(t, false)
+
+	o := newOperation(a)
+	o.Precondition(false)
+	a.Length(o.Headers, 2).
+		Equal(o.Headers[0].Name, header.IfMatch).
+		False(o.Headers[0].Required).
+		Equal(o.Headers[1].Name, header.IfUnmodifiedSince).
+		False(o.Headers[1].Required).
+		Length(o.Responses, 1)
+	resp := o.Responses["412"]
+	a.NotNil(resp).True(resp.Problem).NotNil(resp.Body).Nil(resp.Ref)
+
+	// 引用 WithProblemResponse 声明的对象
+	o = newOperation(a)
+	WithProblemResponse()(o.d)
+	o.Precondition(true)
+	a.Length(o.Responses, 2)
+	a.Equal(o.Responses["412"].Ref.Ref, problemResponseRef).
+		Equal(o.Responses["428"].Ref.Ref, problemResponseRef)
+}
+
 func TestOperation_Callback(t *testing.T) {
 	a := assert.New(t, false)
 	o := newOperation(a)
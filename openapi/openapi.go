@@ -94,6 +94,9 @@ const (
 // 文档中表示没有返回对象在 components/responses 中的引用值
 const EmptyResponseRef = "empty-response-ref"
 
+// 由 [WithProblemResponse] 声明的错误返回对象在 components/responses 中的引用值
+const problemResponseRef = "problem"
+
 type (
 	// Document openapi 文档
 	Document struct {
@@ -108,10 +111,11 @@ type (
 
 		// 以下是一些预定义的项，不存在于 openAPIRenderer。
 
-		mediaTypes    map[string]string // 所有接口都支持的类型，mimetype=>problem mimetype
-		responses     map[string]string // key 为状态码，比如 4XX，值为 components 中的键名
-		headers       []string          // components 中的键名
-		cookies       []string          // components 中的键名
+		mediaTypes    map[string]string   // 所有接口都支持的类型，mimetype=>problem mimetype
+		envelopes     map[string]Envelope // 媒体类型对内容的包装，键名为 mimetype
+		responses     map[string]string   // key 为状态码，比如 4XX，值为 components 中的键名
+		headers       []string            // components 中的键名
+		cookies       []string            // components 中的键名
 		enableOptions bool
 		enableHead    bool
 
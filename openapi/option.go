@@ -80,7 +80,7 @@ func WithResponse(resp *Response, status ...string) Option {
 // PresetOptions 提供 [web.Problem] 的 [Response] 对象并应用所有接口的 4XX 和 5XX 状态码
 func WithProblemResponse() Option {
 	return WithResponse(&Response{
-		Ref:         &Ref{Ref: "problem"},
+		Ref:         &Ref{Ref: problemResponseRef},
 		Body:        NewSchema(web.Problem{}, web.Phrase("problem response schema"), web.Phrase("problem response schema desc")),
 		Problem:     true,
 		Description: web.Phrase("problem response"),
@@ -111,6 +111,31 @@ func WithMediaType(t ...string) Option {
 	}
 }
 
+// Envelope 对媒体类型的内容进行包装
+//
+// s 为 [Response.Body] 或 [Request.Body] 的值；
+// problem 表示 s 是否为 [web.Problem] 的 [Schema]；
+// 返回值为包装之后的 [Schema]，需要注意的是返回值中的 [Schema.Ref] 并不会写入 components/schemas。
+type Envelope func(s *Schema, problem bool) *Schema
+
+// WithEnvelope 指定媒体类型对内容的包装方式
+//
+// HAL 和 JSON:API 等媒体类型会对输出的对象再包装一层，
+// 通过此选项可以让文档中该媒体类型的内容与实际输出保持一致。
+// 仅对 [Response.Body] 和 [Request.Body] 有效，[Response.Content] 和 [Request.Content] 会原样输出。
+//
+// mimetype 需要同时由 [WithMediaType] 指定才会有效果。
+//
+// NOTE: 多次调用会相互覆盖
+func WithEnvelope(mimetype string, f Envelope) Option {
+	return func(d *Document) {
+		if d.envelopes == nil {
+			d.envelopes = make(map[string]Envelope, 2)
+		}
+		d.envelopes[mimetype] = f
+	}
+}
+
 // WithHeader 向 components/headers 添加对象
 //
 // p 要求必须指定 Ref.Ref，其它接口可以通过 Ref 引用该对象。
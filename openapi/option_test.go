@@ -99,6 +99,43 @@ func TestWithMediaType(t *testing.T) {
 	}, "不支持 application/cbor 媒体类型")
 }
 
+func TestWithEnvelope(t *testing.T) {
+	a := assert.New(t, false)
+	ss := newServer(a)
+
+	d := New(ss, web.Phrase("desc"),
+		WithMediaType(json.Mimetype),
+		WithEnvelope(json.Mimetype, func(s *Schema, problem bool) *Schema {
+			if problem {
+				return &Schema{Type: TypeString}
+			}
+			return &Schema{Type: TypeObject, Properties: map[string]*Schema{"data": s}}
+		}),
+	)
+	a.Length(d.envelopes, 1)
+
+	body := &Schema{Type: TypeInteger}
+	resp := (&Response{Body: body}).buildRenderer(nil, d)
+	mt, found := resp.Content.Get(json.Mimetype)
+	a.True(found).
+		Equal(mt.Schema.obj.Type, TypeObject)
+	data, found := mt.Schema.obj.Properties.Get("data")
+	a.True(found).Equal(data.obj.Type, TypeInteger)
+
+	resp = (&Response{Body: body, Problem: true}).buildRenderer(nil, d)
+	mt, found = resp.Content.Get(json.ProblemMimetype)
+	a.True(found).Equal(mt.Schema.obj.Type, TypeString)
+
+	// Content 不受影响
+	resp = (&Response{Body: body, Content: map[string]*Schema{json.Mimetype: body}}).buildRenderer(nil, d)
+	mt, found = resp.Content.Get(json.Mimetype)
+	a.True(found).Equal(mt.Schema.obj.Type, TypeInteger)
+
+	req := (&Request{Body: body}).buildRenderer(nil, d)
+	mt, found = req.Content.Get(json.Mimetype)
+	a.True(found).Equal(mt.Schema.obj.Type, TypeObject)
+}
+
 func TestWithCallback(t *testing.T) {
 	a := assert.New(t, false)
 	ss := newServer(a)
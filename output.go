@@ -5,19 +5,30 @@
 package web
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/issue9/mux/v9/header"
 	"golang.org/x/text/transform"
 
+	"github.com/issue9/web/compressor"
 	"github.com/issue9/web/internal/qheader"
 )
 
+const noTransform = "no-transform"
+
+// 自动生成的 ETag 所采用的哈希值长度
+const autoETagSize = 16
+
 // Responser 向客户端输出对象需要实现的接口
 type Responser interface {
 	// Apply 通过 [Context] 将当前内容渲染到客户端
@@ -56,6 +67,8 @@ func (ctx *Context) Wrap(f func(http.ResponseWriter) http.ResponseWriter) {
 //
 // status 想输出给用户状态码，如果出错，那么最终展示给用户的状态码可能不是此值；
 // body 表示输出的对象，该对象最终调用 [Context.Marshal] 编码；
+//
+// 如果启用了 [Context.AutoETag]，还会根据编码之后的内容输出 ETag 报头。
 func (ctx *Context) Render(status int, body any) {
 	// NOTE: 此方法不返回错误代码，所有错误在方法内直接处理。输出对象时若出错，
 	// 状态码也已经输出，此时向调用方报告错误，除了输出错误日志，也没有其它面向客户的补救措施。
@@ -69,36 +82,161 @@ func (ctx *Context) Render(status int, body any) {
 		return
 	}
 
-	ctx.Header().Set(header.ContentType, qheader.BuildContentType(ctx.Mimetype(false), ctx.Charset()))
+	ctx.Header().Set(header.ContentType, qheader.BuildContentType(ctx.contentType(), ctx.Charset()))
 	if id := ctx.LanguageTag().String(); id != "" {
 		ctx.Header().Set(header.ContentLanguage, id)
 		ctx.Header().Add(header.Vary, header.AcceptLanguage)
 	}
 
-	data, err := ctx.Marshal(body)
-	if err != nil {
-		// [Problem.Apply] 并未调用 [Context.Render]，应该不会死循环。
-		var p *Problem
-		if errors.As(err, &p) {
-			p.Apply(ctx)
-		} else {
-			ctx.Error(err, ProblemNotAcceptable).Apply(ctx)
+	autoETag := ctx.autoETag && ctx.canAutoETag(status)
+	if ctx.outputMimetype.Encode != nil && !autoETag {
+		w := &statusWriter{ctx: ctx, status: status}
+		if err := ctx.Encode(w, body); err != nil {
+			if w.wrote { // 已经有内容输出，状态码无法再修改。
+				ctx.Logs().ERROR().Error(err)
+			} else {
+				ctx.renderError(err)
+			}
+			return
+		}
+
+		if !w.wrote { // 编码结果为空
+			ctx.WriteHeader(status)
 		}
 		return
 	}
 
+	var data []byte
+	var err error
+	if ctx.outputMimetype.Encode != nil { // 需要完整的内容才能计算 ETag
+		buf := &bytes.Buffer{}
+		err = ctx.Encode(buf, body)
+		data = buf.Bytes()
+	} else {
+		data, err = ctx.Marshal(body)
+	}
+	if err != nil {
+		ctx.renderError(err)
+		return
+	}
+
+	if autoETag && ctx.initAutoETag(status, data) {
+		return
+	}
+
 	ctx.WriteHeader(status)
 	if _, err = ctx.Write(data); err != nil {
 		ctx.Logs().ERROR().Error(err)
 	}
 }
 
+// 是否需要为状态码为 status 的输出内容生成 ETag
+func (ctx *Context) canAutoETag(status int) bool {
+	return status >= http.StatusOK && status < http.StatusMultipleChoices &&
+		isGetOrHead(ctx.Request()) &&
+		ctx.Header().Get(header.ETag) == "" // 用户已经指定了 ETag
+}
+
+// 根据输出内容生成 ETag
+//
+// 返回值表示是否已经输出 304 状态码。
+func (ctx *Context) initAutoETag(status int, data []byte) bool {
+	// 同一内容在不同的字符集和压缩算法下，输出的内容并不相同，
+	// 压缩算法以实际采用的为准，使用字典压缩时，不同的字典输出的内容也不相同。
+	h := sha256.New()
+	h.Write(data)
+	h.Write([]byte(ctx.Charset()))
+	h.Write([]byte{0})
+	if c := ctx.actualCompressor(status, len(data)); c != nil {
+		h.Write([]byte(c.Name()))
+		if _, ok := c.(compressor.DictionaryCompressor); ok && ctx.dictionary != nil {
+			h.Write(ctx.dictionary.Hash())
+		}
+	}
+	etag := `"` + base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:autoETagSize]) + `"`
+	ctx.Header().Set(header.ETag, etag)
+
+	if checkIfNoneMatch(ctx.Request(), etag) == condFalse {
+		ctx.Header().Del(header.ContentType)
+		ctx.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// SetAutoETag 是否由 [Context.Render] 自动生成 ETag
+//
+// 默认值由 [WithAutoETag] 指定，需要在调用 [Context.Render] 之前设置才有效果。
+func (ctx *Context) SetAutoETag(enable bool) { ctx.autoETag = enable }
+
+// AutoETag 是否由 [Context.Render] 自动生成 ETag
+func (ctx *Context) AutoETag() bool { return ctx.autoETag }
+
+func (ctx *Context) renderError(err error) {
+	// [Problem.Apply] 并未调用 [Context.Render]，应该不会死循环。
+	var p *Problem
+	if errors.As(err, &p) {
+		p.Apply(ctx)
+	} else {
+		ctx.Error(err, ProblemNotAcceptable).Apply(ctx)
+	}
+}
+
+// 在第一次写入内容时才输出状态码
+type statusWriter struct {
+	ctx    *Context
+	status int
+	wrote  bool
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if !w.wrote {
+		w.wrote = true
+		w.ctx.WriteHeader(w.status)
+	}
+	return w.ctx.Write(p)
+}
+
 // Marshal 将对象 v 按用户要求编码并返回
 func (ctx *Context) Marshal(v any) ([]byte, error) {
 	ctx.Header().Add(header.Vary, header.Accept)
 	return ctx.outputMimetype.Marshal(ctx, v)
 }
 
+// Encode 将对象 v 按用户要求编码并写入 w
+//
+// 如果当前媒体类型未指定 [EncodeFunc]，则采用 [MarshalFunc] 编码之后再写入 w。
+// w 一般为 [Context] 本身或是对其的包装，写入的内容会经过压缩和字符集的转换。
+func (ctx *Context) Encode(w io.Writer, v any) error {
+	if enc := ctx.outputMimetype.Encode; enc != nil {
+		ctx.Header().Add(header.Vary, header.Accept)
+		return enc(ctx, w, v)
+	}
+
+	data, err := ctx.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Marshaler 返回当前输出格式的编码方法
+//
+// 与 [Context.Marshal] 不同，不会修改报头，适用于连接被接管之后的编码，比如 websocket。
+func (ctx *Context) Marshaler() MarshalFunc { return ctx.outputMimetype.Marshal }
+
+// Unmarshaler 返回当前输出格式的解码方法
+//
+// 即根据 Accept 报头协商的输出格式对应的解码方法，而不是 Content-Type 指定的提交内容的解码方法，
+// 读取提交的内容应该采用 [Context.Unmarshal] 或 [Context.Read]。
+// 与 [Context.Marshaler] 相对应，仅适用于连接被接管之后，双方采用同一格式收发数据的场景，比如 websocket。
+func (ctx *Context) Unmarshaler() UnmarshalFunc { return ctx.outputMimetype.Unmarshal }
+
 // Wrote 是否已经有内容输出
 func (ctx *Context) Wrote() bool { return ctx.wrote }
 
@@ -115,46 +253,186 @@ func (ctx *Context) Write(bs []byte) (n int, err error) {
 		return 0, nil
 	}
 
-	if !ctx.Wrote() { // 在第一次有内容输出时，才决定构建 Compress 和 Charset 的 io.Writer
+	if ctx.recorder != nil {
+		ctx.recorder.record(bs)
+	}
+
+	if !ctx.Wrote() {
 		ctx.wrote = true
-		closes := make([]io.Closer, 0, 2)
 
-		if ctx.outputCompressor != nil {
-			ctx.Header().Add(header.Vary, header.AcceptEncoding)
-			w, err := ctx.outputCompressor.NewEncoder(ctx.writer)
-			if err != nil {
+		if ctx.status < http.StatusOK { // 1xx 可能还会改变状态码，比如 103
+			ctx.WriteHeader(http.StatusOK)
+		}
+
+		if ctx.compressMinSize == 0 {
+			if err := ctx.buildWriter(); err != nil {
 				return 0, err
 			}
-			ctx.writer = w
-			closes = append(closes, w)
 		}
+	}
+
+	if ctx.compressMinSize > 0 {
+		ctx.buffer = append(ctx.buffer, bs...)
+		if len(ctx.buffer) < ctx.compressMinSize {
+			return len(bs), nil
+		}
+		return len(bs), ctx.flushBuffer(true)
+	}
+
+	return ctx.writer.Write(bs)
+}
 
-		if !qheader.CharsetIsNop(ctx.outputCharset) {
-			ctx.Header().Add(header.Vary, header.AcceptCharset)
-			w := transform.NewWriter(ctx.writer, ctx.outputCharset.NewEncoder())
-			ctx.writer = w
-			closes = append(closes, w)
+// 在第一次有内容输出时，才构建 Compress 和 Charset 的 io.Writer
+func (ctx *Context) buildWriter() error {
+	closes := make([]io.Closer, 0, 2)
+
+	if ctx.outputCompressor != nil {
+		var w io.WriteCloser
+		var err error
+		if c, ok := ctx.outputCompressor.(compressor.DictionaryCompressor); ok && ctx.dictionary != nil {
+			w, err = c.NewDictionaryEncoder(ctx.writer, ctx.dictionary)
+		} else {
+			w, err = ctx.outputCompressor.NewEncoder(ctx.writer)
 		}
+		if err != nil {
+			return err
+		}
+		ctx.writer = w
+		ctx.compressWriter = w
+		closes = append(closes, w)
+	}
 
-		if l := len(closes); l > 0 {
-			if l > 1 {
-				slices.Reverse(closes)
-			}
+	if ctx.useAsDictionary != nil { // 字典是解压之后的内容
+		ctx.writer = io.MultiWriter(ctx.writer, (*dictionaryWriter)(ctx))
+	}
 
-			ctx.OnExit(func(*Context, int) {
-				for _, c := range closes {
-					if err := c.Close(); err != nil {
-						ctx.Logs().ERROR().Error(err)
-					}
+	if !qheader.CharsetIsNop(ctx.outputCharset) {
+		ctx.Header().Add(header.Vary, header.AcceptCharset)
+		w := transform.NewWriter(ctx.writer, ctx.outputCharset.NewEncoder())
+		ctx.writer = w
+		closes = append(closes, w)
+	}
+
+	if l := len(closes); l > 0 {
+		if l > 1 {
+			slices.Reverse(closes)
+		}
+
+		ctx.OnExit(func(*Context, int) {
+			for _, c := range closes {
+				if err := c.Close(); err != nil {
+					ctx.Logs().ERROR().Error(err)
 				}
-			})
+			}
+		})
+	}
+
+	if ctx.useAsDictionary != nil { // 在 closes 之后执行，保证内容已经完整。
+		ctx.OnExit(func(ctx *Context, _ int) { ctx.storeDictionary() })
+	}
+
+	return nil
+}
+
+// 根据输出的状态码和报头决定是否压缩
+//
+// 返回值表示是否需要缓存内容，等内容达到一定大小之后再决定。
+func (ctx *Context) initCompress(status int) bool {
+	ctx.Header().Add(header.Vary, header.AcceptEncoding)
+	if ctx.dictionary != nil {
+		ctx.Header().Add(header.Vary, availableDictionaryHeader)
+	}
+
+	c := ctx.outputCompression(status)
+	switch {
+	case c == nil:
+		ctx.outputCompressor = nil
+		return false
+	case c.minSize > 0:
+		ctx.compressMinSize = c.minSize
+		return true
+	default:
+		ctx.Header().Set(header.ContentEncoding, c.compressor.Name())
+		return false
+	}
+}
+
+// 查找适用于当前输出内容的压缩设置，返回 nil 表示不需要压缩。
+func (ctx *Context) outputCompression(status int) *compression {
+	if ctx.Header().Get(header.ContentEncoding) != "" { // 内容已经由用户压缩
+		return nil
+	}
+
+	if status == http.StatusNoContent || status == http.StatusNotModified ||
+		qheader.HasDirective(ctx.Header().Values(header.CacheControl), noTransform) ||
+		qheader.HasDirective(ctx.Request().Header.Values(header.CacheControl), noTransform) {
+		return nil
+	}
+
+	// 输出的是协商的媒体类型，采用注册时的名称进行匹配。
+	typ, _, _ := strings.Cut(ctx.Header().Get(header.ContentType), ";")
+	if typ = strings.TrimSpace(typ); typ == "" || typ == ctx.acceptName || typ == ctx.Mimetype(true) {
+		typ = ctx.Mimetype(false)
+	}
+
+	return ctx.config.codec.matchCompression(ctx.outputCompressor, typ)
+}
+
+// 输出 size 大小的内容时实际采用的压缩算法，返回 nil 表示不压缩。
+func (ctx *Context) actualCompressor(status, size int) compressor.Compressor {
+	if ctx.outputCompressor == nil {
+		return nil
+	}
+
+	if c := ctx.outputCompression(status); c != nil && size >= c.minSize {
+		return c.compressor
+	}
+	return nil
+}
+
+// 输出缓存的内容
+//
+// compress 表示是否对内容进行压缩。
+func (ctx *Context) flushBuffer(compress bool) error {
+	ctx.compressMinSize = 0
+	if compress {
+		ctx.Header().Set(header.ContentEncoding, ctx.outputCompressor.Name())
+	} else {
+		ctx.outputCompressor = nil
+	}
+	ctx.originResponse.WriteHeader(ctx.status)
+
+	if !ctx.Wrote() {
+		return nil
+	}
+
+	if err := ctx.buildWriter(); err != nil {
+		return err
+	}
+	_, err := ctx.writer.Write(ctx.buffer)
+	ctx.buffer = ctx.buffer[:0]
+	return err
+}
+
+// FlushError 将缓存的内容输出到客户端
+//
+// 会先刷新压缩算法中缓存的内容，再刷新底层的 [http.ResponseWriter]。
+// 此方法主要供 [http.ResponseController.Flush] 调用。
+//
+// 如果内容还未达到 [CompressionOptions.MinSize]，会被当作是流式的输出而直接进行压缩。
+func (ctx *Context) FlushError() error {
+	if ctx.compressMinSize > 0 {
+		if err := ctx.flushBuffer(true); err != nil {
+			return err
 		}
 	}
 
-	if ctx.status < http.StatusOK { // 1xx 可能还会改变状态码，比如 103
-		ctx.WriteHeader(http.StatusOK)
+	if f, ok := ctx.compressWriter.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
 	}
-	return ctx.writer.Write(bs)
+	return http.NewResponseController(ctx.originResponse).Flush()
 }
 
 // WriteHeader 向客户端输出 HTTP 状态码
@@ -165,8 +443,24 @@ func (ctx *Context) WriteHeader(status int) {
 		panic(fmt.Sprintf("已有状态码 %d，再次设置无效 %d", ctx.status, status))
 	}
 
+	if ctx.compressMinSize > 0 { // 已经在等待内容达到 minSize
+		return
+	}
+
 	ctx.Header().Del(header.ContentLength) // https://github.com/golang/go/issues/14975
+	if ctx.contentLength >= 0 && ctx.outputCompressor == nil && qheader.CharsetIsNop(ctx.outputCharset) {
+		ctx.Header().Set(header.ContentLength, strconv.FormatInt(ctx.contentLength, 10))
+	}
+	first := ctx.status < http.StatusOK
 	ctx.status = status
+
+	if first && status >= http.StatusOK && ctx.useAsDictionary != nil {
+		ctx.initUseAsDictionary(status)
+	}
+
+	if first && status >= http.StatusOK && ctx.outputCompressor != nil && ctx.initCompress(status) {
+		return // 等待内容达到 minSize 之后再输出状态码
+	}
 	ctx.originResponse.WriteHeader(status)
 }
 
@@ -210,7 +504,7 @@ func NotModified(etag func() (string, bool), body func() (any, error)) Responser
 		}
 
 		if data, ok := b.([]byte); ok {
-			ctx.Header().Set(header.ContentType, qheader.BuildContentType(ctx.Mimetype(false), ctx.Charset()))
+			ctx.Header().Set(header.ContentType, qheader.BuildContentType(ctx.contentType(), ctx.Charset()))
 			if id := ctx.LanguageTag().String(); id != "" {
 				ctx.Header().Set(header.ContentLanguage, id)
 			}
@@ -283,3 +577,95 @@ func Redirect(status int, url string) Responser {
 func KeepAlive(ctx context.Context) Responser {
 	return ResponserFunc(func(*Context) { <-ctx.Done() })
 }
+
+// 记录输出的状态码、报头和内容
+type recordWriter struct {
+	http.ResponseWriter
+	status  int
+	header  http.Header
+	body    bytes.Buffer
+	size    int  // 记录内容的最大长度
+	raw     bool // 记录的是压缩和字符集转换之前的内容，由 [Context.Write] 写入。
+	discard bool // 不再记录内容，比如内容超过了 size。
+
+	// 根据状态码和报头判断是否需要记录内容，为空表示始终记录。
+	accept func(status int, h http.Header) bool
+}
+
+// 记录 ctx 的输出内容
+//
+// size 为记录内容的最大长度，超过此值之后不再记录；
+// raw 表示记录压缩和字符集转换之前的内容，否则记录实际输出的内容；
+func (ctx *Context) record(size int, raw bool) *recordWriter {
+	rw := &recordWriter{size: size, raw: raw}
+	ctx.Wrap(func(w http.ResponseWriter) http.ResponseWriter {
+		rw.ResponseWriter = w
+		return rw
+	})
+	if raw {
+		ctx.recorder = rw
+	}
+	return rw
+}
+
+func (w *recordWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= http.StatusOK {
+		w.status = status
+		w.header = w.Header().Clone()
+		w.discard = w.accept != nil && !w.accept(status, w.header)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	if w.status == 0 { // 未调用 WriteHeader
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.raw {
+		w.record(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *recordWriter) record(p []byte) {
+	if w.discard {
+		return
+	}
+
+	if w.body.Len()+len(p) > w.size {
+		w.discard = true
+		w.body = bytes.Buffer{}
+		return
+	}
+	w.body.Write(p)
+}
+
+// 是否完整地记录了内容
+func (w *recordWriter) recorded() bool { return w.status > 0 && !w.discard }
+
+func (w *recordWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// 输出由 [recordWriter] 记录的内容
+//
+// raw 表示记录的是压缩和字符集转换之前的内容，会按当前请求重新压缩和转换字符集，
+// 否则记录的是实际输出的内容，原样输出。
+func (ctx *Context) writeRecorded(status int, h http.Header, body []byte, raw bool) {
+	dst := ctx.Header()
+	for k, v := range h {
+		dst[k] = slices.Clone(v)
+	}
+	dst.Set(ctx.s.requestIDKey, ctx.ID()) // 保留当前请求的 ID
+
+	ctx.useAsDictionary = nil
+	if !raw {
+		ctx.outputCompressor = nil
+		ctx.outputCharset = nil
+	} else if typ, charset := qheader.ParseWithParam(dst.Get(header.ContentType), "charset"); charset != "" {
+		dst.Set(header.ContentType, qheader.BuildContentType(typ, ctx.Charset()))
+	}
+
+	ctx.WriteHeader(status)
+	if _, err := ctx.Write(body); err != nil {
+		ctx.Logs().ERROR().Error(err)
+	}
+}
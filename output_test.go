@@ -7,10 +7,12 @@ package web
 import (
 	"bytes"
 	"compress/flate"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/issue9/assert/v4"
@@ -18,6 +20,7 @@ import (
 	"github.com/issue9/mux/v9/types"
 	"golang.org/x/text/language"
 
+	"github.com/issue9/web/compressor"
 	"github.com/issue9/web/internal/qheader"
 )
 
@@ -145,6 +148,240 @@ func TestContext_Render(t *testing.T) {
 	a.Equal(w.Result().StatusCode, http.StatusNotAcceptable)
 }
 
+func TestContext_Render_encode(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/p1", nil)
+	r.Header.Set(header.Accept, "application/stream")
+	ctx := srv.NewContext(w, r, types.NewContext())
+	a.NotNil(ctx)
+	ctx.Render(http.StatusCreated, objectInst)
+	srv.freeContext(ctx)
+	a.Equal(w.Result().StatusCode, http.StatusCreated).
+		Equal(w.Header().Get(header.ContentType), qheader.BuildContentType("application/stream", header.UTF8)).
+		Equal(w.Body.String(), objectJSONString+"\n")
+
+	// 压缩和字符集
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/p1", nil)
+	r.Header.Set(header.Accept, "application/stream")
+	r.Header.Set(header.AcceptCharset, "gbk")
+	r.Header.Set(header.AcceptEncoding, "deflate")
+	ctx = srv.NewContext(w, r, types.NewContext())
+	a.NotNil(ctx)
+	ctx.Render(http.StatusCreated, objectInst)
+	srv.freeContext(ctx)
+	a.Equal(w.Result().StatusCode, http.StatusCreated).
+		Equal(w.Header().Get(header.ContentEncoding), "deflate")
+	data, err := io.ReadAll(flate.NewReader(w.Body))
+	a.NotError(err).Equal(data, append(objectGBKBytes, '\n'))
+
+	// 在输出内容之前出错，可以改变状态码。
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/p1", nil)
+	r.Header.Set(header.Accept, "application/stream")
+	ctx = srv.NewContext(w, r, types.NewContext())
+	a.NotNil(ctx)
+	ctx.Render(http.StatusCreated, make(chan int))
+	srv.freeContext(ctx)
+	a.Equal(w.Result().StatusCode, http.StatusNotAcceptable).
+		Equal(w.Header().Get(header.ContentType), qheader.BuildContentType("application/problem+stream", header.UTF8))
+
+	// problem
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/p1", nil)
+	r.Header.Set(header.Accept, "application/stream")
+	ctx = srv.NewContext(w, r, types.NewContext())
+	a.NotNil(ctx)
+	ctx.apply(ctx.Problem(ProblemBadRequest))
+	srv.freeContext(ctx)
+	a.Equal(w.Result().StatusCode, http.StatusBadRequest)
+	p := &Problem{}
+	a.NotError(json.Unmarshal(w.Body.Bytes(), p)).
+		Equal(p.Status, http.StatusBadRequest).
+		Equal(p.Type, ProblemBadRequest)
+}
+
+func TestContext_FlushError(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/p1", nil)
+	r.Header.Set(header.Accept, header.JSON)
+	r.Header.Set(header.AcceptEncoding, "deflate")
+	ctx := srv.NewContext(w, r, types.NewContext())
+	a.NotNil(ctx)
+	_, err := ctx.Write([]byte("123"))
+	a.NotError(err)
+	a.NotError(http.NewResponseController(ctx).Flush()).
+		True(w.Flushed)
+
+	// 未关闭压缩对象，也能读取已经刷新的内容。
+	data := make([]byte, 3)
+	_, err = io.ReadFull(flate.NewReader(bytes.NewReader(w.Body.Bytes())), data)
+	a.NotError(err).Equal(string(data), "123")
+	srv.freeContext(ctx)
+}
+
+func TestContext_compress(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+
+	c := NewCodec().
+		AddCompressorWithOptions(compressor.NewDeflate(flate.DefaultCompression, nil), &CompressionOptions{
+			Exclude: []string{"image/*"},
+			MinSize: 100,
+		}).
+		AddMimetype(header.JSON, marshalJSON, unmarshalJSON, "application/problem+json", true, true)
+	router := srv.Routers().New("compress", nil, WithCodec(c))
+
+	large := strings.Repeat("1", 200)
+	router.Get("/small", func(ctx *Context) Responser { return OK("small") })
+	router.Get("/large", func(ctx *Context) Responser { return OK(large) })
+	router.Get("/image", func(ctx *Context) Responser {
+		return ResponserFunc(func(ctx *Context) {
+			ctx.Header().Set(header.ContentType, "image/png")
+			_, err := ctx.Write([]byte(large))
+			a.NotError(err)
+		})
+	})
+	router.Get("/no-transform", func(ctx *Context) Responser {
+		ctx.Header().Set(header.CacheControl, "public, no-transform")
+		return OK(large)
+	})
+	router.Get("/flush", func(ctx *Context) Responser {
+		return ResponserFunc(func(ctx *Context) {
+			_, err := ctx.Write([]byte("123"))
+			a.NotError(err).
+				Empty(ctx.Header().Get(header.ContentEncoding)).
+				NotError(http.NewResponseController(ctx).Flush())
+		})
+	})
+
+	get := func(path string, h ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(header.Accept, header.JSON)
+		r.Header.Set(header.AcceptEncoding, "deflate")
+		for i := 0; i < len(h); i += 2 {
+			r.Header.Set(h[i], h[i+1])
+		}
+		router.ServeHTTP(w, r)
+		a.Contains(w.Header().Values(header.Vary), header.AcceptEncoding)
+		return w
+	}
+
+	w := get("/small")
+	a.Equal(w.Code, http.StatusOK).
+		Empty(w.Header().Get(header.ContentEncoding)).
+		Equal(w.Body.String(), `"small"`)
+
+	w = get("/large")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(header.ContentEncoding), "deflate")
+	data, err := io.ReadAll(flate.NewReader(w.Body))
+	a.NotError(err).Equal(string(data), `"`+large+`"`)
+
+	w = get("/image")
+	a.Equal(w.Code, http.StatusOK).
+		Empty(w.Header().Get(header.ContentEncoding)).
+		Equal(w.Body.String(), large)
+
+	w = get("/no-transform")
+	a.Equal(w.Code, http.StatusOK).
+		Empty(w.Header().Get(header.ContentEncoding)).
+		Equal(w.Body.String(), `"`+large+`"`)
+
+	w = get("/large", header.CacheControl, "no-transform")
+	a.Equal(w.Code, http.StatusOK).
+		Empty(w.Header().Get(header.ContentEncoding))
+
+	w = get("/flush")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(header.ContentEncoding), "deflate")
+	data, err = io.ReadAll(flate.NewReader(w.Body))
+	a.NotError(err).Equal(string(data), "123")
+}
+
+func TestContext_Render_autoETag(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+
+	c := NewCodec().
+		AddCompressorWithOptions(compressor.NewDeflate(flate.DefaultCompression, nil), &CompressionOptions{MinSize: 100}).
+		AddMimetype(header.JSON, marshalJSON, unmarshalJSON, "application/problem+json", true, true, encodeJSON)
+	router := srv.Routers().New("etag", nil, WithCodec(c), WithAutoETag(true))
+
+	large := strings.Repeat("1", 200)
+	router.Get("/small", func(ctx *Context) Responser { return OK("small") })
+	router.Get("/large", func(ctx *Context) Responser { return OK(large) })
+
+	get := func(path string, h ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(header.Accept, header.JSON)
+		for i := 0; i < len(h); i += 2 {
+			r.Header.Set(h[i], h[i+1])
+		}
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// 以 EncodeFunc 输出的内容也会生成 ETag
+	w := get("/small")
+	etag := w.Header().Get(header.ETag)
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Body.String(), `"small"`+"\n").
+		NotEmpty(etag)
+
+	w = get("/small", header.IfNoneMatch, etag)
+	a.Equal(w.Code, http.StatusNotModified).Empty(w.Body.String())
+
+	// 未达到 MinSize，实际未压缩，ETag 与未压缩的相同。
+	w = get("/small", header.AcceptEncoding, "deflate")
+	a.Equal(w.Code, http.StatusOK).
+		Empty(w.Header().Get(header.ContentEncoding)).
+		Equal(w.Header().Get(header.ETag), etag)
+
+	w = get("/large")
+	etag = w.Header().Get(header.ETag)
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Body.String(), `"`+large+`"`+"\n").
+		NotEmpty(etag)
+
+	w = get("/large", header.AcceptEncoding, "deflate")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get(header.ContentEncoding), "deflate").
+		NotEmpty(w.Header().Get(header.ETag)).
+		NotEqual(w.Header().Get(header.ETag), etag)
+	data, err := io.ReadAll(flate.NewReader(w.Body))
+	a.NotError(err).Equal(string(data), `"`+large+`"`+"\n")
+
+	w = get("/large", header.AcceptEncoding, "deflate", header.IfNoneMatch, etag)
+	a.Equal(w.Code, http.StatusOK)
+}
+
+func TestContext_Marshaler(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/p1", nil)
+	r.Header.Set(header.Accept, header.JSON)
+	ctx := srv.NewContext(w, r, types.NewContext())
+	a.NotNil(ctx)
+
+	data, err := ctx.Marshaler()(ctx, map[string]int{"a": 1})
+	a.NotError(err).Equal(string(data), `{"a":1}`).Empty(ctx.Header().Get(header.Vary))
+
+	v := map[string]int{}
+	a.NotError(ctx.Unmarshaler()(bytes.NewReader(data), &v)).Equal(v, map[string]int{"a": 1})
+	srv.freeContext(ctx)
+}
+
 func TestContext_Wrap(t *testing.T) {
 	a := assert.New(t, false)
 	s := newTestServer(a)
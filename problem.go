@@ -5,11 +5,14 @@
 package web
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"iter"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/issue9/localeutil"
@@ -88,6 +91,31 @@ func newProblem() *Problem {
 // MarshalHTML 实现 [mimetype/html.Marshaler] 接口
 func (p *Problem) MarshalHTML() (string, any) { return "problem", p }
 
+// MarshalCSV 实现 [mimetype/csv.Marshaler] 接口
+//
+// 输出为只有一行数据的表格，params 中的每一项占一行，以 name: reason 的形式表示，
+// extensions 以 JSON 的形式表示。
+func (p *Problem) MarshalCSV() ([][]string, error) {
+	params := make([]string, 0, len(p.Params))
+	for _, param := range p.Params {
+		params = append(params, param.Name+": "+param.Reason)
+	}
+
+	var ext string
+	if p.Extensions != nil {
+		data, err := json.Marshal(p.Extensions)
+		if err != nil {
+			return nil, err
+		}
+		ext = string(data)
+	}
+
+	return [][]string{
+		{"type", "title", "detail", "instance", "status", "params", "extensions"},
+		{p.Type, p.Title, p.Detail, p.Instance, strconv.Itoa(p.Status), strings.Join(params, "\n"), ext},
+	}, nil
+}
+
 func (p *Problem) Error() string { return p.Title }
 
 func (p *Problem) Apply(ctx *Context) {
@@ -98,14 +126,10 @@ func (p *Problem) Apply(ctx *Context) {
 
 	ctx.WriteHeader(p.Status) // Problem 先输出状态码
 
-	data, err := ctx.Marshal(p)
-	if err != nil {
+	if err := ctx.Encode(ctx, p); err != nil {
 		ctx.Logs().ERROR().Error(err)
 		return
 	}
-	if _, err = ctx.Write(data); err != nil {
-		ctx.Logs().ERROR().Error(err)
-	}
 
 	if len(p.Params) < 30 {
 		problemPool.Put(p)
@@ -143,7 +167,7 @@ func (p *Problem) WithInstance(instance string) *Problem {
 func (ctx *Context) Problem(id string) *Problem { return ctx.initProblem(newProblem(), id) }
 
 func (ctx *Context) initProblem(pp *Problem, id string) *Problem {
-	ctx.Server().Problems().initProblem(pp, id, ctx.LocalePrinter())
+	ctx.Server().Problems().initProblem(pp, id, ctx.config.problemPrefix, ctx.LocalePrinter())
 	return pp.WithInstance(ctx.ID())
 }
 
@@ -221,11 +245,7 @@ func (ps *Problems) Add(s int, p ...*LocaleProblem) *Problems {
 			panic("detail 不能为空")
 		}
 
-		if ps.Prefix() == ProblemAboutBlank {
-			pp.typ = ProblemAboutBlank
-		} else {
-			pp.typ = ps.Prefix() + pp.ID
-		}
+		pp.typ = problemType(ps.Prefix(), pp.ID)
 
 		pp.status = s
 
@@ -251,10 +271,22 @@ func (ps *Problems) Problems() iter.Seq2[int, *LocaleProblem] {
 	}
 }
 
-func (ps *Problems) initProblem(pp *Problem, id string, p *localeutil.Printer) {
+func problemType(prefix, id string) string {
+	if prefix == ProblemAboutBlank {
+		return ProblemAboutBlank
+	}
+	return prefix + id
+}
+
+// prefix 为路由指定的前缀，与 [Problems.Prefix] 不同时，会重新生成 [Problem.Type]。
+func (ps *Problems) initProblem(pp *Problem, id, prefix string, p *localeutil.Printer) {
 	if i := slices.IndexFunc(ps.problems, func(p *LocaleProblem) bool { return p.ID == id }); i > -1 {
 		sp := ps.problems[i]
-		pp.Type = sp.Type()
+		if prefix == ps.Prefix() {
+			pp.Type = sp.Type()
+		} else {
+			pp.Type = problemType(prefix, sp.ID)
+		}
 		pp.Title = sp.Title.LocaleString(p)
 		pp.Detail = sp.Detail.LocaleString(p)
 		pp.Status = sp.status
@@ -144,14 +144,20 @@ func TestProblems_initProblem(t *testing.T) {
 	a.NotNil(ps)
 	ps.Add(400, &LocaleProblem{ID: "40010", Title: Phrase("title"), Detail: Phrase("detail")})
 	pp := &Problem{}
-	ps.initProblem(pp, "40010", p)
+	ps.initProblem(pp, "40010", "", p)
 	a.Equal(pp.Type, "40010")
 
+	// 路由指定了不同的前缀
+	ps.initProblem(pp, "40010", "https://example.com/admin#", p)
+	a.Equal(pp.Type, "https://example.com/admin#40010")
+	ps.initProblem(pp, "40010", ProblemAboutBlank, p)
+	a.Equal(pp.Type, ProblemAboutBlank)
+
 	ps = newProblems("https://example.com/qa#")
 	a.NotNil(ps)
 	ps.Add(400, &LocaleProblem{ID: "40011", Title: Phrase("title"), Detail: Phrase("detail")})
 	pp = &Problem{}
-	ps.initProblem(pp, "40011", p)
+	ps.initProblem(pp, "40011", ps.Prefix(), p)
 	a.Equal(pp.Type, "https://example.com/qa#40011").
 		Equal(ps.Prefix(), "https://example.com/qa#")
 
@@ -159,10 +165,10 @@ func TestProblems_initProblem(t *testing.T) {
 	a.NotNil(ps)
 	ps.Add(400, &LocaleProblem{ID: "40012", Title: Phrase("title"), Detail: Phrase("detail")})
 	pp = &Problem{}
-	ps.initProblem(pp, "40012", p)
+	ps.initProblem(pp, "40012", ps.Prefix(), p)
 	a.Equal(pp.Type, ProblemAboutBlank)
 
 	a.PanicString(func() {
-		ps.initProblem(pp, "not-exists", p)
+		ps.initProblem(pp, "not-exists", ps.Prefix(), p)
 	}, "未找到有关 not-exists 的定义")
 }
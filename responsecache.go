@@ -0,0 +1,452 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/cache"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/mux/v9/types"
+
+	"github.com/issue9/web/internal/qheader"
+)
+
+const (
+	responseCachePrefix     = "web-response-cache:"
+	responseCacheVarySuffix = ":vary"
+	responseCacheLockSuffix = ":lock"
+	responseCacheTagPrefix  = "tag:"
+)
+
+// 始终参与键名计算的请求报头
+//
+// 这些报头只有在请求中存在时才会被添加到响应的 Vary 报头中。
+var responseCacheImplicitVary = []string{header.AcceptCharset, header.AcceptEncoding, availableDictionaryHeader}
+
+// 以流的形式输出的媒体类型，不作缓存。
+var responseCacheStreamTypes = []string{header.EventStream, multipartByteranges}
+
+type (
+	// ResponseCache 对 GET 和 HEAD 请求的响应内容进行缓存
+	//
+	// 缓存保存在 [Server.Cache] 之中，多个实例之间可以共享。
+	// 缓存项以请求方法、地址以及响应的 Vary 报头中列出的请求报头作为键名，
+	// 由于 Accept-Charset 和 Accept-Encoding 等报头仅在客户端提供时才会出现在 Vary 之中，
+	// 这些报头也始终参与键名的计算。
+	//
+	// 遵守处理函数和客户端的 Cache-Control 报头：
+	//   - 响应包含 no-store、no-cache 或 private 时不缓存，有效期依次取 s-maxage、max-age 和默认值；
+	//   - 响应包含 stale-while-revalidate 时，在过期之后的指定时间内依然返回缓存内容，同时在后台更新缓存；
+	//   - 请求包含 no-store 时不使用缓存，包含 no-cache 时不读取缓存但是会更新缓存，包含 max-age 时不返回超过该时间的缓存；
+	//
+	// 以下响应不会被缓存：
+	//   - 包含 Set-Cookie 或是 Vary: * 的响应；
+	//   - 206 以及 text/event-stream 等以流的形式输出的响应；
+	//   - 内容超过指定大小的响应；
+	//
+	// 包含 Authorization 报头的请求，只有响应指定了 public 或 s-maxage 才会被缓存。
+	//
+	// 由 [NewResponseCache] 声明，作为中间件应用于路由项：
+	//
+	//	c := web.NewResponseCache(s, time.Minute, 1<<20)
+	//	router.Get("/articles/{id}", handler, c)
+	//
+	// 可以通过 [ResponseCache.Tag] 为缓存项指定标签，并通过 [ResponseCache.Invalidate] 使其失效。
+	//
+	// NOTE: 保存的是实际输出的内容，包括压缩之后的内容和相关报头，返回时原样输出。
+	ResponseCache struct {
+		cache Cache
+		ttl   time.Duration
+		size  int
+	}
+
+	// 保存在缓存中的响应内容
+	responseCacheEntry struct {
+		Status  int
+		Header  http.Header
+		Body    []byte
+		Created time.Time
+		MaxAge  time.Duration
+		Stale   time.Duration     // stale-while-revalidate 的值
+		Tags    map[string]uint64 // 标签及其在保存时的版本号
+	}
+
+	// 单次请求的状态
+	responseCacheState struct {
+		key  string // 未包含 Vary 的键名
+		w    *recordWriter
+		tags map[string]uint64 // 标签及其在调用 Tag 时的版本号
+		err  bool              // 获取标签的版本号时出错，不再缓存。
+	}
+
+	// 后台更新缓存时使用的 [http.ResponseWriter]
+	discardResponseWriter struct {
+		header http.Header
+	}
+)
+
+// NewResponseCache 声明 [ResponseCache] 对象
+//
+// ttl 为响应未通过 Cache-Control 指定有效期时采用的默认值，0 表示仅缓存指定了有效期的响应；
+// size 为可缓存的响应内容的最大长度，超过此值的响应不会被缓存；
+func NewResponseCache(s Server, ttl time.Duration, size int) *ResponseCache {
+	if ttl < 0 {
+		panic("参数 ttl 不能小于 0")
+	}
+	if size <= 0 {
+		panic("参数 size 必须大于 0")
+	}
+
+	c := &ResponseCache{
+		cache: NewCache(responseCachePrefix, s.Cache()),
+		ttl:   ttl,
+		size:  size,
+	}
+	s.OnExitContext(c.exit) // 此时所有的内容都已经输出，包括压缩算法缓存的内容。
+	return c
+}
+
+// Middleware 实现 [Middleware] 接口
+//
+// 仅对 GET 和 HEAD 请求有效。
+func (c *ResponseCache) Middleware(next HandlerFunc, method, _, router string) HandlerFunc {
+	if method != http.MethodGet && method != http.MethodHead {
+		return next
+	}
+
+	return func(ctx *Context) Responser {
+		r := ctx.Request()
+		cc := r.Header.Values(header.CacheControl)
+		if qheader.HasDirective(cc, "no-store") {
+			return next(ctx)
+		}
+
+		key := responseCacheKey(router, r)
+		if !qheader.HasDirective(cc, "no-cache") {
+			e, variant, err := c.load(key, r)
+			if err != nil {
+				ctx.Logs().ERROR().Error(err)
+			} else if e != nil {
+				age := ctx.Begin().Sub(e.Created)
+				if maxAge, ok := qheader.DirectiveSeconds(cc, "max-age"); !ok || age <= maxAge {
+					switch {
+					case age <= e.MaxAge:
+						return e.replay(age)
+					case age <= e.MaxAge+e.Stale:
+						c.revalidate(ctx, next, key, variant, e.Stale)
+						return e.replay(age)
+					}
+				}
+			}
+		}
+
+		c.record(ctx, key)
+		return next(ctx)
+	}
+}
+
+// Tag 为当前请求的响应内容指定标签
+//
+// 之后可以通过 [ResponseCache.Invalidate] 使拥有该标签的缓存项失效。
+// 仅在经过 [ResponseCache.Middleware] 且需要更新缓存的请求中才有效果。
+//
+// 标签的版本号在调用此方法时获取，所以应该在读取数据之前调用，
+// 如果在请求结束之前标签已经失效，响应内容不会被缓存。
+func (c *ResponseCache) Tag(ctx *Context, tag ...string) {
+	v, found := ctx.GetVar(c)
+	if !found {
+		return
+	}
+
+	state := v.(*responseCacheState)
+	if state.tags == nil {
+		state.tags = make(map[string]uint64, len(tag))
+	}
+	for _, t := range tag {
+		if _, found := state.tags[t]; found {
+			continue
+		}
+
+		n, err := c.tagVersion(t)
+		if err != nil {
+			ctx.Logs().ERROR().Error(err)
+			state.err = true
+			return
+		}
+		state.tags[t] = n
+	}
+}
+
+// Invalidate 使拥有指定标签的缓存项失效
+func (c *ResponseCache) Invalidate(tag ...string) error {
+	for _, t := range tag {
+		_, inc, _, err := c.cache.Counter(responseCacheTagPrefix+t, cache.Forever)
+		if err != nil {
+			return err
+		}
+		if _, err = inc(1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ResponseCache) tagVersion(tag string) (uint64, error) {
+	n, _, _, err := c.cache.Counter(responseCacheTagPrefix+tag, cache.Forever)
+	return n, err
+}
+
+// 加载与 r 相匹配的缓存项
+//
+// variant 为缓存项的键名，不存在时返回 nil。
+func (c *ResponseCache) load(key string, r *http.Request) (e *responseCacheEntry, variant string, err error) {
+	var vary string
+	if err = c.cache.Get(key+responseCacheVarySuffix, &vary); err != nil {
+		if errors.Is(err, cache.ErrCacheMiss()) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+
+	variant = responseCacheVariant(key, splitVary(vary), r)
+	e = &responseCacheEntry{}
+	if err = c.cache.Get(variant, e); err != nil {
+		if errors.Is(err, cache.ErrCacheMiss()) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+
+	for tag, ver := range e.Tags {
+		n, err := c.tagVersion(tag)
+		if err != nil {
+			return nil, "", err
+		}
+		if n != ver { // 标签已经失效
+			return nil, "", nil
+		}
+	}
+
+	return e, variant, nil
+}
+
+// 记录 ctx 的输出内容，在退出时保存至缓存。
+func (c *ResponseCache) record(ctx *Context, key string) {
+	state := &responseCacheState{key: key, w: ctx.record(c.size, false)}
+	state.w.accept = isCacheableResponse
+	ctx.SetVar(c, state)
+}
+
+// 在后台更新缓存
+//
+// 同一缓存项同时只会有一个更新操作，timeout 为更新操作的最长时间。
+func (c *ResponseCache) revalidate(ctx *Context, next HandlerFunc, key, variant string, timeout time.Duration) {
+	_, inc, _, err := c.cache.Counter(variant+responseCacheLockSuffix, timeout)
+	if err != nil {
+		ctx.Logs().ERROR().Error(err)
+		return
+	}
+	if n, err := inc(1); err != nil {
+		ctx.Logs().ERROR().Error(err)
+		return
+	} else if n > 1 { // 已经在更新
+		return
+	}
+
+	s := ctx.s
+	route := cloneRoute(ctx.Route()) // ctx.Route() 会在请求结束后被回收
+	r := ctx.Request().Clone(context.WithoutCancel(ctx.Request().Context()))
+	for _, h := range []string{s.requestIDKey, header.IfNoneMatch, header.IfModifiedSince, header.IfMatch, header.IfUnmodifiedSince, header.IfRange} {
+		r.Header.Del(h)
+	}
+
+	go func() {
+		defer func() {
+			if msg := recover(); msg != nil {
+				s.Logs().ERROR().Error(fmt.Errorf("%v", msg))
+			}
+			if err := c.cache.Delete(variant + responseCacheLockSuffix); err != nil {
+				s.Logs().ERROR().Error(err)
+			}
+		}()
+
+		rctx := s.NewContext(&discardResponseWriter{header: http.Header{}}, r, route)
+		if rctx == nil {
+			return
+		}
+		c.record(rctx, key)
+		if resp := next(rctx); resp != nil {
+			resp.Apply(rctx)
+		}
+		s.freeContext(rctx)
+	}()
+}
+
+func (c *ResponseCache) exit(ctx *Context, _ int) {
+	v, found := ctx.GetVar(c)
+	if !found {
+		return
+	}
+	state := v.(*responseCacheState)
+	w := state.w
+
+	if state.err || !w.recorded() || w.header.Get(header.SetCookie) != "" {
+		return
+	}
+
+	cc := w.header.Values(header.CacheControl)
+	if qheader.HasDirective(cc, "no-store") || qheader.HasDirective(cc, "no-cache") || qheader.HasDirective(cc, "private") {
+		return
+	}
+
+	maxAge, shared := qheader.DirectiveSeconds(cc, "s-maxage")
+	if !shared {
+		var ok bool
+		if maxAge, ok = qheader.DirectiveSeconds(cc, "max-age"); !ok {
+			maxAge = c.ttl
+		}
+	}
+	if maxAge <= 0 {
+		return
+	}
+
+	r := ctx.Request()
+	if r.Header.Get(header.Authorization) != "" && !shared && !qheader.HasDirective(cc, "public") {
+		return
+	}
+
+	vary := qheader.ParseList(w.header.Values(header.Vary))
+	if slices.Contains(vary, "*") {
+		return
+	}
+
+	e := &responseCacheEntry{
+		Status:  w.status,
+		Header:  w.header,
+		Body:    w.body.Bytes(),
+		Created: ctx.Begin(),
+		MaxAge:  maxAge,
+	}
+	e.Stale, _ = qheader.DirectiveSeconds(cc, "stale-while-revalidate")
+
+	for tag, ver := range state.tags {
+		n, err := c.tagVersion(tag)
+		if err != nil {
+			ctx.Logs().ERROR().Error(err)
+			return
+		}
+		if n != ver { // 处理请求期间标签已经失效，内容可能已经过时。
+			return
+		}
+	}
+	e.Tags = state.tags
+
+	ttl := e.MaxAge + e.Stale
+	if err := c.cache.Set(state.key+responseCacheVarySuffix, strings.Join(vary, ","), ttl); err != nil {
+		ctx.Logs().ERROR().Error(err)
+		return
+	}
+	if err := c.cache.Set(responseCacheVariant(state.key, vary, r), e, ttl); err != nil {
+		ctx.Logs().ERROR().Error(err)
+	}
+}
+
+// 输出缓存的内容
+//
+// age 为缓存项已经存在的时间。
+func (e *responseCacheEntry) replay(age time.Duration) Responser {
+	return ResponserFunc(func(ctx *Context) {
+		ctx.Header().Set(header.Age, strconv.Itoa(int(age.Seconds())))
+
+		var modtime time.Time
+		if lm := e.Header.Get(header.LastModified); lm != "" {
+			modtime, _ = http.ParseTime(lm)
+		}
+		if checkPreconditions(ctx.Request(), e.Header.Get(header.ETag), modtime) == http.StatusNotModified {
+			h := e.Header.Clone()
+			h.Del(header.ContentType)
+			ctx.writeRecorded(http.StatusNotModified, h, nil, false)
+			return
+		}
+
+		ctx.writeRecorded(e.Status, e.Header, e.Body, false)
+	})
+}
+
+// 根据状态码和报头判断响应是否可被缓存
+func isCacheableResponse(status int, h http.Header) bool {
+	if !isCacheableStatus(status) {
+		return false
+	}
+
+	typ, _, _ := strings.Cut(h.Get(header.ContentType), ";")
+	return !slices.Contains(responseCacheStreamTypes, strings.ToLower(strings.TrimSpace(typ)))
+}
+
+// 可被缓存的状态码
+//
+// 参考 RFC9110 的 15.1 节。
+func isCacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusPermanentRedirect,
+		http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusGone,
+		http.StatusRequestURITooLong, http.StatusNotImplemented:
+		return true
+	default:
+		return false
+	}
+}
+
+// 生成未包含 Vary 的键名
+func responseCacheKey(router string, r *http.Request) string {
+	return idempotencyHash(router, r.Method, r.Host, r.URL.RequestURI())
+}
+
+// 根据 Vary 报头生成缓存项的键名
+func responseCacheVariant(key string, vary []string, r *http.Request) string {
+	v := make([]string, 0, (len(vary)+len(responseCacheImplicitVary))*2+1)
+	v = append(v, key)
+	for _, name := range responseCacheImplicitVary {
+		v = append(v, name, strings.Join(r.Header.Values(name), ","))
+	}
+	for _, name := range vary {
+		if name = http.CanonicalHeaderKey(name); !slices.Contains(responseCacheImplicitVary, name) {
+			v = append(v, name, strings.Join(r.Header.Values(name), ","))
+		}
+	}
+	return idempotencyHash(v...)
+}
+
+func cloneRoute(r types.Route) types.Route {
+	route := types.NewContext()
+	route.SetNode(r.Node())
+	route.SetRouterName(r.RouterName())
+	if ps, ok := r.Params().(interface{ Range(func(string, string)) }); ok {
+		ps.Range(route.Set)
+	}
+	return route
+}
+
+func splitVary(vary string) []string {
+	if vary == "" {
+		return nil
+	}
+	return strings.Split(vary, ",")
+}
+
+func (w *discardResponseWriter) Header() http.Header { return w.header }
+
+func (w *discardResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+func (w *discardResponseWriter) WriteHeader(int) {}
@@ -0,0 +1,314 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+)
+
+var _ Middleware = &ResponseCache{}
+
+func TestNewResponseCache(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+
+	a.PanicString(func() {
+		NewResponseCache(srv, -1, 1024)
+	}, "参数 ttl 不能小于 0")
+
+	a.PanicString(func() {
+		NewResponseCache(srv, 0, 0)
+	}, "参数 size 必须大于 0")
+
+	a.NotNil(NewResponseCache(srv, 0, 1024))
+}
+
+func TestResponseCache(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+	c := NewResponseCache(srv, time.Minute, 1024)
+
+	var count atomic.Int64
+	var cacheControl string
+	router := srv.Routers().New("response-cache", nil, WithAutoETag(true))
+	router.Get("/articles", func(ctx *Context) Responser {
+		n := count.Add(1)
+		c.Tag(ctx, "articles")
+		if cacheControl != "" {
+			ctx.Header().Set(header.CacheControl, cacheControl)
+		}
+		return OK(&object{Name: "articles", Age: int(n)})
+	}, c)
+	router.Get("/cookie", func(ctx *Context) Responser {
+		count.Add(1)
+		ctx.SetCookies(&http.Cookie{Name: "c", Value: "v"})
+		return OK("cookie")
+	}, c)
+	router.Post("/articles", func(ctx *Context) Responser {
+		count.Add(1)
+		return NoContent()
+	}, c)
+	router.Get("/large", func(ctx *Context) Responser {
+		count.Add(1)
+		return OK(strings.Repeat("1", 1024))
+	}, c)
+	router.Get("/events", func(ctx *Context) Responser {
+		count.Add(1)
+		return ResponserFunc(func(ctx *Context) {
+			ctx.Header().Set(header.ContentType, header.EventStream)
+			_, err := ctx.Write([]byte("data: 1\n\n"))
+			a.NotError(err)
+		})
+	}, c)
+	router.Get("/invalidated", func(ctx *Context) Responser {
+		n := count.Add(1)
+		c.Tag(ctx, "invalidated")
+		a.NotError(c.Invalidate("invalidated")) // 读取数据之后，输出之前失效。
+		return OK(int(n))
+	}, c)
+	router.Get("/vary", func(ctx *Context) Responser {
+		n := count.Add(1)
+		ctx.Header().Set(header.Vary, "accept-encoding, x-custom")
+		return OK(int(n))
+	}, c)
+
+	serve := func(method, path string, h ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, nil)
+		r.Header.Set(header.Accept, header.JSON)
+		for j := 0; j < len(h); j += 2 {
+			r.Header.Set(h[j], h[j+1])
+		}
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// 命中缓存
+
+	w := serve(http.MethodGet, "/articles")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(count.Load(), 1).
+		Equal(w.Body.String(), `{"name":"articles","Age":1}`).
+		Empty(w.Header().Get(header.Age))
+	id := w.Header().Get(header.XRequestID)
+
+	w = serve(http.MethodGet, "/articles")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(count.Load(), 1).
+		Equal(w.Body.String(), `{"name":"articles","Age":1}`).
+		Equal(w.Header().Get(header.ContentType), "application/json; charset=utf-8").
+		Equal(w.Header().Get(header.Age), "0").
+		NotEqual(w.Header().Get(header.XRequestID), id)
+
+	// 查询参数不同
+	w = serve(http.MethodGet, "/articles?page=2")
+	a.Equal(w.Code, http.StatusOK).Equal(count.Load(), 2)
+
+	// Vary: Accept-Encoding
+
+	w = serve(http.MethodGet, "/articles", header.AcceptEncoding, "gzip")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(count.Load(), 3).
+		Equal(w.Header().Get(header.ContentEncoding), "gzip")
+	body := w.Body.Bytes()
+
+	w = serve(http.MethodGet, "/articles", header.AcceptEncoding, "gzip")
+	a.Equal(w.Code, http.StatusOK).
+		Equal(count.Load(), 3).
+		Equal(w.Header().Get(header.ContentEncoding), "gzip").
+		Equal(w.Body.Bytes(), body)
+
+	// Vary: Accept
+	w = serve(http.MethodGet, "/articles", header.Accept, header.XML)
+	a.Equal(w.Code, http.StatusOK).Equal(count.Load(), 4)
+
+	// 客户端的 Cache-Control
+
+	w = serve(http.MethodGet, "/articles", header.CacheControl, "no-store")
+	a.Equal(w.Code, http.StatusOK).Equal(count.Load(), 5).
+		Equal(w.Body.String(), `{"name":"articles","Age":5}`)
+	w = serve(http.MethodGet, "/articles")
+	a.Equal(w.Body.String(), `{"name":"articles","Age":1}`).Equal(count.Load(), 5)
+
+	w = serve(http.MethodGet, "/articles", header.CacheControl, "no-cache") // 更新缓存
+	a.Equal(w.Code, http.StatusOK).Equal(count.Load(), 6)
+	w = serve(http.MethodGet, "/articles")
+	a.Equal(w.Body.String(), `{"name":"articles","Age":6}`).Equal(count.Load(), 6)
+
+	// If-None-Match
+
+	w = serve(http.MethodGet, "/articles?etag=1")
+	a.Equal(w.Code, http.StatusOK).Equal(count.Load(), 7)
+	etag := w.Header().Get(header.ETag)
+	a.NotEmpty(etag)
+	w = serve(http.MethodGet, "/articles?etag=1", header.IfNoneMatch, etag)
+	a.Equal(w.Code, http.StatusNotModified).
+		Equal(count.Load(), 7).
+		Empty(w.Body.String()).
+		Equal(w.Header().Get(header.ETag), etag)
+
+	// 标签失效
+
+	a.NotError(c.Invalidate("articles"))
+	w = serve(http.MethodGet, "/articles")
+	a.Equal(w.Body.String(), `{"name":"articles","Age":8}`).Equal(count.Load(), 8)
+	w = serve(http.MethodGet, "/articles")
+	a.Equal(w.Body.String(), `{"name":"articles","Age":8}`).Equal(count.Load(), 8)
+
+	// Authorization
+
+	w = serve(http.MethodGet, "/articles?auth=1", header.Authorization, "Bearer t")
+	a.Equal(w.Code, http.StatusOK).Equal(count.Load(), 9)
+	w = serve(http.MethodGet, "/articles?auth=1", header.Authorization, "Bearer t")
+	a.Equal(w.Code, http.StatusOK).Equal(count.Load(), 10)
+
+	cacheControl = "public"
+	w = serve(http.MethodGet, "/articles?auth=2", header.Authorization, "Bearer t")
+	a.Equal(w.Code, http.StatusOK).Equal(count.Load(), 11)
+	w = serve(http.MethodGet, "/articles?auth=2", header.Authorization, "Bearer t")
+	a.Equal(w.Code, http.StatusOK).Equal(count.Load(), 11)
+
+	// 处理函数的 Cache-Control
+
+	for _, cc := range []string{"no-store", "private", "no-cache", "max-age=0"} {
+		cacheControl = cc
+		n := count.Load()
+		serve(http.MethodGet, "/articles?cc="+cc)
+		serve(http.MethodGet, "/articles?cc="+cc)
+		a.Equal(count.Load(), n+2, cc)
+	}
+
+	// Set-Cookie 不缓存
+
+	n := count.Load()
+	serve(http.MethodGet, "/cookie")
+	serve(http.MethodGet, "/cookie")
+	a.Equal(count.Load(), n+2)
+
+	// POST 不作处理
+
+	n = count.Load()
+	w = serve(http.MethodPost, "/articles")
+	a.Equal(w.Code, http.StatusNoContent)
+	serve(http.MethodPost, "/articles")
+	a.Equal(count.Load(), n+2)
+
+	// 超过大小的内容不缓存
+
+	n = count.Load()
+	w = serve(http.MethodGet, "/large")
+	a.Equal(w.Code, http.StatusOK).Length(w.Body.String(), 1026)
+	serve(http.MethodGet, "/large")
+	a.Equal(count.Load(), n+2)
+
+	// 流不缓存
+
+	n = count.Load()
+	w = serve(http.MethodGet, "/events")
+	a.Equal(w.Code, http.StatusOK).Equal(w.Body.String(), "data: 1\n\n")
+	serve(http.MethodGet, "/events")
+	a.Equal(count.Load(), n+2)
+
+	// 处理期间标签失效
+
+	n = count.Load()
+	serve(http.MethodGet, "/invalidated")
+	serve(http.MethodGet, "/invalidated")
+	a.Equal(count.Load(), n+2)
+
+	// 小写的 Vary
+
+	n = count.Load()
+	serve(http.MethodGet, "/vary", "X-Custom", "1")
+	serve(http.MethodGet, "/vary", "X-Custom", "1")
+	a.Equal(count.Load(), n+1)
+	serve(http.MethodGet, "/vary", "X-Custom", "2")
+	a.Equal(count.Load(), n+2)
+
+	w = serve(http.MethodGet, "/vary", "X-Custom", "1", header.AcceptEncoding, "gzip")
+	a.Equal(count.Load(), n+3).Equal(w.Header().Get(header.ContentEncoding), "gzip")
+	w = serve(http.MethodGet, "/vary", "X-Custom", "1", header.AcceptEncoding, "gzip")
+	a.Equal(count.Load(), n+3).Equal(w.Header().Get(header.ContentEncoding), "gzip")
+	w = serve(http.MethodGet, "/vary", "X-Custom", "1")
+	a.Equal(count.Load(), n+3).Empty(w.Header().Get(header.ContentEncoding))
+}
+
+func TestResponseCache_expired(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+	c := NewResponseCache(srv, 0, 1024)
+
+	var count atomic.Int64
+	router := srv.Routers().New("response-cache", nil)
+	router.Get("/max-age", func(ctx *Context) Responser {
+		n := count.Add(1)
+		ctx.Header().Set(header.CacheControl, "max-age=1")
+		return OK(int(n))
+	}, c)
+	router.Get("/stale", func(ctx *Context) Responser {
+		n := count.Add(1)
+		ctx.Header().Set(header.CacheControl, "max-age=1, stale-while-revalidate=10")
+		return OK(int(n))
+	}, c)
+	router.Get("/default", func(ctx *Context) Responser {
+		count.Add(1)
+		return OK("default")
+	}, c)
+
+	serve := func(path string, h ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(header.Accept, header.JSON)
+		for j := 0; j < len(h); j += 2 {
+			r.Header.Set(h[j], h[j+1])
+		}
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// ttl 为 0，未指定有效期的响应不缓存。
+	serve("/default")
+	serve("/default")
+	a.Equal(count.Load(), 2)
+
+	// max-age
+
+	w := serve("/max-age")
+	a.Equal(w.Body.String(), "3").Equal(count.Load(), 3)
+	w = serve("/max-age")
+	a.Equal(w.Body.String(), "3").Equal(count.Load(), 3)
+
+	// 客户端的 max-age
+	time.Sleep(1100 * time.Millisecond)
+	w = serve("/max-age", header.CacheControl, "max-age=0")
+	a.Equal(w.Body.String(), "4").Equal(count.Load(), 4)
+
+	time.Sleep(2100 * time.Millisecond)
+	w = serve("/max-age")
+	a.Equal(w.Body.String(), "5").Equal(count.Load(), 5)
+
+	// stale-while-revalidate
+
+	w = serve("/stale")
+	a.Equal(w.Body.String(), "6").Equal(count.Load(), 6)
+
+	time.Sleep(2100 * time.Millisecond)
+	w = serve("/stale") // 返回过期的内容，同时在后台更新。
+	a.Equal(w.Body.String(), "6").
+		Equal(w.Header().Get(header.Age), "2")
+
+	a.Wait(500 * time.Millisecond)
+	a.Equal(count.Load(), 7)
+	w = serve("/stale")
+	a.Equal(w.Body.String(), "7").Equal(count.Load(), 7)
+}
@@ -7,12 +7,15 @@ package web
 import (
 	"errors"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/issue9/mux/v9"
 	"github.com/issue9/mux/v9/header"
 	"github.com/issue9/mux/v9/types"
 	"github.com/issue9/source"
+	"github.com/jellydator/ttlcache/v3"
+	"github.com/puzpuzpuz/xsync/v4"
 
 	"github.com/issue9/web/internal/errs"
 )
@@ -23,7 +26,6 @@ type (
 	Resource          = mux.Resource[HandlerFunc]
 	RouterMatcher     = mux.Matcher
 	RouterMatcherFunc = mux.MatcherFunc
-	RouterOption      = mux.Option
 	MiddlewareFunc    = types.MiddlewareFunc[HandlerFunc]
 	Middleware        = types.Middleware[HandlerFunc]
 
@@ -36,12 +38,70 @@ type (
 	// 返回值可以为空，表示在中间件执行过程中已经向客户端输出同内容。
 	HandlerFunc func(*Context) Responser
 
+	// RouterOption 路由的设置项
+	RouterOption func(*routerOptions)
+
+	routerOptions struct {
+		mux           []mux.Option
+		codec         *Codec
+		compressors   []string
+		hasCompressor bool // compressors 为空也是有效值，表示禁用压缩
+		problemPrefix *string
+		bodyLimit     *BodyLimit
+		autoETag      *bool
+	}
+
+	// 路由级别的设置
+	//
+	// 所有由 [RouterOption] 覆盖的设置项都保存在此，未覆盖的项与 [InternalServer] 相同。
+	routerConfig struct {
+		codec         *Codec
+		negotiations  *ttlcache.Cache[negotiationKey, *negotiation]
+		problemPrefix string
+		bodyLimit     *BodyLimit
+		autoETag      bool
+	}
+
 	// Routers 提供管理路由的接口
 	Routers struct {
-		g *mux.Group[HandlerFunc]
+		s       *InternalServer
+		g       *mux.Group[HandlerFunc]
+		options []RouterOption // 对所有路由都有效的选项
+		configs *xsync.Map[string, *routerConfig]
 	}
 )
 
+func newRouters(s *InternalServer, o ...RouterOption) *Routers {
+	r := &Routers{
+		s:       s,
+		options: o,
+		configs: xsync.NewMap[string, *routerConfig](),
+	}
+
+	r.g = mux.NewGroup(s.call,
+		notFound,
+		buildNodeHandle(http.StatusMethodNotAllowed),
+		buildNodeHandle(http.StatusOK),
+		buildRouterOptions(o...).mux...)
+	return r
+}
+
+func buildRouterOptions(o ...RouterOption) *routerOptions {
+	opt := &routerOptions{}
+	for _, f := range o {
+		f(opt)
+	}
+	return opt
+}
+
+func newRouterConfig(c *Codec, problemPrefix string) *routerConfig {
+	return &routerConfig{
+		codec:         c,
+		negotiations:  newNegotiationCache(),
+		problemPrefix: problemPrefix,
+	}
+}
+
 func notFound(ctx *Context) Responser { return ctx.NotFound() }
 
 func buildNodeHandle(status int) types.BuildNodeHandler[HandlerFunc] {
@@ -51,8 +111,8 @@ func buildNodeHandle(status int) types.BuildNodeHandler[HandlerFunc] {
 
 			if ctx.Request().Method == http.MethodOptions { // OPTIONS 200
 				return ResponserFunc(func(ctx *Context) {
-					ctx.Header().Set(header.AcceptEncoding, ctx.s.codec.acceptEncodingHeader)
-					ctx.Header().Set(header.Accept, ctx.s.codec.serverAcceptHeader)
+					ctx.Header().Set(header.AcceptEncoding, ctx.config.codec.acceptEncodingHeader)
+					ctx.Header().Set(header.Accept, ctx.config.codec.serverAcceptHeader)
 					ctx.Header().Set(header.AcceptLanguage, ctx.s.locale.AcceptLanguage())
 					ctx.WriteHeader(http.StatusOK)
 				})
@@ -83,14 +143,53 @@ func (s *InternalServer) Routers() *Routers { return s.routers }
 func (r *Routers) Get(name string) *Router { return r.g.Router(name) }
 
 // New 声明新路由
+//
+// 可以通过 [WithCodec]、[WithCompressors]、[WithProblemPrefix]、[WithBodyLimit] 和 [WithAutoETag]
+// 为该路由指定与 [Server] 不同的设置。
 func (r *Routers) New(name string, matcher RouterMatcher, o ...RouterOption) *Router {
-	return r.g.New(name, matcher, o...)
+	opt := buildRouterOptions(o...)
+	router := r.g.New(name, matcher, opt.mux...) // 所有路由都有效的 mux.Option 已经由 mux.Group 处理
+
+	if opt = buildRouterOptions(slices.Concat(r.options, o)...); opt.codec != nil || opt.hasCompressor || opt.problemPrefix != nil || opt.bodyLimit != nil || opt.autoETag != nil {
+		c := r.s.codec
+		if opt.codec != nil {
+			c = opt.codec
+		}
+		if opt.hasCompressor {
+			c = c.withCompressors(opt.compressors...)
+		}
+
+		prefix := r.s.Problems().Prefix()
+		if opt.problemPrefix != nil {
+			prefix = *opt.problemPrefix
+		}
+
+		conf := newRouterConfig(c, prefix)
+		conf.bodyLimit = opt.bodyLimit
+		conf.autoETag = opt.autoETag != nil && *opt.autoETag
+		r.configs.Store(name, conf)
+	}
+
+	return router
 }
 
 // Remove 删除指定名称的路由
 //
 // name 指由 [Routers.New] 的 name 参数指定的值；
-func (r *Routers) Remove(name string) { r.g.Remove(name) }
+func (r *Routers) Remove(name string) {
+	r.g.Remove(name)
+	r.configs.Delete(name)
+}
+
+// 返回 route 所在路由的设置，如果未覆盖任何设置，返回的是 [InternalServer] 的设置。
+func (r *Routers) config(route types.Route) *routerConfig {
+	if route != nil {
+		if c, found := r.configs.Load(route.RouterName()); found {
+			return c
+		}
+	}
+	return r.s.config
+}
 
 // Routers 返回所有的路由
 func (r *Routers) Routers() []*Router { return r.g.Routers() }
@@ -98,11 +197,65 @@ func (r *Routers) Routers() []*Router { return r.g.Routers() }
 // Use 对所有的路由使用中间件
 func (r *Routers) Use(m ...Middleware) { r.g.Use(m...) }
 
+func withMux(o mux.Option) RouterOption { return WithMuxOptions(o) }
+
+// WithMuxOptions 直接使用 [mux.Option] 作为路由的设置项
+//
+// 用于指定未由当前包包装的设置项，比如 [mux.WithLock] 和 [mux.WithStatusRecovery] 等。
+func WithMuxOptions(o ...mux.Option) RouterOption {
+	return func(opt *routerOptions) { opt.mux = append(opt.mux, o...) }
+}
+
+// WithCodec 为路由指定编码方式
+//
+// 该路由下的请求将采用 c 进行内容协商，而不是 [Server] 的 [Codec]。
+func WithCodec(c *Codec) RouterOption {
+	if c == nil {
+		panic("参数 c 不能为空")
+	}
+	return func(opt *routerOptions) { opt.codec = c }
+}
+
+// WithCompressors 指定路由可用的压缩算法
+//
+// name 为压缩算法的名称，必须是 [Codec] 中已经添加的算法，为空表示该路由禁用压缩。
+func WithCompressors(name ...string) RouterOption {
+	return func(opt *routerOptions) {
+		opt.compressors = name
+		opt.hasCompressor = true
+	}
+}
+
+// WithProblemPrefix 为路由的 [Problem.Type] 指定不同的前缀
+//
+// 与 [Problems.Prefix] 的作用相同，仅对当前路由有效。
+func WithProblemPrefix(prefix string) RouterOption {
+	return func(opt *routerOptions) { opt.problemPrefix = &prefix }
+}
+
+// WithBodyLimit 限制路由中客户端提交内容的大小
+//
+// 单个路由项可以将 [BodyLimit] 作为中间件覆盖此设置。
+func WithBodyLimit(l *BodyLimit) RouterOption {
+	return func(opt *routerOptions) { opt.bodyLimit = l }
+}
+
+// WithAutoETag 是否为路由中由 [Context.Render] 输出的内容自动生成 ETag
+//
+// 启用之后，GET 和 HEAD 请求的 2xx 响应会根据编码之后的内容生成强验证的 ETag，
+// 如果与 If-None-Match 报头相匹配，则返回 304 且不输出内容。
+// 单个路由项可通过 [Context.SetAutoETag] 覆盖此设置。
+//
+// NOTE: 需要生成 ETag 时，即使媒体类型指定了 [EncodeFunc]，也会在编码完整个内容之后才输出。
+func WithAutoETag(enable bool) RouterOption {
+	return func(opt *routerOptions) { opt.autoETag = &enable }
+}
+
 // WithRecovery 在路由奔溃之后的处理方式
 //
 // 相对于 [mux.WithRecovery]，提供了对 [NewError] 错误的处理。
 func WithRecovery(status int, l *Logger) RouterOption {
-	return mux.WithRecovery(func(w http.ResponseWriter, msg any) {
+	return withMux(mux.WithRecovery(func(w http.ResponseWriter, msg any) {
 		err, ok := msg.(error)
 		if !ok {
 			http.Error(w, http.StatusText(status), status)
@@ -117,41 +270,41 @@ func WithRecovery(status int, l *Logger) RouterOption {
 		}
 		http.Error(w, http.StatusText(he.Status), he.Status)
 		l.String(source.Stack(4, true, he.Message))
-	})
+	}))
 }
 
 // WithCORS 自定义跨域请求设置项
 //
 // 具体参数可参考 [mux.WithCORS]。
 func WithCORS(origin, allowHeaders, exposedHeaders []string, maxAge int, allowCredentials bool) RouterOption {
-	return mux.WithCORS(origin, allowHeaders, exposedHeaders, maxAge, allowCredentials)
+	return withMux(mux.WithCORS(origin, allowHeaders, exposedHeaders, maxAge, allowCredentials))
 }
 
 // WithDenyCORS 禁用跨域请求
-func WithDenyCORS() RouterOption { return mux.WithDenyCORS() }
+func WithDenyCORS() RouterOption { return withMux(mux.WithDenyCORS()) }
 
 // WithAllowedCORS 允许跨域请求
-func WithAllowedCORS(maxAge int) RouterOption { return mux.WithAllowedCORS(maxAge) }
+func WithAllowedCORS(maxAge int) RouterOption { return withMux(mux.WithAllowedCORS(maxAge)) }
 
 // WithURLDomain 为 [Router.URL] 生成的地址带上域名
-func WithURLDomain(prefix string) RouterOption { return mux.WithURLDomain(prefix) }
+func WithURLDomain(prefix string) RouterOption { return withMux(mux.WithURLDomain(prefix)) }
 
 // WithTrace 控制 TRACE 请求是否有效
 //
 // body 表示是否显示 body 内容；
 func WithTrace(body bool) RouterOption {
-	return mux.WithTrace(func(ctx *Context) Responser {
+	return withMux(mux.WithTrace(func(ctx *Context) Responser {
 		mux.Trace(ctx, ctx.Request(), body)
 		return nil
-	})
+	}))
 }
 
-func WithAnyInterceptor(rule string) RouterOption { return mux.WithAnyInterceptor(rule) }
+func WithAnyInterceptor(rule string) RouterOption { return withMux(mux.WithAnyInterceptor(rule)) }
 
-func WithDigitInterceptor(rule string) RouterOption { return mux.WithDigitInterceptor(rule) }
+func WithDigitInterceptor(rule string) RouterOption { return withMux(mux.WithDigitInterceptor(rule)) }
 
-func WithWordInterceptor(rule string) RouterOption { return mux.WithWordInterceptor(rule) }
+func WithWordInterceptor(rule string) RouterOption { return withMux(mux.WithWordInterceptor(rule)) }
 
 func WithInterceptor(f mux.InterceptorFunc, rule ...string) RouterOption {
-	return mux.WithInterceptor(f, rule...)
+	return withMux(mux.WithInterceptor(f, rule...))
 }
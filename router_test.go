@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
 )
 
 func TestRouters(t *testing.T) {
@@ -70,19 +71,164 @@ func TestRouters_Handle(t *testing.T) {
 	r = httptest.NewRequest(http.MethodGet, "/panic-http-error", nil)
 	router.ServeHTTP(w, r)
 	a.Equal(w.Result().StatusCode, http.StatusConflict).
-		Contains(s.logBuf.String(), "router_test.go:44")
+		Contains(s.logBuf.String(), "router_test.go:45")
 
 	s.logBuf.Reset()
 	w = httptest.NewRecorder()
 	r = httptest.NewRequest(http.MethodGet, "/panic-error", nil)
 	router.ServeHTTP(w, r)
 	a.Equal(w.Result().StatusCode, http.StatusInternalServerError).
-		Contains(s.logBuf.String(), "router_test.go:47")
+		Contains(s.logBuf.String(), "router_test.go:48")
 
 	s.logBuf.Reset()
 	w = httptest.NewRecorder()
 	r = httptest.NewRequest(http.MethodGet, "/panic-string", nil)
 	router.ServeHTTP(w, r)
 	a.Equal(w.Result().StatusCode, http.StatusInternalServerError).
-		Contains(s.logBuf.String(), "router_test.go:50")
+		Contains(s.logBuf.String(), "router_test.go:51")
+}
+
+func TestRouters_config(t *testing.T) {
+	a := assert.New(t, false)
+	s := newTestServer(a)
+	rs := s.Routers()
+
+	def := rs.New("def", nil)
+	a.Equal(rs.config(nil), s.config)
+
+	c := NewCodec().
+		AddCompressor(newCodec(a).compressions[1].compressor).
+		AddMimetype(header.XML, marshalXML, unmarshalXML, "application/problem+xml", true, true)
+	admin := rs.New("admin", nil, WithCodec(c), WithProblemPrefix("https://example.com/admin#"))
+	api := rs.New("api", nil, WithCompressors())
+	a.PanicString(func() {
+		rs.New("panic", nil, WithCompressors("not-exists"))
+	}, "未找到名为 not-exists 的压缩算法")
+
+	handle := func(ctx *Context) Responser { return ctx.NotFound() }
+	def.Get("/path", handle)
+	admin.Get("/path", handle)
+	api.Get("/path", handle)
+
+	// def 采用服务端的设置
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set(header.Accept, header.JSON)
+	r.Header.Set(header.AcceptEncoding, "gzip")
+	def.ServeHTTP(w, r)
+	a.Equal(w.Result().StatusCode, http.StatusNotFound).
+		Equal(w.Header().Get(header.ContentEncoding), "gzip")
+
+	// admin 不支持 JSON
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, r)
+	a.Equal(w.Result().StatusCode, http.StatusNotAcceptable)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set(header.Accept, header.XML)
+	r.Header.Set(header.AcceptEncoding, "gzip,deflate")
+	admin.ServeHTTP(w, r)
+	a.Equal(w.Result().StatusCode, http.StatusNotFound).
+		Equal(w.Header().Get(header.ContentEncoding), "deflate")
+
+	w = httptest.NewRecorder()
+	r.Header.Del(header.AcceptEncoding)
+	admin.ServeHTTP(w, r)
+	a.Equal(w.Result().StatusCode, http.StatusNotFound).
+		Contains(w.Body.String(), "https://example.com/admin#404")
+
+	// api 禁用了压缩
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set(header.Accept, header.JSON)
+	r.Header.Set(header.AcceptEncoding, "gzip")
+	api.ServeHTTP(w, r)
+	a.Equal(w.Result().StatusCode, http.StatusNotFound).
+		Empty(w.Header().Get(header.ContentEncoding)).
+		Contains(w.Body.String(), `"type":"404"`)
+
+	rs.Remove("admin")
+	_, found := rs.configs.Load("admin")
+	a.False(found)
+}
+
+func TestRouters_autoETag(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+	rs := srv.Routers()
+
+	def := rs.New("def", nil)
+	def.Get("/path", func(*Context) Responser { return OK("body") })
+
+	router := rs.New("etag", nil, WithAutoETag(true))
+	router.Get("/path", func(*Context) Responser { return OK("body") })
+	router.Post("/path", func(*Context) Responser { return OK("body") })
+	router.Get("/disable", func(ctx *Context) Responser {
+		ctx.SetAutoETag(false)
+		return OK("body")
+	})
+	router.Get("/custom", func(ctx *Context) Responser {
+		ctx.Header().Set(header.ETag, `"custom"`)
+		return OK("body")
+	})
+	router.Get("/created", func(*Context) Responser { return Created("body", "") })
+	router.Get("/not-found", func(ctx *Context) Responser { return ctx.NotFound() })
+
+	serve := func(r *Router, method, path string, h ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(header.Accept, header.JSON)
+		for i := 0; i < len(h); i += 2 {
+			req.Header.Set(h[i], h[i+1])
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(def, http.MethodGet, "/path")
+	a.Equal(w.Code, http.StatusOK).Empty(w.Header().Get(header.ETag))
+
+	w = serve(router, http.MethodGet, "/path")
+	etag := w.Header().Get(header.ETag)
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Body.String(), `"body"`).
+		Length(etag, 24). // 16 字节的哈希值经过 base64 编码之后为 22 个字符，再加上双引号。
+		Equal(etag[0], byte('"'))
+
+	w = serve(router, http.MethodHead, "/path")
+	a.Equal(w.Code, http.StatusOK).Equal(w.Header().Get(header.ETag), etag)
+
+	w = serve(router, http.MethodGet, "/path", header.IfNoneMatch, etag)
+	a.Equal(w.Code, http.StatusNotModified).
+		Empty(w.Body.String()).
+		Empty(w.Header().Get(header.ContentType)).
+		Equal(w.Header().Get(header.ETag), etag)
+
+	w = serve(router, http.MethodGet, "/path", header.IfNoneMatch, `W/`+etag)
+	a.Equal(w.Code, http.StatusNotModified)
+
+	w = serve(router, http.MethodGet, "/path", header.IfNoneMatch, `"other"`)
+	a.Equal(w.Code, http.StatusOK).Equal(w.Body.String(), `"body"`)
+
+	// 不同的压缩算法生成不同的 ETag
+	w = serve(router, http.MethodGet, "/path", header.AcceptEncoding, "gzip")
+	a.Equal(w.Code, http.StatusOK).
+		NotEmpty(w.Header().Get(header.ETag)).
+		NotEqual(w.Header().Get(header.ETag), etag)
+
+	w = serve(router, http.MethodPost, "/path")
+	a.Equal(w.Code, http.StatusOK).Empty(w.Header().Get(header.ETag))
+
+	w = serve(router, http.MethodGet, "/disable")
+	a.Equal(w.Code, http.StatusOK).Empty(w.Header().Get(header.ETag))
+
+	w = serve(router, http.MethodGet, "/custom", header.IfNoneMatch, etag)
+	a.Equal(w.Code, http.StatusOK).Equal(w.Header().Get(header.ETag), `"custom"`)
+
+	w = serve(router, http.MethodGet, "/created")
+	a.Equal(w.Code, http.StatusCreated).NotEmpty(w.Header().Get(header.ETag))
+
+	w = serve(router, http.MethodGet, "/not-found")
+	a.Equal(w.Code, http.StatusNotFound).Empty(w.Header().Get(header.ETag))
 }
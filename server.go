@@ -15,7 +15,6 @@ import (
 
 	"github.com/issue9/cache"
 	"github.com/issue9/config"
-	"github.com/issue9/mux/v9"
 	"github.com/issue9/mux/v9/types"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
@@ -204,6 +203,7 @@ type (
 
 		requestIDKey    string
 		codec           *Codec
+		config          *routerConfig // 默认的路由设置
 		services        *Services
 		problems        *Problems
 		routers         *Routers
@@ -213,6 +213,7 @@ type (
 		logs            *Logs
 		closes          []func() error
 		cache           cache.Driver
+		dictionaries    *dictionaries
 		onRender        func(int, any) (int, any)
 		exitContexts    []OnExitContextFunc
 	}
@@ -267,14 +268,10 @@ func InternalNewServer(
 		onRender:     onRender,
 		exitContexts: make([]OnExitContextFunc, 0, 10),
 	}
+	is.dictionaries = newDictionaries(c)
+	is.config = newRouterConfig(codec, problemPrefix)
 	is.initServices()
-	is.routers = &Routers{
-		g: mux.NewGroup(is.call,
-			notFound,
-			buildNodeHandle(http.StatusMethodNotAllowed),
-			buildNodeHandle(http.StatusOK),
-			o...),
-	}
+	is.routers = newRouters(is, o...)
 
 	return is
 }
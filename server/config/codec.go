@@ -12,7 +12,9 @@ import (
 	"github.com/issue9/sliceutil"
 
 	"github.com/issue9/web"
+	"github.com/issue9/web/compressor"
 	"github.com/issue9/web/locales"
+	"github.com/issue9/web/mimetype/multipart"
 )
 
 type compressConfig struct {
@@ -36,7 +38,45 @@ type compressConfig struct {
 	//  - br-best-compression
 	//  - br-best-speed
 	//  - zstd-default
+	//  - dcz-default
+	//  - dcb-default
+	// 其中 dcz 和 dcb 为基于字典的压缩方法，仅在客户端提供了可用的字典时才会采用，可参考 [web.UseAsDictionary]。
 	ID string `json:"id" xml:"id,attr" yaml:"id" toml:"id"`
+
+	// Exclude 不采用此压缩方法的 content-type 值
+	//
+	// 格式与 Types 相同，优先级高于 Types。一般用于排除已经压缩过的内容，比如 image/*。
+	Exclude []string `json:"exclude,omitempty" xml:"exclude,omitempty" yaml:"exclude,omitempty" toml:"exclude,omitempty"`
+
+	// MinSize 内容小于此值时不作压缩
+	//
+	// 单位为 byte，0 表示始终压缩。
+	MinSize int `json:"minSize,omitempty" xml:"minSize,attr,omitempty" yaml:"minSize,omitempty" toml:"minSize,omitempty"`
+
+	// Adaptive 根据负载自动切换压缩等级
+	//
+	// 如果不为空，则忽略 ID 的值。
+	Adaptive *adaptiveConfig `json:"adaptive,omitempty" xml:"adaptive,omitempty" yaml:"adaptive,omitempty" toml:"adaptive,omitempty"`
+}
+
+type adaptiveConfig struct {
+	// IDs 参与切换的压缩方法
+	//
+	// 值为由 [RegisterCompression] 注册的 ID，必须是同一种压缩算法，且按压缩率从高到低排列，比如：
+	//  - gzip-best-compression
+	//  - gzip-default
+	//  - gzip-best-speed
+	IDs []string `json:"ids" xml:"id" yaml:"ids" toml:"ids"`
+
+	// MaxActive 同时进行压缩的数量
+	//
+	// 达到此值时采用 IDs 中的最后一项，0 表示不以此作为判断依据。
+	MaxActive int `json:"maxActive,omitempty" xml:"maxActive,attr,omitempty" yaml:"maxActive,omitempty" toml:"maxActive,omitempty"`
+
+	// MaxLatency 最近压缩每 KB 内容的平均耗时
+	//
+	// 仅统计压缩本身的耗时，不包含向客户端写入的时间。达到此值时采用 IDs 中的最后一项，0 表示不以此作为判断依据。
+	MaxLatency Duration `json:"maxLatency,omitempty" xml:"maxLatency,attr,omitempty" yaml:"maxLatency,omitempty" toml:"maxLatency,omitempty"`
 }
 
 type mimetypeConfig struct {
@@ -62,6 +102,13 @@ type mimetypeConfig struct {
 	//  - html
 	//  - gob
 	//  - yaml
+	//  - ndjson
+	//  - multipart 仅支持解码，可由 Multipart 指定大小限制和文件的保存目录。
+	//  - csv
+	//  - tsv
+	//  - msgpack
+	//  - hal
+	//  - jsonapi
 	//  - nop  没有具体实现的方法，对于上传等需要自行处理的情况可以指定此值。
 	Target string `json:"target" yaml:"target" xml:"target,attr" toml:"target"`
 
@@ -69,11 +116,39 @@ type mimetypeConfig struct {
 	//  - request 出现在作为客户端请求时的 Accept 报头中；
 	//  - response 出现在作为服务端响应时的 Accept 报头中，一般只有 OPTIONS 会有 Accept 报头；
 	Accept string `json:"accept,omitempty" yaml:"accept,omitempty" xml:"accept,attr,omitempty" toml:"accept,omitempty"`
+
+	// multipart 的解码选项
+	//
+	// 仅在 Target 为 multipart 时有效，为空表示采用默认值。
+	Multipart *multipartConfig `json:"multipart,omitempty" yaml:"multipart,omitempty" xml:"multipart,omitempty" toml:"multipart,omitempty"`
+}
+
+type multipartConfig struct {
+	// 单个文件的最大值
+	//
+	// 单位为 byte，0 表示采用 [multipart.DefaultMaxFileSize]，小于 0 表示不限制。
+	MaxFileSize int64 `json:"maxFileSize,omitempty" yaml:"maxFileSize,omitempty" xml:"maxFileSize,attr,omitempty" toml:"maxFileSize,omitempty"`
+
+	// 所有内容的最大值
+	//
+	// 单位为 byte，0 表示采用 [multipart.DefaultMaxTotalSize]，小于 0 表示不限制。
+	MaxTotalSize int64 `json:"maxTotalSize,omitempty" yaml:"maxTotalSize,omitempty" xml:"maxTotalSize,attr,omitempty" toml:"maxTotalSize,omitempty"`
+
+	// 单个普通字段的最大值
+	//
+	// 单位为 byte，0 表示采用 [multipart.DefaultMaxValueSize]，小于 0 表示不限制。
+	MaxValueSize int64 `json:"maxValueSize,omitempty" yaml:"maxValueSize,omitempty" xml:"maxValueSize,attr,omitempty" toml:"maxValueSize,omitempty"`
+
+	// 上传文件的保存目录
+	//
+	// 为空表示系统的临时目录。
+	Dir string `json:"dir,omitempty" yaml:"dir,omitempty" xml:"dir,omitempty" toml:"dir,omitempty"`
 }
 
 type mimetype struct {
 	marshal   web.MarshalFunc
 	unmarshal web.UnmarshalFunc
+	encode    []web.EncodeFunc
 }
 
 func (conf *configOf[T]) buildCodec() *web.FieldError {
@@ -84,18 +159,60 @@ func (conf *configOf[T]) buildCodec() *web.FieldError {
 	c := web.NewCodec()
 
 	for index, e := range conf.Compressors {
-		enc, found := compressorFactory.get(e.ID)
-		if !found {
-			field := "compresses[" + strconv.Itoa(index) + "].id"
-			return web.NewFieldError(field, locales.ErrNotFound())
+		var enc compressor.Compressor
+		if e.Adaptive != nil {
+			var err *web.FieldError
+			if enc, err = e.Adaptive.build(); err != nil {
+				return err.AddFieldParent("compresses[" + strconv.Itoa(index) + "].adaptive")
+			}
+		} else {
+			var found bool
+			if enc, found = compressorFactory.get(e.ID); !found {
+				field := "compresses[" + strconv.Itoa(index) + "].id"
+				return web.NewFieldError(field, locales.ErrNotFound())
+			}
+		}
+
+		if e.MinSize < 0 {
+			field := "compresses[" + strconv.Itoa(index) + "].minSize"
+			return web.NewFieldError(field, locales.ShouldGreatThan(0))
 		}
 
-		c.AddCompressor(enc, e.Types...)
+		c.AddCompressorWithOptions(enc, &web.CompressionOptions{Types: e.Types, Exclude: e.Exclude, MinSize: e.MinSize})
 	}
 
 	return conf.sanitizeMimetypes(c)
 }
 
+func (conf *adaptiveConfig) build() (compressor.Compressor, *web.FieldError) {
+	if len(conf.IDs) == 0 {
+		return nil, web.NewFieldError("ids", locales.CanNotBeEmpty)
+	}
+
+	levels := make([]compressor.Compressor, 0, len(conf.IDs))
+	for index, id := range conf.IDs {
+		c, found := compressorFactory.get(id)
+		if !found {
+			return nil, web.NewFieldError("ids["+strconv.Itoa(index)+"]", locales.ErrNotFound())
+		}
+
+		if index > 0 && c.Name() != levels[0].Name() {
+			return nil, web.NewFieldError("ids["+strconv.Itoa(index)+"]", locales.InvalidValue)
+		}
+		levels = append(levels, c)
+	}
+
+	if conf.MaxActive < 0 {
+		return nil, web.NewFieldError("maxActive", locales.ShouldGreatThan(0))
+	}
+
+	if conf.MaxLatency < 0 {
+		return nil, web.NewFieldError("maxLatency", locales.ShouldGreatThan(0))
+	}
+
+	return compressor.NewAdaptive(conf.MaxActive, conf.MaxLatency.Duration(), levels...), nil
+}
+
 func (conf *configOf[T]) sanitizeMimetypes(c *web.Codec) *web.FieldError {
 	if indexes := sliceutil.Dup(conf.Mimetypes, func(i, j *mimetypeConfig) bool { return i.Type == j.Type }); len(indexes) > 0 {
 		value := conf.Mimetypes[indexes[1]].Type
@@ -131,7 +248,20 @@ func (conf *configOf[T]) sanitizeMimetypes(c *web.Codec) *web.FieldError {
 			}
 		}
 
-		c.AddMimetype(item.Type, m.marshal, m.unmarshal, item.Problem, request, response)
+		unmarshal := m.unmarshal
+		if item.Multipart != nil {
+			if item.Target != "multipart" {
+				return web.NewFieldError("mimetypes["+strconv.Itoa(index)+"].multipart", locales.ErrInvalidValue())
+			}
+			unmarshal = multipart.New(&multipart.Options{
+				MaxFileSize:  item.Multipart.MaxFileSize,
+				MaxTotalSize: item.Multipart.MaxTotalSize,
+				MaxValueSize: item.Multipart.MaxValueSize,
+				Sink:         multipart.TempDir(item.Multipart.Dir),
+			})
+		}
+
+		c.AddMimetype(item.Type, m.marshal, unmarshal, item.Problem, request, response, m.encode...)
 	}
 
 	conf.codec = c
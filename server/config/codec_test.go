@@ -6,6 +6,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/issue9/assert/v4"
 
@@ -33,6 +34,65 @@ func TestConfigOf_buildCodec(t *testing.T) {
 	}
 	err := conf.buildCodec()
 	a.Error(err).Equal(err.Field, "compresses[1].id")
+
+	conf = &configOf[empty]{
+		Compressors: []*compressConfig{
+			{Types: []string{"text/*"}, Exclude: []string{"text/event-stream"}, MinSize: 1024, ID: "gzip-default"},
+			{ID: "br-default", MinSize: -1},
+		},
+	}
+	err = conf.buildCodec()
+	a.Error(err).Equal(err.Field, "compresses[1].minSize")
+
+	// adaptive
+
+	conf = &configOf[empty]{
+		Compressors: []*compressConfig{
+			{Adaptive: &adaptiveConfig{IDs: []string{"gzip-best-compression", "gzip-best-speed"}, MaxActive: 10, MaxLatency: Duration(time.Second)}},
+		},
+	}
+	a.NotError(conf.buildCodec())
+
+	conf = &configOf[empty]{
+		Compressors: []*compressConfig{
+			{ID: "br-default"},
+			{Adaptive: &adaptiveConfig{}},
+		},
+	}
+	err = conf.buildCodec()
+	a.Error(err).Equal(err.Field, "compresses[1].adaptive.ids")
+
+	conf = &configOf[empty]{
+		Compressors: []*compressConfig{
+			{Adaptive: &adaptiveConfig{IDs: []string{"gzip-best-compression", "not-exists-id"}}},
+		},
+	}
+	err = conf.buildCodec()
+	a.Error(err).Equal(err.Field, "compresses[0].adaptive.ids[1]")
+
+	conf = &configOf[empty]{
+		Compressors: []*compressConfig{
+			{Adaptive: &adaptiveConfig{IDs: []string{"gzip-best-compression", "br-best-speed"}}},
+		},
+	}
+	err = conf.buildCodec()
+	a.Error(err).Equal(err.Field, "compresses[0].adaptive.ids[1]")
+
+	conf = &configOf[empty]{
+		Compressors: []*compressConfig{
+			{Adaptive: &adaptiveConfig{IDs: []string{"gzip-best-compression"}, MaxActive: -1}},
+		},
+	}
+	err = conf.buildCodec()
+	a.Error(err).Equal(err.Field, "compresses[0].adaptive.maxActive")
+
+	conf = &configOf[empty]{
+		Compressors: []*compressConfig{
+			{Adaptive: &adaptiveConfig{IDs: []string{"gzip-best-compression"}, MaxLatency: -1}},
+		},
+	}
+	err = conf.buildCodec()
+	a.Error(err).Equal(err.Field, "compresses[0].adaptive.maxLatency")
 }
 
 func TestConfigOf_sanitizeMimetypes(t *testing.T) {
@@ -97,6 +157,25 @@ func TestConfigOf_sanitizeMimetypes(t *testing.T) {
 	a.Error(err).
 		Equal(err.Field, "mimetypes[0].accept").
 		Equal(err.Message, locales.ErrInvalidValue())
+
+	// Multipart
+
+	conf = &configOf[empty]{
+		Mimetypes: []*mimetypeConfig{
+			{Type: "multipart/form-data", Target: "multipart", Multipart: &multipartConfig{MaxFileSize: 1024}},
+		},
+	}
+	a.NotError(conf.buildCodec()).NotNil(conf.codec)
+
+	conf = &configOf[empty]{
+		Mimetypes: []*mimetypeConfig{
+			{Type: "json", Target: "json", Multipart: &multipartConfig{MaxFileSize: 1024}},
+		},
+	}
+	err = conf.buildCodec()
+	a.Error(err).
+		Equal(err.Field, "mimetypes[0].multipart").
+		Equal(err.Message, locales.ErrInvalidValue())
 }
 
 func TestRegisterMimetype(t *testing.T) {
@@ -102,6 +102,11 @@ type (
 		Trace string           `yaml:"trace,omitempty" json:"trace,omitempty" xml:"trace,omitempty" toml:"trace,omitempty"`
 		trace web.RouterOption // 由 Trace 字段转换而来
 
+		// 对客户端提交内容的限制
+		//
+		// NOTE: 这些设置对所有路径均有效，但会被 [web.Routers.New] 的参数修改。
+		BodyLimit *bodyLimitConfig `yaml:"bodyLimit,omitempty" json:"bodyLimit,omitempty" xml:"bodyLimit,omitempty" toml:"bodyLimit,omitempty"`
+
 		init       func(*server.Options)
 		httpServer *http.Server
 	}
@@ -141,6 +146,26 @@ type (
 		RenewBefore uint `yaml:"renewBefore,omitempty" json:"renewBefore,omitempty" xml:"renewBefore,attr,omitempty" toml:"renewBefore,omitempty"`
 	}
 
+	// bodyLimitConfig 对提交内容的限制
+	//
+	// 提交的内容始终会按 Content-Encoding 解压，各字段仅用于限制内容的大小。
+	bodyLimitConfig struct {
+		// 提交内容的最大字节数
+		//
+		// 如果指定了 Content-Encoding，表示的是解压之前的大小。0 表示不限制。
+		Size int64 `yaml:"size,omitempty" json:"size,omitempty" xml:"size,attr,omitempty" toml:"size,omitempty"`
+
+		// 解压之后的最大字节数
+		//
+		// 仅在指定了 Content-Encoding 时有效。0 表示不限制。
+		DecodedSize int64 `yaml:"decodedSize,omitempty" json:"decodedSize,omitempty" xml:"decodedSize,attr,omitempty" toml:"decodedSize,omitempty"`
+
+		// 解压之后与解压之前的最大比值
+		//
+		// 仅在指定了 Content-Encoding 时有效。0 表示不限制。
+		Ratio int64 `yaml:"ratio,omitempty" json:"ratio,omitempty" xml:"ratio,attr,omitempty" toml:"ratio,omitempty"`
+	}
+
 	corsConfig struct {
 		// 指定跨域中的 Access-Control-Allow-Origin 报头内容
 		//
@@ -233,6 +258,12 @@ func (h *httpConfig) sanitize(l *logs.Logs) *web.FieldError {
 		return err
 	}
 
+	if h.BodyLimit != nil {
+		if err := h.BodyLimit.sanitize(); err != nil {
+			return err.AddFieldParent("bodyLimit")
+		}
+	}
+
 	h.buildInit(l)
 	h.buildHTTPServer()
 	return nil
@@ -282,9 +313,22 @@ func (h *httpConfig) buildInit(l *logs.Logs) {
 		if h.URL != "" {
 			o.RoutersOptions = append(o.RoutersOptions, web.WithURLDomain(h.URL))
 		}
+
+		if l := h.BodyLimit; l != nil {
+			o.BodyLimit = &web.BodyLimit{Size: l.Size, DecodedSize: l.DecodedSize, Ratio: l.Ratio}
+		}
 	}
 }
 
+func (l *bodyLimitConfig) sanitize() *web.FieldError {
+	greatThan0 := filter.V(func(v int64) bool { return v >= 0 }, locales.ShouldGreatThan(0))
+	return filter.ToFieldError(
+		filter.New("size", &l.Size, greatThan0),
+		filter.New("decodedSize", &l.DecodedSize, greatThan0),
+		filter.New("ratio", &l.Ratio, greatThan0),
+	)
+}
+
 func (h *httpConfig) buildTLSConfig() *web.FieldError {
 	if len(h.Certificates) > 0 && h.ACME != nil {
 		return web.NewFieldError("acme", web.Phrase("conflict with certificates"))
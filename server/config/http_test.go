@@ -14,6 +14,9 @@ import (
 	"github.com/goccy/go-yaml"
 	"github.com/issue9/assert/v4"
 	"github.com/issue9/logs/v7"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/server"
 )
 
 func TestCertificate_sanitize(t *testing.T) {
@@ -47,6 +50,17 @@ func TestHTTP_sanitize(t *testing.T) {
 	http.ReadHeaderTimeout = -1
 	ferr = http.sanitize(l)
 	a.Equal(ferr.Field, "readHeaderTimeout")
+
+	http.ReadHeaderTimeout = 0
+	http.BodyLimit = &bodyLimitConfig{Ratio: -1}
+	ferr = http.sanitize(l)
+	a.Equal(ferr.Field, "bodyLimit.ratio")
+
+	http.BodyLimit.Ratio = 100
+	a.NotError(http.sanitize(l))
+	o := &server.Options{}
+	http.init(o)
+	a.Equal(o.BodyLimit, &web.BodyLimit{Ratio: 100})
 }
 
 func TestHTTP_buildTLSConfig(t *testing.T) {
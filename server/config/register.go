@@ -29,10 +29,16 @@ import (
 	"github.com/issue9/web"
 	"github.com/issue9/web/compressor"
 	"github.com/issue9/web/mimetype/cbor"
+	"github.com/issue9/web/mimetype/csv"
 	"github.com/issue9/web/mimetype/form"
 	"github.com/issue9/web/mimetype/gob"
+	"github.com/issue9/web/mimetype/hal"
 	"github.com/issue9/web/mimetype/html"
 	"github.com/issue9/web/mimetype/json"
+	"github.com/issue9/web/mimetype/jsonapi"
+	"github.com/issue9/web/mimetype/msgpack"
+	"github.com/issue9/web/mimetype/multipart"
+	"github.com/issue9/web/mimetype/ndjson"
 	"github.com/issue9/web/mimetype/nop"
 	"github.com/issue9/web/mimetype/xml"
 	"github.com/issue9/web/mimetype/yaml"
@@ -110,9 +116,13 @@ func RegisterIDGenerator(id string, b IDGeneratorBuilder) { idGeneratorFactory.r
 
 // RegisterMimetype 注册用于序列化用户提交数据的方法
 //
-// name 为名称，这将在配置文件中被引用，如果存在同名，则会覆盖。
-func RegisterMimetype(m web.MarshalFunc, u web.UnmarshalFunc, name string) {
-	mimetypesFactory.register(mimetype{marshal: m, unmarshal: u}, name)
+// name 为名称，这将在配置文件中被引用，如果存在同名，则会覆盖；
+// enc 为流式的编码方法，可以为空，最多只能指定一个；
+func RegisterMimetype(m web.MarshalFunc, u web.UnmarshalFunc, name string, enc ...web.EncodeFunc) {
+	if len(enc) > 1 {
+		panic("参数 enc 最多只能指定一个")
+	}
+	mimetypesFactory.register(mimetype{marshal: m, unmarshal: u, encode: enc}, name)
 }
 
 // RegisterFileSerializer 注册用于文件序列化的方法
@@ -180,6 +190,9 @@ func init() {
 
 	RegisterCompression("zstd-default", compressor.NewZstd())
 
+	RegisterCompression("dcz-default", compressor.NewDCZ())
+	RegisterCompression("dcb-default", compressor.NewDCB())
+
 	// RegisterIDGenerator
 
 	RegisterIDGenerator("date", func() (func() string, web.Service) {
@@ -197,13 +210,20 @@ func init() {
 
 	// RegisterMimetype
 
-	RegisterMimetype(json.Marshal, json.Unmarshal, "json")
-	RegisterMimetype(yaml.Marshal, yaml.Unmarshal, "yaml")
-	RegisterMimetype(cbor.Marshal, cbor.Unmarshal, "cbor")
-	RegisterMimetype(xml.Marshal, xml.Unmarshal, "xml")
+	RegisterMimetype(json.Marshal, json.Unmarshal, "json", json.Encode)
+	RegisterMimetype(yaml.Marshal, yaml.Unmarshal, "yaml", yaml.Encode)
+	RegisterMimetype(cbor.Marshal, cbor.Unmarshal, "cbor", cbor.Encode)
+	RegisterMimetype(xml.Marshal, xml.Unmarshal, "xml", xml.Encode)
 	RegisterMimetype(html.Marshal, html.Unmarshal, "html")
 	RegisterMimetype(form.Marshal, form.Unmarshal, "form")
 	RegisterMimetype(gob.Marshal, gob.Unmarshal, "gob")
+	RegisterMimetype(ndjson.Marshal, ndjson.Unmarshal, "ndjson", ndjson.Encode)
+	RegisterMimetype(multipart.Marshal, multipart.Unmarshal, "multipart")
+	RegisterMimetype(csv.Marshal, csv.Unmarshal, "csv", csv.Encode)
+	RegisterMimetype(csv.MarshalTSV, csv.UnmarshalTSV, "tsv", csv.EncodeTSV)
+	RegisterMimetype(msgpack.Marshal, msgpack.Unmarshal, "msgpack", msgpack.Encode)
+	RegisterMimetype(hal.Marshal, hal.Unmarshal, "hal", hal.Encode)
+	RegisterMimetype(jsonapi.Marshal, jsonapi.Unmarshal, "jsonapi", jsonapi.Encode)
 	RegisterMimetype(nop.Marshal, nop.Unmarshal, "nop")
 
 	// RegisterFileSerializer
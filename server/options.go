@@ -82,6 +82,12 @@ type (
 		// 路由选项
 		RoutersOptions []web.RouterOption
 
+		// 对客户端提交内容的限制
+		//
+		// 对所有路由均有效，可由 RoutersOptions 中的 [web.WithBodyLimit]
+		// 或是作为中间件的 [web.BodyLimit] 修改。为空表示不作限制。
+		BodyLimit *web.BodyLimit
+
 		// 指定获取 x-request-id 内容的报头名
 		//
 		// 如果为空，则采用 [header.XRequestID] 作为默认值
@@ -224,10 +230,15 @@ func sanitizeOptions(o *Options, t int) (*Options, *web.FieldError) {
 }
 
 func (o *Options) internalServer(id, version string, s web.Server) *web.InternalServer {
+	ro := o.RoutersOptions
+	if o.BodyLimit != nil { // 放在最前，可以被 RoutersOptions 中的值覆盖。
+		ro = append([]web.RouterOption{web.WithBodyLimit(o.BodyLimit)}, ro...)
+	}
+
 	return web.InternalNewServer(s, id, version,
 		o.Location, o.Logs, o.IDGenerator, o.locale,
 		o.Cache, o.Codec, o.RequestIDKey, o.ProblemTypePrefix,
-		o.OnRender, o.RoutersOptions...)
+		o.OnRender, ro...)
 }
 
 // Render200 统一 API 的返回格式
@@ -16,6 +16,7 @@ import (
 	"github.com/issue9/assert/v4"
 	"github.com/issue9/cache/caches/memory"
 	"github.com/issue9/logs/v7"
+	"github.com/issue9/mux/v9"
 	"github.com/issue9/mux/v9/header"
 	"github.com/issue9/unique/v2"
 	"golang.org/x/text/language"
@@ -79,3 +80,16 @@ func TestOnExitContextFunc(t *testing.T) {
 	s.freeContext(ctx)
 	a.Equal(ctx.Header().Get("exit-status"), "202")
 }
+
+func TestWithMuxOptions(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newTestServer(a)
+
+	router := srv.Routers().New("mux", nil, WithMuxOptions(mux.WithLock(true), mux.WithStatusRecovery(http.StatusBadGateway)))
+	router.Get("/panic", func(*Context) Responser { panic("panic") })
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	router.ServeHTTP(w, r)
+	a.Equal(w.Code, http.StatusBadGateway)
+}
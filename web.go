@@ -54,11 +54,26 @@ type (
 	//
 	// NOTE: 不采用流的方式处理数据的原因是因为：编码过程中可能会出错，
 	// 此时需要修改状态码，流式的因为有内容输出，状态码也已经固定，无法修改。
+	// 如果对内存占用比较敏感，可以额外指定 [EncodeFunc]。
 	MarshalFunc func(*Context, any) ([]byte, error)
 
+	// EncodeFunc 流式的序列化函数原型
+	//
+	// 与 [MarshalFunc] 功能相同，但是会将内容直接写入 [io.Writer]，
+	// 而不是先生成完整的 []byte，适用于输出大对象的情况。
+	// 对于同一对象，输出的内容应该与 [MarshalFunc] 完全相同。
+	//
+	// NOTE: 在向 [io.Writer] 写入内容之前返回的错误，依然可以改变状态码；
+	// 一旦有内容写入，之后的错误只能记录于日志中。
+	//
+	// NOTE: EncodeFunc 的作用是输出内容，所以在实现中不能调用 [Context.Render] 等输出方法。
+	EncodeFunc func(*Context, io.Writer, any) error
+
 	// UnmarshalFunc 反序列化函数原型
 	//
 	// NOTE: 参数 [io.Reader] 必定不会为空。
+	// 由 [Context] 传入的 [io.Reader] 还实现了 ContentType() string 方法，
+	// 可用于获取 Content-Type 报头中的参数。
 	UnmarshalFunc func(io.Reader, any) error
 )
 
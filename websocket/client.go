@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web"
+)
+
+// Dial 连接 websocket 服务端
+//
+// l 用于记录连接中的错误信息；
+// rawURL 为服务端的地址，支持 ws、wss、http 和 https 四种协议；
+// h 为握手时额外发送的报头，可以为空；
+// bufCap 可缓存的待发送消息数量；
+// limit 单条消息的最大长度，小于等于零表示采用 [DefaultLimit]；
+// marshal 和 unmarshal 为消息的编码和解码方法，需要与服务端协商的结果一致；
+// onMessage 收到消息时的处理函数；
+//
+// ctx 仅作用于连接和握手阶段，连接建立之后，需要调用 [Conn.Close] 关闭连接。
+func Dial(
+	ctx context.Context,
+	l *web.Logger,
+	rawURL string,
+	h http.Header,
+	bufCap int,
+	limit int64,
+	marshal func(any) ([]byte, error),
+	unmarshal web.UnmarshalFunc,
+	onMessage func(*Conn, *Message),
+) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var secure bool
+	switch u.Scheme {
+	case "ws", "http":
+		u.Scheme = "http"
+	case "wss", "https":
+		u.Scheme = "https"
+		secure = true
+	default:
+		return nil, web.NewLocaleError("invalid websocket url scheme %s", u.Scheme)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		if secure {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	d := &net.Dialer{}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if secure {
+		tc := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err = tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+
+	r, err := handshake(ctx, conn, u, h)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := newConn(conn, r, bufio.NewWriter(conn), true, limit, bufCap, l, marshal, unmarshal, onMessage)
+	c.start()
+	return c, nil
+}
+
+func handshake(ctx context.Context, conn net.Conn, u *url.URL, h http.Header) (*bufio.Reader, error) {
+	if dl, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(dl); err != nil {
+			return nil, err
+		}
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	if h == nil {
+		h = http.Header{}
+	} else {
+		h = h.Clone()
+	}
+	key := newKey()
+	h.Set(header.Upgrade, "websocket")
+	h.Set(header.Connection, "Upgrade")
+	h.Set("Sec-WebSocket-Key", key)
+	h.Set("Sec-WebSocket-Version", Version)
+
+	req := (&http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: h}).WithContext(ctx)
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, web.NewError(resp.StatusCode, errBadRequest)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, errBadRequest
+	}
+
+	return r, nil
+}
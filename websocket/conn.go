@@ -0,0 +1,318 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/issue9/web"
+)
+
+// 发送 close 帧之后等待对方回应的时间
+const closeTimeout = 5 * time.Second
+
+// 写入单个帧的最长时间，防止对方不再读取时写操作一直占用 wmux。
+const writeTimeout = 10 * time.Second
+
+// Conn 表示一条 websocket 连接
+//
+// 可以是由 [Server.NewConn] 创建的服务端连接，也可以是由 [Dial] 创建的客户端连接。
+type Conn struct {
+	conn      net.Conn
+	r         *bufio.Reader
+	w         *bufio.Writer
+	wmux      sync.Mutex
+	sentClose bool // 已经写入了 close 帧，由 wmux 保护。
+	client    bool // 客户端发送的帧需要进行掩码处理
+	limit     int64
+	logger    *web.Logger
+
+	marshal   func(any) ([]byte, error)
+	unmarshal web.UnmarshalFunc
+	onMessage func(*Conn, *Message)
+	onClose   func()
+
+	buf        chan *frame
+	closing    atomic.Bool // 已经发送了 close 帧
+	closed     chan struct{}
+	closeOnce  sync.Once
+	lastActive atomic.Int64
+}
+
+func newConn(
+	conn net.Conn,
+	r *bufio.Reader,
+	w *bufio.Writer,
+	client bool,
+	limit int64,
+	bufCap int,
+	l *web.Logger,
+	marshal func(any) ([]byte, error),
+	unmarshal web.UnmarshalFunc,
+	onMessage func(*Conn, *Message),
+) *Conn {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	c := &Conn{
+		conn:   conn,
+		r:      r,
+		w:      w,
+		client: client,
+		limit:  limit,
+		logger: l,
+
+		marshal:   marshal,
+		unmarshal: unmarshal,
+		onMessage: onMessage,
+
+		buf:    make(chan *frame, bufCap),
+		closed: make(chan struct{}),
+	}
+	c.lastActive.Store(time.Now().UnixNano())
+
+	return c
+}
+
+func (c *Conn) start() {
+	go c.writeLoop()
+	go c.readLoop()
+}
+
+func (c *Conn) writeLoop() {
+	for {
+		select {
+		case f := <-c.buf:
+			if c.closing.Load() { // 已经发送或即将发送 close 帧，丢弃队列中的内容。
+				continue
+			}
+
+			if err := c.write(f.op, f.payload); errors.Is(err, errClosed) {
+				continue
+			} else if err != nil {
+				c.logger.Error(err)
+				c.teardown()
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+func (c *Conn) write(op byte, payload []byte) error {
+	c.wmux.Lock()
+	defer c.wmux.Unlock()
+
+	select {
+	case <-c.closed:
+		return errClosed
+	default:
+		if c.sentClose { // close 帧之后不能再发送任何帧
+			return errClosed
+		}
+		if op == opClose {
+			c.sentClose = true
+		}
+		if err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+			return err
+		}
+		return writeFrame(c.w, op, payload, c.client)
+	}
+}
+
+func (c *Conn) readLoop() {
+	defer c.teardown()
+
+	var msgOp byte // 分片消息的类型，为 0 表示当前没有未完成的分片消息。
+	var data []byte
+	for {
+		f, err := readFrame(c.r, !c.client, c.limit)
+		switch {
+		case errors.Is(err, errProtocol):
+			c.closeWith(CloseProtocolError)
+			return
+		case errors.Is(err, errTooBig):
+			c.closeWith(CloseMessageTooBig)
+			return
+		case err != nil: // 连接已经断开
+			return
+		}
+		c.lastActive.Store(time.Now().UnixNano())
+
+		switch f.op {
+		case opPing:
+			if err := c.write(opPong, f.payload); err != nil && !errors.Is(err, errClosed) {
+				return
+			}
+		case opPong:
+		case opClose:
+			if !c.closing.Swap(true) { // 由对方发起的关闭操作，需要回应 close 帧。
+				code := CloseNoStatus
+				if len(f.payload) >= 2 {
+					code = int(binary.BigEndian.Uint16(f.payload))
+				}
+				if err := c.write(opClose, closePayload(code, "")); err != nil {
+					c.logger.Error(err)
+				}
+			}
+			return
+		case opText, opBinary:
+			if msgOp != 0 {
+				c.closeWith(CloseProtocolError)
+				return
+			}
+
+			if f.fin {
+				if !c.deliver(f.op, f.payload) {
+					return
+				}
+				continue
+			}
+			msgOp = f.op
+			data = f.payload
+		case opContinuation:
+			if msgOp == 0 {
+				c.closeWith(CloseProtocolError)
+				return
+			}
+
+			if int64(len(data)+len(f.payload)) > c.limit {
+				c.closeWith(CloseMessageTooBig)
+				return
+			}
+			data = append(data, f.payload...)
+
+			if f.fin {
+				if !c.deliver(msgOp, data) {
+					return
+				}
+				msgOp = 0
+				data = nil
+			}
+		default:
+			c.closeWith(CloseProtocolError)
+			return
+		}
+	}
+}
+
+func (c *Conn) deliver(op byte, data []byte) bool {
+	if op == opText && !utf8.Valid(data) {
+		c.closeWith(CloseInvalidPayload)
+		return false
+	}
+
+	if c.onMessage != nil {
+		c.onMessage(c, &Message{Type: MessageType(op), Data: data, unmarshal: c.unmarshal})
+	}
+	return true
+}
+
+// 因为错误而关闭连接，不需要等待对方的回应。
+func (c *Conn) closeWith(code int) {
+	if !c.closing.Swap(true) {
+		if err := c.write(opClose, closePayload(code, "")); err != nil {
+			c.logger.Error(err)
+		}
+	}
+}
+
+// 释放连接
+func (c *Conn) teardown() {
+	c.closeOnce.Do(func() {
+		c.closing.Store(true)
+
+		// 先关闭连接，让阻塞在写操作上的 write 返回并释放 wmux。
+		if err := c.conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			c.logger.Error(err)
+		}
+
+		c.wmux.Lock()
+		close(c.closed)
+		c.wmux.Unlock()
+
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+}
+
+// Sent 发送对象 v
+//
+// v 的编码方式由 [Server.NewConn] 或 [Dial] 决定，
+// 编码后的内容如果是合法的 UTF-8 字符串，则以文本消息发送，否则以二进制消息发送。
+//
+// 如果发送队列已满，会阻塞直到有空间或是连接被关闭。
+func (c *Conn) Sent(v any) error {
+	if c.closing.Load() {
+		return errClosed
+	}
+
+	data, err := c.marshal(v)
+	if err != nil {
+		return err
+	}
+
+	typ := TextMessage
+	if !utf8.Valid(data) {
+		typ = BinaryMessage
+	}
+	return c.SentRaw(typ, data)
+}
+
+// SentRaw 发送原始的消息内容
+//
+// 如果发送队列已满，会阻塞直到有空间或是连接被关闭。
+func (c *Conn) SentRaw(typ MessageType, data []byte) error {
+	if c.closing.Load() {
+		return errClosed
+	}
+
+	select {
+	case c.buf <- &frame{fin: true, op: byte(typ), payload: data}:
+		return nil
+	case <-c.closed:
+		return errClosed
+	}
+}
+
+// Ping 发送 ping 帧
+//
+// 与 [Conn.Sent] 不同，Ping 不经过发送队列，直接发送给对方。
+func (c *Conn) Ping() error { return c.write(opPing, nil) }
+
+// Close 关闭连接
+//
+// 向对方发送 close 帧，并等待对方的回应之后关闭连接，如果对方在一定时间内未回应，则直接关闭。
+// 发送队列中未发送的内容将被丢弃。
+// code 为关闭的状态码，reason 为关闭的原因，可以为空。
+func (c *Conn) Close(code int, reason string) error {
+	if c.closing.Swap(true) {
+		return nil
+	}
+
+	if err := c.write(opClose, closePayload(code, reason)); err != nil {
+		c.teardown()
+		return err
+	}
+	return c.conn.SetReadDeadline(time.Now().Add(closeTimeout))
+}
+
+// LastActive 最后一次收到对方数据的时间
+func (c *Conn) LastActive() time.Time { return time.Unix(0, c.lastActive.Load()) }
+
+// Done 连接关闭之后，返回的通道会被关闭
+func (c *Conn) Done() <-chan struct{} { return c.closed }
+
+func (c *Conn) wait() { <-c.closed }
@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package websocket
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/puzpuzpuz/xsync/v4"
+
+	"github.com/issue9/web"
+)
+
+// Server websocket 服务端
+//
+// T 表示用于区分不同连接的 ID，比如按用户区分，
+// 那么该类型可能是 int64 类型的用户 ID 值。
+type Server[T comparable] struct {
+	s         web.Server
+	keepAlive time.Duration
+	bufCap    int
+	limit     int64
+	conns     *xsync.Map[T, *Conn]
+}
+
+// NewServer 将 [Server] 注册为 [web.Server.Services] 服务并返回
+//
+// keepAlive 表示发送 ping 帧的时间间隔，如果小于等于零，表示不会发送，
+// 超过两个周期未收到对方任何数据的连接将被断开；
+// bufCap 每个连接可缓存的待发送消息数量，超过此数量，调用的 Sent 将被阻塞；
+// limit 单条消息的最大长度，小于等于零表示采用 [DefaultLimit]；
+// desc 对该服务的描述；
+func NewServer[T comparable](s web.Server, keepAlive time.Duration, bufCap int, limit int64, desc web.LocaleStringer) *Server[T] {
+	srv := &Server[T]{
+		s:         s,
+		keepAlive: keepAlive,
+		bufCap:    bufCap,
+		limit:     limit,
+		conns:     xsync.NewMap[T, *Conn](),
+	}
+
+	s.Services().AddFunc(desc, srv.serve)
+
+	return srv
+}
+
+func (srv *Server[T]) ping(now time.Time) error {
+	for _, c := range srv.Conns() {
+		if now.Sub(c.LastActive()) > 2*srv.keepAlive { // 对方已经失去响应
+			c.teardown()
+			continue
+		}
+
+		if err := c.Ping(); err != nil && !errors.Is(err, errClosed) {
+			srv.s.Logs().ERROR().Error(err)
+		}
+	}
+	return nil
+}
+
+func (srv *Server[T]) serve(ctx context.Context) error {
+	if srv.keepAlive > 0 { // 不采用 Services().AddTicker，服务运行之后再添加定时任务存在数据竞争。
+		ticker := time.NewTicker(srv.keepAlive)
+		defer ticker.Stop()
+
+	LOOP:
+		for {
+			select {
+			case now := <-ticker.C:
+				if err := srv.ping(now); err != nil {
+					srv.s.Logs().ERROR().Error(err)
+				}
+			case <-ctx.Done():
+				break LOOP
+			}
+		}
+	} else {
+		<-ctx.Done()
+	}
+
+	for _, c := range srv.Conns() {
+		if err := c.Close(CloseGoingAway, ""); err != nil {
+			srv.s.Logs().ERROR().Error(err)
+		}
+	}
+	for _, c := range srv.Conns() {
+		c.wait() // 等待对方的回应或是超时
+	}
+
+	return ctx.Err()
+}
+
+// Len 当前连接的数量
+func (srv *Server[T]) Len() int { return srv.conns.Size() }
+
+// Get 返回指定 sid 的连接
+//
+// 仅在 [Server.NewConn] 执行之后，此函数才能返回非空值。
+func (srv *Server[T]) Get(sid T) *Conn {
+	if c, found := srv.conns.Load(sid); found {
+		return c
+	}
+	return nil
+}
+
+// Conns 所有的连接
+func (srv *Server[T]) Conns() iter.Seq2[T, *Conn] {
+	return func(yield func(T, *Conn) bool) {
+		srv.conns.Range(func(key T, value *Conn) bool {
+			return yield(key, value)
+		})
+	}
+}
+
+// Sent 向所有的连接发送消息
+//
+// f 用于生成每个连接需要发送的对象，如果返回 nil，表示不向该连接发送。
+func (srv *Server[T]) Sent(f func(sid T) any) {
+	for sid, c := range srv.Conns() {
+		if v := f(sid); v != nil {
+			if err := c.Sent(v); err != nil {
+				srv.s.Logs().ERROR().Error(err)
+			}
+		}
+	}
+}
+
+// NewConn 将当前请求升级为 websocket 连接
+//
+// sid 表示连接的唯一 ID，如果已经存在相同 ID 的连接，旧连接会被关闭；
+// onMessage 收到消息时的处理函数，同一连接的消息按顺序调用；
+// wait 连接关闭时才会返回，可以在 [web.Handler] 中阻止路由退出导致的 ctx 被回收。
+//
+// 消息的编码和解码方式由 ctx 协商的结果决定，即 [web.Context.Marshaler] 和 [web.Context.Unmarshaler]。
+// 由于连接的生命周期可能长于 ctx，编码时传递给 [web.MarshalFunc] 的 [web.Context] 始终为 nil，
+// 依赖 [web.Context] 的编码方法（比如 html 和 jsonp）不能用于 websocket。
+// 如果不是合法的握手请求，返回的 err 为 [web.NewError] 包装的错误，可直接传递给 [web.Context.Error]。
+func (srv *Server[T]) NewConn(sid T, ctx *web.Context, onMessage func(*Conn, *Message)) (c *Conn, wait func(), err error) {
+	key, err := checkHandshake(ctx.Request())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h := ctx.Header().Clone()
+	h.Del(header.ContentEncoding)
+	h.Del(header.ContentType)
+	h.Del(header.Vary)
+	h.Set(header.Upgrade, "websocket")
+	h.Set(header.Connection, "Upgrade")
+	h.Set("Sec-WebSocket-Accept", acceptKey(key))
+
+	conn, rw, err := http.NewResponseController(ctx).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = conn.SetDeadline(time.Time{}); err != nil { // 清除由 http.Server 设置的超时
+		conn.Close()
+		return nil, nil, err
+	}
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	h.Write(rw)
+	rw.WriteString("\r\n")
+	if err = rw.Flush(); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	m := ctx.Marshaler()
+	marshal := func(v any) ([]byte, error) { return m(nil, v) } // ctx 在处理函数退出之后会被回收，不能被引用。
+	c = newConn(conn, rw.Reader, rw.Writer, false, srv.limit, srv.bufCap, srv.s.Logs().ERROR(), marshal, ctx.Unmarshaler(), onMessage)
+	c.onClose = func() {
+		srv.conns.Compute(sid, func(old *Conn, loaded bool) (*Conn, xsync.ComputeOp) {
+			if loaded && old == c { // 可能已经被同 sid 的新连接替换
+				return nil, xsync.DeleteOp
+			}
+			return old, xsync.CancelOp
+		})
+	}
+
+	if old, loaded := srv.conns.LoadAndStore(sid, c); loaded {
+		if err := old.Close(CloseGoingAway, ""); err != nil {
+			srv.s.Logs().ERROR().Error(err)
+		}
+	}
+	c.start()
+
+	return c, c.wait, nil
+}
@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package websocket
+
+import (
+	"context"
+	sj "encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/logs/v7"
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/mimetype/json"
+	"github.com/issue9/web/server"
+	"github.com/issue9/web/server/servertest"
+)
+
+type object struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestServer(t *testing.T) {
+	a := assert.New(t, false)
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Codec:      web.NewCodec().AddMimetype(json.Mimetype, json.Marshal, json.Unmarshal, "", true, true),
+		Logs:       logs.New(logs.NewTermHandler(os.Stderr, nil), logs.WithCreated(logs.MicroLayout), logs.WithLevels(logs.AllLevels()...)),
+	})
+	a.NotError(err).NotNil(s)
+	srv := NewServer[int64](s, time.Second, 10, 1024, web.StringPhrase("websocket"))
+	a.NotNil(srv)
+
+	s.Routers().New("default", nil).Get("/ws/{id}", func(ctx *web.Context) web.Responser {
+		id, resp := ctx.PathInt64("id", web.ProblemBadRequest)
+		if resp != nil {
+			return resp
+		}
+
+		_, wait, err := srv.NewConn(id, ctx, func(c *Conn, m *Message) { // 原样返回
+			obj := &object{}
+			a.NotError(m.Unmarshal(obj))
+			obj.ID = int(id)
+			a.NotError(c.Sent(obj))
+		})
+		if err != nil {
+			return ctx.Error(err, "")
+		}
+		wait()
+		return nil
+	})
+
+	defer servertest.Run(a, s)()
+
+	// 非 websocket 请求
+	servertest.Get(a, "http://localhost:8080/ws/1").
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusBadRequest)
+
+	msgs := make(chan *object, 10)
+	onMessage := func(_ *Conn, m *Message) {
+		obj := &object{}
+		a.NotError(m.Unmarshal(obj)).Equal(m.Type, TextMessage)
+		msgs <- obj
+	}
+	h := http.Header{header.Accept: []string{header.JSON}}
+	c1, err := Dial(context.Background(), s.Logs().ERROR(), "ws://localhost:8080/ws/1", h, 10, 0, sj.Marshal, json.Unmarshal, onMessage)
+	a.NotError(err).NotNil(c1)
+	c2, err := Dial(context.Background(), s.Logs().ERROR(), "ws://localhost:8080/ws/2", h, 10, 0, sj.Marshal, json.Unmarshal, onMessage)
+	a.NotError(err).NotNil(c2)
+
+	a.NotError(c1.Sent(&object{Name: "c1"}))
+	a.Equal(<-msgs, &object{ID: 1, Name: "c1"})
+
+	a.NotError(srv.ping(time.Now())) // 客户端会自动回应 pong
+	time.Sleep(50 * time.Millisecond)
+	a.Equal(srv.Len(), 2).NotNil(srv.Get(1)).NotNil(srv.Get(2)).Nil(srv.Get(3))
+
+	// 广播
+	srv.Sent(func(sid int64) any {
+		if sid == 1 {
+			return nil
+		}
+		return &object{ID: int(sid), Name: "broadcast"}
+	})
+	a.Equal(<-msgs, &object{ID: 2, Name: "broadcast"})
+
+	// 客户端关闭
+	a.NotError(c2.Close(CloseNormal, ""))
+	<-c2.Done()
+	a.ErrorIs(c2.Sent(&object{}), ErrClosed())
+	time.Sleep(50 * time.Millisecond)
+	a.Equal(srv.Len(), 1).Nil(srv.Get(2))
+
+	// 相同 sid 替换旧连接
+	c3, err := Dial(context.Background(), s.Logs().ERROR(), "ws://localhost:8080/ws/1", h, 10, 0, sj.Marshal, json.Unmarshal, onMessage)
+	a.NotError(err).NotNil(c3)
+	<-c1.Done()
+	time.Sleep(50 * time.Millisecond)
+	a.Equal(srv.Len(), 1)
+
+	// 超过两个 keep-alive 周期未响应
+	a.NotError(srv.ping(time.Now().Add(3 * time.Second)))
+	<-c3.Done()
+	time.Sleep(50 * time.Millisecond)
+	a.Equal(srv.Len(), 0)
+
+	c4, err := Dial(context.Background(), s.Logs().ERROR(), "ws://localhost:8080/ws/4", h, 10, 0, sj.Marshal, json.Unmarshal, onMessage)
+	a.NotError(err).NotNil(c4)
+	time.Sleep(50 * time.Millisecond)
+	a.Equal(srv.Len(), 1)
+
+	// 服务关闭
+	s.Close(500 * time.Millisecond)
+	<-c4.Done()
+	time.Sleep(50 * time.Millisecond)
+	a.Equal(srv.Len(), 0)
+}
@@ -0,0 +1,281 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package websocket [WebSocket] 的实现
+//
+// 基于 [http.Hijacker] 实现，不依赖任何第三方的服务。
+// 不支持 WebSocket 的扩展功能，比如 permessage-deflate 等。
+//
+// [WebSocket]: https://www.rfc-editor.org/rfc/rfc6455.html
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/issue9/mux/v9/header"
+
+	"github.com/issue9/web"
+)
+
+// 计算 Sec-WebSocket-Accept 时需要的 GUID
+const guid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Version 支持的 WebSocket 协议版本
+const Version = "13"
+
+// MessageType 消息的类型
+type MessageType byte
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// 帧的类型
+const (
+	opContinuation byte = 0
+	opText         byte = 1
+	opBinary       byte = 2
+	opClose        byte = 8
+	opPing         byte = 9
+	opPong         byte = 10
+)
+
+// 关闭连接的状态码
+//
+// 仅列出了常用的部分，完整的列表可参考 [RFC6455] 第 7.4 节。
+//
+// [RFC6455]: https://www.rfc-editor.org/rfc/rfc6455.html#section-7.4
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+// DefaultLimit 未指定单条消息的最大长度时采用的默认值
+const DefaultLimit = 32 << 20
+
+const (
+	finBit  = 0x80
+	rsvBits = 0x70
+	maskBit = 0x80
+
+	maxControlPayload = 125
+)
+
+var (
+	errProtocol   = web.NewLocaleError("websocket protocol error")
+	errTooBig     = web.NewLocaleError("websocket message too big")
+	errClosed     = web.NewLocaleError("websocket connection closed")
+	errBadRequest = web.NewLocaleError("invalid websocket handshake")
+)
+
+// ErrClosed 向已经关闭的连接发送数据时返回的错误
+func ErrClosed() error { return errClosed }
+
+// Message 接收到的消息
+type Message struct {
+	Type MessageType
+	Data []byte
+
+	unmarshal web.UnmarshalFunc
+}
+
+// Unmarshal 将 [Message.Data] 解码至 v
+//
+// 解码方法与发送时的编码方法相同，由 [Server.NewConn] 或 [Dial] 决定。
+func (m *Message) Unmarshal(v any) error {
+	if m.unmarshal == nil {
+		return web.NewLocaleError("not found unmarshal function")
+	}
+	return m.unmarshal(bytes.NewReader(m.Data), v)
+}
+
+type frame struct {
+	fin     bool
+	op      byte
+	payload []byte
+}
+
+// 计算 Sec-WebSocket-Accept 的值
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key))
+	h.Write([]byte(guid))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func newKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// 报头 h 中是否包含了 token，不区分大小写。
+func headerContains(h http.Header, key, token string) bool {
+	for _, v := range h.Values(key) {
+		for s := range strings.SplitSeq(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 检测是否为合法的 websocket 握手请求
+func checkHandshake(r *http.Request) (key string, err error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, header.Connection, "upgrade") ||
+		!headerContains(r.Header, header.Upgrade, "websocket") {
+		return "", web.NewError(http.StatusBadRequest, errBadRequest)
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != Version {
+		return "", web.NewError(http.StatusUpgradeRequired, errBadRequest)
+	}
+
+	key = r.Header.Get("Sec-WebSocket-Key")
+	if k, err := base64.StdEncoding.DecodeString(key); err != nil || len(k) != 16 {
+		return "", web.NewError(http.StatusBadRequest, errBadRequest)
+	}
+	return key, nil
+}
+
+// 写入一个完整的帧
+//
+// mask 表示是否需要对内容进行掩码处理，客户端发送的帧必须进行掩码处理。
+func writeFrame(w *bufio.Writer, op byte, payload []byte, mask bool) error {
+	b := make([]byte, 0, 14)
+	b = append(b, finBit|op)
+
+	var m byte
+	if mask {
+		m = maskBit
+	}
+
+	switch l := len(payload); {
+	case l <= maxControlPayload:
+		b = append(b, m|byte(l))
+	case l <= 0xffff:
+		b = append(b, m|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(l))
+	default:
+		b = append(b, m|127)
+		b = binary.BigEndian.AppendUint64(b, uint64(l))
+	}
+
+	if mask {
+		key := make([]byte, 4)
+		rand.Read(key)
+		b = append(b, key...)
+
+		masked := make([]byte, len(payload))
+		for i, c := range payload {
+			masked[i] = c ^ key[i%4]
+		}
+		payload = masked
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// 读取一个帧
+//
+// masked 表示对方发送的帧是否必须经过掩码处理；
+// limit 表示 payload 的最大长度；
+func readFrame(r *bufio.Reader, masked bool, limit int64) (*frame, error) {
+	var h [2]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return nil, err
+	}
+
+	if h[0]&rsvBits != 0 { // 未协商任何扩展，保留位必须为 0。
+		return nil, errProtocol
+	}
+
+	f := &frame{fin: h[0]&finBit != 0, op: h[0] & 0x0f}
+	if (h[1]&maskBit != 0) != masked {
+		return nil, errProtocol
+	}
+
+	l := int64(h[1] & 0x7f)
+	switch l {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		l = int64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		if b[0]&0x80 != 0 { // 最高位必须为 0
+			return nil, errProtocol
+		}
+		l = int64(binary.BigEndian.Uint64(b[:]))
+	}
+
+	if f.op >= opClose && (l > maxControlPayload || !f.fin) { // 控制帧不能分片且长度不能大于 125
+		return nil, errProtocol
+	}
+
+	if l > limit {
+		return nil, errTooBig
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, key[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	f.payload = make([]byte, l)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return nil, err
+	}
+	if masked {
+		for i := range f.payload {
+			f.payload[i] ^= key[i%4]
+		}
+	}
+
+	return f, nil
+}
+
+func closePayload(code int, reason string) []byte {
+	if code == CloseNoStatus {
+		return nil
+	}
+
+	b := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(reason)), uint16(code))
+	b = append(b, reason...)
+	if len(b) > maxControlPayload {
+		b = b[:maxControlPayload]
+	}
+	return b
+}
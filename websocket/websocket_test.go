@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2025 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+)
+
+func TestAcceptKey(t *testing.T) {
+	a := assert.New(t, false)
+	a.Equal(acceptKey("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=") // RFC6455 1.3 中的示例
+	a.Length(newKey(), 24)
+}
+
+func TestCheckHandshake(t *testing.T) {
+	a := assert.New(t, false)
+
+	r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	r.Header.Set(header.Connection, "keep-alive, Upgrade")
+	r.Header.Set(header.Upgrade, "websocket")
+	r.Header.Set("Sec-WebSocket-Version", Version)
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	key, err := checkHandshake(r)
+	a.NotError(err).Equal(key, "dGhlIHNhbXBsZSBub25jZQ==")
+
+	r.Header.Set("Sec-WebSocket-Key", "abc")
+	key, err = checkHandshake(r)
+	a.Error(err).Empty(key)
+
+	r.Header.Set("Sec-WebSocket-Version", "8")
+	key, err = checkHandshake(r)
+	a.Error(err).Empty(key)
+
+	r = httptest.NewRequest(http.MethodPost, "/ws", nil)
+	key, err = checkHandshake(r)
+	a.Error(err).Empty(key)
+}
+
+func TestFrame(t *testing.T) {
+	a := assert.New(t, false)
+
+	for _, size := range []int{0, 125, 126, 0xffff, 0x10000} {
+		for _, mask := range []bool{true, false} {
+			buf := &bytes.Buffer{}
+			payload := bytes.Repeat([]byte("x"), size)
+			a.NotError(writeFrame(bufio.NewWriter(buf), opBinary, payload, mask))
+
+			f, err := readFrame(bufio.NewReader(bytes.NewReader(buf.Bytes())), mask, DefaultLimit)
+			a.NotError(err).True(f.fin).Equal(f.op, opBinary).Equal(f.payload, payload)
+
+			// 掩码与预期不符
+			f, err = readFrame(bufio.NewReader(bytes.NewReader(buf.Bytes())), !mask, DefaultLimit)
+			a.ErrorIs(err, errProtocol).Nil(f)
+		}
+	}
+
+	// 超出大小
+	buf := &bytes.Buffer{}
+	a.NotError(writeFrame(bufio.NewWriter(buf), opText, []byte("12345"), false))
+	f, err := readFrame(bufio.NewReader(buf), false, 4)
+	a.ErrorIs(err, errTooBig).Nil(f)
+
+	// 控制帧过长
+	buf = &bytes.Buffer{}
+	a.NotError(writeFrame(bufio.NewWriter(buf), opPing, bytes.Repeat([]byte("x"), 126), false))
+	f, err = readFrame(bufio.NewReader(buf), false, DefaultLimit)
+	a.ErrorIs(err, errProtocol).Nil(f)
+
+	// 64 位长度超出大小，不会分配内存。
+	f, err = readFrame(bufio.NewReader(bytes.NewReader([]byte{finBit | opBinary, 127, 0, 0, 0x10, 0, 0, 0, 0, 0})), false, DefaultLimit)
+	a.ErrorIs(err, errTooBig).Nil(f)
+
+	// 64 位长度的最高位必须为 0
+	f, err = readFrame(bufio.NewReader(bytes.NewReader([]byte{finBit | opBinary, 127, 0x80, 0, 0, 0, 0, 0, 0, 1})), false, DefaultLimit)
+	a.ErrorIs(err, errProtocol).Nil(f)
+}
+
+func TestConn_Close(t *testing.T) {
+	a := assert.New(t, false)
+	p1, p2 := net.Pipe()
+
+	ops := make(chan byte, 10)
+	go func() {
+		r := bufio.NewReader(p2)
+		for {
+			f, err := readFrame(r, false, DefaultLimit)
+			if err != nil {
+				close(ops)
+				return
+			}
+			ops <- f.op
+		}
+	}()
+
+	c := newConn(p1, bufio.NewReader(p1), bufio.NewWriter(p1), false, 0, 10, nil, nil, nil, nil)
+	a.Equal(c.limit, DefaultLimit)
+	a.NotError(c.SentRaw(TextMessage, []byte("1"))).
+		NotError(c.SentRaw(TextMessage, []byte("2")))
+
+	// 队列中的内容在 close 帧之后不再发送
+	a.NotError(c.Close(CloseNormal, ""))
+	c.start()
+	a.ErrorIs(c.SentRaw(TextMessage, []byte("3")), ErrClosed()).
+		ErrorIs(c.Ping(), ErrClosed())
+	time.Sleep(50 * time.Millisecond)
+	c.teardown()
+
+	var got []byte
+	for op := range ops {
+		got = append(got, op)
+	}
+	a.Equal(got, []byte{opClose})
+}
+
+func TestConn_teardown(t *testing.T) {
+	a := assert.New(t, false)
+	p1, _ := net.Pipe() // 对方不读取任何内容
+
+	c := newConn(p1, bufio.NewReader(p1), bufio.NewWriter(p1), false, 0, 10, nil, nil, nil, nil)
+	written := make(chan error, 1)
+	go func() { written <- c.write(opBinary, make([]byte, 1024)) }()
+	time.Sleep(50 * time.Millisecond) // 等待 write 阻塞
+
+	done := make(chan struct{})
+	go func() {
+		c.teardown()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		a.TB().Fatal("teardown 被阻塞")
+	}
+	a.Error(<-written)
+}
+
+func TestClosePayload(t *testing.T) {
+	a := assert.New(t, false)
+	a.Nil(closePayload(CloseNoStatus, "")).
+		Equal(closePayload(CloseNormal, "bye"), []byte{0x03, 0xe8, 'b', 'y', 'e'}).
+		Length(closePayload(CloseNormal, string(bytes.Repeat([]byte("x"), 200))), maxControlPayload)
+}